@@ -24,12 +24,19 @@ import (
 
 // Comparator object
 type Comparator struct {
-	client elasticsearch.Client
+	client *elasticsearch.Client
 	index  string
 }
 
 // NewComparator returns a new comparator for the given index and elasticsearch client
+//
+// Deprecated: the client holds a lock, which passing it by value copies. Use NewClientComparator instead.
 func NewComparator(client elasticsearch.Client, index string) Comparator {
+	return NewClientComparator(&client, index)
+}
+
+// NewClientComparator returns a new comparator for the given index sharing the given elasticsearch client
+func NewClientComparator(client *elasticsearch.Client, index string) Comparator {
 	return Comparator{
 		client: client,
 		index:  index,
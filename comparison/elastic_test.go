@@ -31,16 +31,22 @@ var searchFunc = func(o ...func(*esapi.SearchRequest)) (*esapi.Response, error)
 var _ = Describe("Tests for elastic.go", func() {
 	Context("Tests for NewComparator", func() {
 		var index string
-		var client elasticsearch.Client
+		var client *elasticsearch.Client
 		var expectedComparator Comparator
 		BeforeEach(func() {
 			index = "go-commons-test"
 			expectedComparator = Comparator{client: client, index: index}
 		})
 		It("Test 1: Returns the expected comparator", func() {
-			actualComparator := NewComparator(client, index)
+			actualComparator := NewClientComparator(client, index)
 			Expect(actualComparator).To(BeEquivalentTo(expectedComparator))
 		})
+
+		It("Test 2: Returns a comparator with a copy of the client", func() {
+			actualComparator := NewComparator(elasticsearch.Client{}, index)
+			Expect(actualComparator.client).NotTo(BeNil())
+			Expect(actualComparator.index).To(Equal(index))
+		})
 	})
 
 	Context("Tests for queryStringStats()", func() {
@@ -73,7 +79,7 @@ var _ = Describe("Tests for elastic.go", func() {
 				defer res.Body.Close()
 				return res, nil
 			}
-			comparator = NewComparator(*client, "placeholder")
+			comparator = NewClientComparator(client, "placeholder")
 			_, err := comparator.queryStringStats(query, field)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(1))
@@ -82,7 +88,7 @@ var _ = Describe("Tests for elastic.go", func() {
 
 		It("Test2 no error", func() {
 			client.Search = searchFunc
-			comparator = NewComparator(*client, "_all")
+			comparator = NewClientComparator(client, "_all")
 
 			_, err := comparator.queryStringStats(query, field)
 			//Asserting the number of times the mock implementation is called
@@ -91,7 +97,7 @@ var _ = Describe("Tests for elastic.go", func() {
 		})
 
 		It("Test3 not a valid link", func() {
-			comparator = NewComparator(*client, "_all")
+			comparator = NewClientComparator(client, "_all")
 			_, err := comparator.queryStringStats(query, field)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(0))
@@ -109,7 +115,7 @@ var _ = Describe("Tests for elastic.go", func() {
 				defer res.Body.Close()
 				return res, nil
 			}
-			comparator = NewComparator(*client, "_all")
+			comparator = NewClientComparator(client, "_all")
 			_, err := comparator.queryStringStats(query, field)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(1))
@@ -126,7 +132,7 @@ var _ = Describe("Tests for elastic.go", func() {
 				defer res.Body.Close()
 				return res, nil
 			}
-			comparator = NewComparator(*client, "placeholder")
+			comparator = NewClientComparator(client, "placeholder")
 			_, err := comparator.queryStringStats(query, field)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(1))
@@ -149,7 +155,7 @@ var _ = Describe("Tests for elastic.go", func() {
 
 		It("Test1 no error", func() {
 			client.Search = searchFunc
-			comparator := NewComparator(*client, "_all")
+			comparator := NewClientComparator(client, "_all")
 			_, err := comparator.Compare("placeholder", "placeholder", "max", 1.0, 1.0)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(1))
@@ -158,7 +164,7 @@ var _ = Describe("Tests for elastic.go", func() {
 
 		It("Test2 negative value", func() {
 			client.Search = searchFunc
-			comparator := NewComparator(*client, "_all")
+			comparator := NewClientComparator(client, "_all")
 			_, err := comparator.Compare("placeholder", "placeholder", "min", -1.0, 1.0)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(1))
@@ -167,7 +173,7 @@ var _ = Describe("Tests for elastic.go", func() {
 
 		It("Test3 negative tolerance", func() {
 			client.Search = searchFunc
-			comparator := NewComparator(*client, "_all")
+			comparator := NewClientComparator(client, "_all")
 			_, err := comparator.Compare("placeholder", "placeholder", "avg", 1.0, -1.0)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(1))
@@ -176,7 +182,7 @@ var _ = Describe("Tests for elastic.go", func() {
 
 		It("Test4 negative tolerance", func() {
 			client.Search = searchFunc
-			comparator := NewComparator(*client, "_all")
+			comparator := NewClientComparator(client, "_all")
 			_, err := comparator.Compare("placeholder", "placeholder", "sum", 1.0, -1.0)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(1))
@@ -184,7 +190,7 @@ var _ = Describe("Tests for elastic.go", func() {
 		})
 
 		It("Test5 no error with sum stat", func() {
-			comparator := NewComparator(*client, "_all")
+			comparator := NewClientComparator(client, "_all")
 			_, err := comparator.Compare("placeholder", "placeholder", "sum", 1.0, -1.0)
 			//Asserting the number of times the mock implementation is called
 			Expect(c).To(BeEquivalentTo(0))
@@ -0,0 +1,104 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// backgroundBatch state of the bulk indexers held open between the indexing calls when flushing on interval.
+// Its lock guards the bulk indexers held by the indexer backend
+type backgroundBatch struct {
+	sync.Mutex
+	interval    time.Duration
+	statsLock   sync.Mutex
+	stats       itemStats
+	start       time.Time
+	latencies   flushLatencies
+	stopFlusher func()
+	newTicker   func(time.Duration) (<-chan time.Time, func())
+}
+
+// newBackgroundBatch returns the background batch of the given configuration, nil when flushing on interval is disabled
+func newBackgroundBatch(indexerConfig IndexerConfig) (*backgroundBatch, error) {
+	if indexerConfig.FlushInterval < 0 {
+		return nil, fmt.Errorf("invalid flush interval: %s", indexerConfig.FlushInterval)
+	}
+	if indexerConfig.FlushInterval == 0 {
+		return nil, nil
+	}
+	return &backgroundBatch{
+		interval:  indexerConfig.FlushInterval,
+		stats:     newItemStats(indexerConfig.AtomicStats),
+		start:     time.Now(),
+		newTicker: newTimeTicker,
+	}, nil
+}
+
+// newTimeTicker returns the channel of a ticker of the given period along with the function stopping it
+func newTimeTicker(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// startFlusher calls flush every interval until stopFlusher is called, unless already started.
+// Must be called holding the lock
+func (b *backgroundBatch) startFlusher(flush func()) {
+	if b.stopFlusher != nil {
+		return
+	}
+	ticks, stopTicker := b.newTicker(b.interval)
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		defer stopTicker()
+		for {
+			select {
+			case <-ticks:
+				flush()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	b.stopFlusher = func() {
+		close(stop)
+		<-done
+	}
+}
+
+// stop stops the flusher, waiting for an ongoing flush to finish
+func (b *backgroundBatch) stop() {
+	b.Lock()
+	stopFlusher := b.stopFlusher
+	b.stopFlusher = nil
+	b.Unlock()
+	if stopFlusher != nil {
+		stopFlusher()
+	}
+}
+
+// result returns the outcome of the documents flushed since the previous call and resets it.
+// The stats are reset in place, as the pending bulk indexer items keep a reference to them
+func (b *backgroundBatch) result() IndexingResult {
+	b.statsLock.Lock()
+	defer b.statsLock.Unlock()
+	result := newIndexingResult(b.stats.take(), 0, time.Since(b.start))
+	result.FlushLatency = b.latencies.percentiles()
+	b.start = time.Now()
+	return result
+}
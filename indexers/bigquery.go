@@ -0,0 +1,214 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+const bigQueryIndexer = "bigquery"
+
+// defaultBigQueryFlushDocs rows per insert request when FlushDocs isn't set, as recommended by BigQuery
+const defaultBigQueryFlushDocs = 500
+
+// bigQueryInserter streams rows into a BigQuery table
+type bigQueryInserter interface {
+	Put(ctx context.Context, src interface{}) error
+}
+
+// bigQueryRow row streamed into BigQuery, the insert ID letting BigQuery drop the rows inserted twice
+type bigQueryRow struct {
+	values   map[string]bigquery.Value
+	insertID string
+}
+
+// Save implements bigquery.ValueSaver
+func (r bigQueryRow) Save() (map[string]bigquery.Value, string, error) {
+	return r.values, r.insertID, nil
+}
+
+// BigQuery BigQuery instance
+type BigQuery struct {
+	client    *bigquery.Client
+	dataset   *bigquery.Dataset
+	inserter  bigQueryInserter
+	flushDocs int
+	logger    Logger
+}
+
+// Init function
+func init() {
+	Register(bigQueryIndexer, func() Indexer { return &BigQuery{} })
+}
+
+// Returns new indexer for BigQuery, using the application default credentials. Documents are streamed into the
+// Index table of the configured dataset
+func (b *BigQuery) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if indexerConfig.Dataset == "" {
+		return fmt.Errorf("dataset not specified")
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	project, dataset := bigquery.DetectProjectID, indexerConfig.Dataset
+	if i := strings.LastIndex(dataset, "."); i >= 0 {
+		project, dataset = dataset[:i], dataset[i+1:]
+	}
+	client, err := bigquery.NewClient(context.Background(), project)
+	if err != nil {
+		return fmt.Errorf("error creating the BigQuery client: %s", err)
+	}
+	b.client = client
+	b.dataset = client.Dataset(dataset)
+	b.inserter = b.dataset.Table(indexerConfig.Index).Inserter()
+	b.flushDocs = indexerConfig.FlushDocs
+	if b.flushDocs == 0 {
+		b.flushDocs = defaultBigQueryFlushDocs
+	}
+	b.logger = loggerOrNop(indexerConfig.Logger)
+	return nil
+}
+
+// Index streams the documents into the configured table
+func (b *BigQuery) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := b.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult streams the documents into the configured table in inserts of up to flushDocs rows and returns
+// the indexing result. Rows are inserted with the document ID as insert ID, so BigQuery drops the retried ones
+func (b *BigQuery) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, b.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var rows []bigQueryRow
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := b.insert(ctx, rows, indexerStats); err != nil {
+			return err
+		}
+		rows = nil
+		return nil
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		insertID := documentID(j, opts.DocumentIDField, docHashKey)
+		reportDocumentID(opts, document, insertID)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		values := make(map[string]bigquery.Value)
+		decoder := json.NewDecoder(bytes.NewReader(objectDocument(j)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot decode document %s: %s", j, err)
+		}
+		rows = append(rows, bigQueryRow{values: values, insertID: insertID})
+		if len(rows) == b.flushDocs {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if err := flush(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// insert streams the given rows into the table, counting the rows rejected by BigQuery as failed
+func (b *BigQuery) insert(ctx context.Context, rows []bigQueryRow, indexerStats map[string]int) error {
+	err := b.inserter.Put(ctx, rows)
+	var rowErrors bigquery.PutMultiError
+	if errors.As(err, &rowErrors) {
+		logger := loggerOrNop(b.logger)
+		for _, rowError := range rowErrors {
+			logger.Errorf("Error indexing document %s: %s", rowError.InsertID, rowError.Errors)
+		}
+		indexerStats["failed"] += len(rowErrors)
+		indexerStats["created"] += len(rows) - len(rowErrors)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unexpected BigQuery error: %s", err)
+	}
+	indexerStats["created"] += len(rows)
+	return nil
+}
+
+// Ping checks the BigQuery dataset is reachable, telling the rejected credentials apart
+func (b *BigQuery) Ping(ctx context.Context) error {
+	_, err := b.dataset.Metadata(ctx)
+	if err == nil {
+		return nil
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		if statusErr := pingStatus("BigQuery", apiErr.Code); errors.Is(statusErr, ErrUnauthorized) {
+			return statusErr
+		}
+		return pingFailed("BigQuery", ErrUnhealthy, err)
+	}
+	return pingFailed("BigQuery", ErrUnreachable, err)
+}
+
+// Close closes the BigQuery client
+func (b *BigQuery) Close() error {
+	if b.client != nil {
+		return b.client.Close()
+	}
+	return nil
+}
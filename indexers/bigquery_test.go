@@ -0,0 +1,174 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"cloud.google.com/go/bigquery"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/api/option"
+)
+
+// mockBigQueryInserter records the inserted batches of rows
+type mockBigQueryInserter struct {
+	batches [][]bigQueryRow
+	err     error
+}
+
+func (m *mockBigQueryInserter) Put(ctx context.Context, src interface{}) error {
+	m.batches = append(m.batches, src.([]bigQueryRow))
+	return m.err
+}
+
+var _ = Describe("Tests for bigquery.go", func() {
+	Context("Tests for New()", func() {
+		var indexerConfig IndexerConfig
+		var indexer BigQuery
+		BeforeEach(func() {
+			indexerConfig = IndexerConfig{Type: "bigquery",
+				Index:   "go_commons_test",
+				Dataset: "perf",
+			}
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no dataset", func() {
+			indexerConfig.Dataset = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("dataset not specified")))
+		})
+
+		It("Returns err invalid number of documents per flush", func() {
+			indexerConfig.FlushDocs = -1
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("invalid number of documents per flush: -1")))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer BigQuery
+		var inserter *mockBigQueryInserter
+		BeforeEach(func() {
+			inserter = &mockBigQueryInserter{}
+			indexer = BigQuery{inserter: inserter, flushDocs: defaultBigQueryFlushDocs}
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Inserts the documents as rows keyed by their ID", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(inserter.batches).To(HaveLen(1))
+			rows := inserter.batches[0]
+			Expect(rows).To(HaveLen(3))
+			values, insertID, err := rows[0].Save()
+			Expect(err).To(BeNil())
+			Expect(values).To(Equal(map[string]bigquery.Value{"document": "example document", "metricName": "placeholder"}))
+			Expect(insertID).To(Equal(hashDocument([]byte(`"example document"`))))
+			values, _, _ = rows[2].Save()
+			Expect(values).To(HaveKeyWithValue("key2", BeEquivalentTo("123")))
+		})
+
+		It("Splits the inserts every flushDocs rows", func() {
+			indexer.flushDocs = 2
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(inserter.batches).To(HaveLen(2))
+			Expect(inserter.batches[1]).To(HaveLen(1))
+		})
+
+		It("Counts the rows rejected by BigQuery as failed", func() {
+			inserter.err = bigquery.PutMultiError{bigquery.RowInsertionError{InsertID: "a", RowIndex: 1}}
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.Failed).To(Equal(1))
+		})
+
+		It("Doesn't insert any row in dry-run mode", func() {
+			testcase.opts.DryRun = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 3))
+			Expect(inserter.batches).To(BeEmpty())
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(result.Created).To(Equal(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Returns err when the insert fails", func() {
+			inserter.err = errors.New("table not found")
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeEquivalentTo(errors.New("Unexpected BigQuery error: table not found")))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer BigQuery
+		var status int
+		BeforeEach(func() {
+			status = http.StatusOK
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				if status != http.StatusOK {
+					fmt.Fprintf(w, `{"error":{"code":%d,"message":"%s"}}`, status, http.StatusText(status))
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":"go-commons:test","datasetReference":{"projectId":"go-commons","datasetId":"test"}}`))
+			}))
+			DeferCleanup(server.Close)
+			client, err := bigquery.NewClient(context.Background(), "go-commons", option.WithEndpoint(server.URL), option.WithoutAuthentication())
+			Expect(err).To(BeNil())
+			DeferCleanup(client.Close)
+			indexer = BigQuery{client: client, dataset: client.Dataset("test")}
+		})
+
+		It("Reads the dataset metadata", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Returns ErrUnauthorized when the credentials are rejected", func() {
+			status = http.StatusForbidden
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnauthorized))
+		})
+
+		It("Returns ErrUnhealthy when the dataset isn't found", func() {
+			status = http.StatusNotFound
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnhealthy))
+		})
+	})
+})
@@ -0,0 +1,114 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen returned instead of indexing the documents while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open, cluster failing")
+
+// defaultCircuitBreakerCooldown time the circuit breaker stays open when no cooldown is configured
+const defaultCircuitBreakerCooldown = 30 * time.Second
+
+// circuitState state of the circuit breaker
+type circuitState int
+
+const (
+	// circuitClosed indexing calls go through
+	circuitClosed circuitState = iota
+	// circuitOpen indexing calls are rejected until the cooldown expires
+	circuitOpen
+	// circuitHalfOpen a single trial call goes through, closing the breaker when it succeeds
+	circuitHalfOpen
+)
+
+// circuitBreaker rejects the indexing calls for a cooldown after a number of consecutive failed calls
+type circuitBreaker struct {
+	sync.Mutex
+	maxFailures int
+	cooldown    time.Duration
+	state       circuitState
+	failures    int
+	openedAt    time.Time
+	now         func() time.Time
+}
+
+// newCircuitBreaker returns the circuit breaker of the given configuration, nil when it's disabled
+func newCircuitBreaker(indexerConfig IndexerConfig) (*circuitBreaker, error) {
+	if indexerConfig.CircuitBreakerFailures < 0 {
+		return nil, fmt.Errorf("invalid number of circuit breaker failures: %d", indexerConfig.CircuitBreakerFailures)
+	}
+	if indexerConfig.CircuitBreakerFailures == 0 {
+		return nil, nil
+	}
+	cooldown := indexerConfig.CircuitBreakerCooldown
+	if cooldown <= 0 {
+		cooldown = defaultCircuitBreakerCooldown
+	}
+	return &circuitBreaker{maxFailures: indexerConfig.CircuitBreakerFailures, cooldown: cooldown, now: time.Now}, nil
+}
+
+// call runs the indexing call unless the breaker is open, recording whether it failed. A call fails when it returns
+// an error or when every document failed to be indexed
+func (cb *circuitBreaker) call(index func() (IndexingResult, error)) (IndexingResult, error) {
+	if cb == nil {
+		return index()
+	}
+	if err := cb.allow(); err != nil {
+		return IndexingResult{}, err
+	}
+	result, err := index()
+	cb.record(err != nil || (result.Failed > 0 && result.Created+result.Updated == 0))
+	return result, err
+}
+
+// allow returns ErrCircuitOpen when the breaker is open or a trial call is in flight,
+// moving to half-open once the cooldown expires
+func (cb *circuitBreaker) allow() error {
+	cb.Lock()
+	defer cb.Unlock()
+	switch cb.state {
+	case circuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cooldown {
+			return ErrCircuitOpen
+		}
+		cb.state = circuitHalfOpen
+	case circuitHalfOpen:
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record records the outcome of a call, opening the breaker after maxFailures consecutive failures
+// or when the trial call fails
+func (cb *circuitBreaker) record(failed bool) {
+	cb.Lock()
+	defer cb.Unlock()
+	if !failed {
+		cb.state = circuitClosed
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.state == circuitHalfOpen || cb.failures >= cb.maxFailures {
+		cb.state = circuitOpen
+		cb.openedAt = cb.now()
+	}
+}
@@ -0,0 +1,98 @@
+package indexers
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for circuitbreaker.go", func() {
+	var breaker *circuitBreaker
+	var now time.Time
+	var calls int
+	succeed := func() (IndexingResult, error) {
+		calls++
+		return IndexingResult{Created: 1}, nil
+	}
+	fail := func() (IndexingResult, error) {
+		calls++
+		return IndexingResult{}, errors.New("cluster unavailable")
+	}
+	BeforeEach(func() {
+		var err error
+		now = time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+		calls = 0
+		breaker, err = newCircuitBreaker(IndexerConfig{CircuitBreakerFailures: 2, CircuitBreakerCooldown: time.Minute})
+		Expect(err).To(BeNil())
+		breaker.now = func() time.Time { return now }
+	})
+
+	It("Is disabled by default", func() {
+		breaker, err := newCircuitBreaker(IndexerConfig{})
+		Expect(err).To(BeNil())
+		Expect(breaker).To(BeNil())
+		for i := 0; i < 5; i++ {
+			_, err = breaker.call(fail)
+			Expect(err).To(MatchError("cluster unavailable"))
+		}
+	})
+
+	It("Returns err negative number of failures", func() {
+		_, err := newCircuitBreaker(IndexerConfig{CircuitBreakerFailures: -1})
+		Expect(err).To(MatchError("invalid number of circuit breaker failures: -1"))
+	})
+
+	It("Opens after consecutive failures", func() {
+		_, err := breaker.call(fail)
+		Expect(err).To(MatchError("cluster unavailable"))
+		_, err = breaker.call(succeed)
+		Expect(err).To(BeNil())
+		_, _ = breaker.call(fail)
+		_, _ = breaker.call(fail)
+		_, err = breaker.call(succeed)
+		Expect(err).To(MatchError(ErrCircuitOpen))
+		Expect(calls).To(Equal(4))
+	})
+
+	It("Counts the calls where every document failed as failed", func() {
+		allFailed := func() (IndexingResult, error) { return IndexingResult{Failed: 3}, nil }
+		someFailed := func() (IndexingResult, error) { return IndexingResult{Created: 1, Failed: 2}, nil }
+		_, _ = breaker.call(someFailed)
+		_, _ = breaker.call(someFailed)
+		Expect(breaker.state).To(Equal(circuitClosed))
+		_, _ = breaker.call(allFailed)
+		_, _ = breaker.call(allFailed)
+		Expect(breaker.state).To(Equal(circuitOpen))
+	})
+
+	It("Half-opens after the cooldown and closes when the trial call succeeds", func() {
+		_, _ = breaker.call(fail)
+		_, _ = breaker.call(fail)
+		now = now.Add(59 * time.Second)
+		_, err := breaker.call(succeed)
+		Expect(err).To(MatchError(ErrCircuitOpen))
+		now = now.Add(time.Second)
+		Expect(breaker.allow()).To(Succeed())
+		Expect(breaker.state).To(Equal(circuitHalfOpen))
+		// Only the trial call goes through while half-open
+		_, err = breaker.call(succeed)
+		Expect(err).To(MatchError(ErrCircuitOpen))
+		breaker.record(false)
+		Expect(breaker.state).To(Equal(circuitClosed))
+		_, err = breaker.call(succeed)
+		Expect(err).To(BeNil())
+	})
+
+	It("Opens again when the trial call fails", func() {
+		_, _ = breaker.call(fail)
+		_, _ = breaker.call(fail)
+		now = now.Add(time.Minute)
+		_, err := breaker.call(fail)
+		Expect(err).To(MatchError("cluster unavailable"))
+		Expect(breaker.state).To(Equal(circuitOpen))
+		_, err = breaker.call(succeed)
+		Expect(err).To(MatchError(ErrCircuitOpen))
+	})
+})
@@ -0,0 +1,173 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+const clickHouseIndexer = "clickhouse"
+
+// ClickHouse exception codes reporting rejected credentials
+const (
+	clickHouseAccessDenied         = 497
+	clickHouseAuthenticationFailed = 516
+)
+
+// clickHouseConn runs queries on a ClickHouse server
+type clickHouseConn interface {
+	Exec(ctx context.Context, query string, args ...interface{}) error
+	Close() error
+}
+
+// ClickHouse ClickHouse instance
+type ClickHouse struct {
+	table string
+	conn  clickHouseConn
+}
+
+// Init function
+func init() {
+	Register(clickHouseIndexer, func() Indexer { return &ClickHouse{} })
+}
+
+// Returns new indexer for ClickHouse, Servers[0] being either the address of the native protocol endpoint or a DSN
+func (c *ClickHouse) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	options := &clickhouse.Options{Addr: []string{indexerConfig.Servers[0]}}
+	if strings.Contains(indexerConfig.Servers[0], "://") {
+		var err error
+		if options, err = clickhouse.ParseDSN(indexerConfig.Servers[0]); err != nil {
+			return fmt.Errorf("invalid ClickHouse DSN: %s", err)
+		}
+	}
+	if indexerConfig.Username != "" {
+		options.Auth.Username = indexerConfig.Username
+		options.Auth.Password = indexerConfig.Password
+	}
+	if indexerConfig.InsecureSkipVerify || indexerConfig.CACertPath != "" {
+		tlsClientConfig, err := tlsConfig(indexerConfig)
+		if err != nil {
+			return err
+		}
+		options.TLS = tlsClientConfig
+	}
+	// Connections are established on the first query
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		return fmt.Errorf("error creating the ClickHouse client: %s", err)
+	}
+	c.table = quoteClickHouseTable(indexerConfig.Index)
+	c.conn = conn
+	return nil
+}
+
+// Index inserts the documents as rows of the configured table
+func (c *ClickHouse) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := c.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult inserts the documents as rows of the configured table, in a single JSONEachRow insert,
+// and returns the indexing result
+func (c *ClickHouse) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, c.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var rows bytes.Buffer
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		rows.Write(objectDocument(j))
+		rows.WriteByte('\n')
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		indexerStats["created"]++
+	}
+	query := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow\n%s", c.table, rows.String())
+	if err := c.conn.Exec(ctx, query); err != nil {
+		return IndexingResult{}, fmt.Errorf("Unexpected ClickHouse error: %s", err)
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// Ping checks the ClickHouse server can be queried, telling the rejected credentials apart
+func (c *ClickHouse) Ping(ctx context.Context) error {
+	err := c.conn.Exec(ctx, "SELECT 1")
+	if err == nil {
+		return nil
+	}
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) && (exception.Code == clickHouseAuthenticationFailed || exception.Code == clickHouseAccessDenied) {
+		return pingFailed("ClickHouse", ErrUnauthorized, err)
+	}
+	return pingFailed("ClickHouse", ErrUnreachable, err)
+}
+
+// Close closes the ClickHouse connections
+func (c *ClickHouse) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// quoteClickHouseTable quotes the given, optionally database qualified, table name
+func quoteClickHouseTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(part) + "`"
+	}
+	return strings.Join(parts, ".")
+}
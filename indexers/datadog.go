@@ -0,0 +1,248 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const datadogIndexer = "datadog"
+
+// datadogGauge gauge type of the Datadog metrics intake
+const datadogGauge = 3
+
+// datadogSample document format expected by the datadog indexer
+type datadogSample struct {
+	MetricName string            `json:"metricName"`
+	Labels     map[string]string `json:"labels"`
+	Value      *float64          `json:"value"`
+	Timestamp  json.RawMessage   `json:"timestamp"`
+}
+
+// datadogPoint point of a Datadog series
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// datadogSeries series of the Datadog metrics intake
+type datadogSeries struct {
+	Metric string         `json:"metric"`
+	Type   int            `json:"type"`
+	Points []datadogPoint `json:"points"`
+	Tags   []string       `json:"tags,omitempty"`
+}
+
+// Datadog metrics indexer instance
+type Datadog struct {
+	url         string
+	client      *http.Client
+	apiKey      string
+	flushDocs   int
+	flushBytes  int
+	contentType string
+}
+
+// Init function
+func init() {
+	Register(datadogIndexer, func() Indexer { return &Datadog{} })
+}
+
+// Returns new indexer for Datadog, Servers[0] being the Datadog API URL, i.e. https://api.datadoghq.com
+func (d *Datadog) New(indexerConfig IndexerConfig) error {
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if indexerConfig.Token == "" {
+		return fmt.Errorf("Datadog API key not specified")
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	d.url = strings.TrimSuffix(indexerConfig.Servers[0], "/") + "/api/v2/series"
+	d.client = &http.Client{Transport: transport}
+	d.apiKey = indexerConfig.Token
+	d.flushDocs = indexerConfig.FlushDocs
+	d.contentType = contentType(indexerConfig)
+	d.flushBytes = indexerConfig.FlushBytes
+	if d.flushBytes <= 0 {
+		d.flushBytes = defaultFlushBytes
+	}
+	return nil
+}
+
+// Index submits the documents as series points to Datadog
+func (d *Datadog) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := d.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult submits the documents as series points to Datadog and returns the indexing result.
+// Series are batched in requests of up to flushDocs documents and flushBytes
+func (d *Datadog) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, d.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var batch [][]byte
+	batchBytes := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := d.submit(ctx, batch); err != nil {
+			return err
+		}
+		indexerStats["created"] += len(batch)
+		batch = nil
+		batchBytes = 0
+		return nil
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		series, err := encodeSeries(j, opts, start)
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		if batchBytes+len(series) > d.flushBytes {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		batch = append(batch, series)
+		batchBytes += len(series)
+		if len(batch) == d.flushDocs {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if err := flush(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// encodeSeries encodes the given sample as a Datadog series holding a single point, tagged with its labels
+// and the job name
+func encodeSeries(j []byte, opts IndexingOpts, now time.Time) ([]byte, error) {
+	var sample datadogSample
+	if err := json.Unmarshal(j, &sample); err != nil {
+		return nil, fmt.Errorf("Cannot decode sample %s: %s", j, err)
+	}
+	if sample.Value == nil {
+		return nil, fmt.Errorf("value not found in sample %s", j)
+	}
+	if sample.MetricName == "" {
+		sample.MetricName = opts.MetricName
+	}
+	if sample.MetricName == "" {
+		return nil, fmt.Errorf("metric name not found in sample %s", j)
+	}
+	timestamp, err := sampleTimestamp(sample.Timestamp, now)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	if opts.JobName != "" {
+		tags = append(tags, "jobName:"+opts.JobName)
+	}
+	for name, value := range sample.Labels {
+		tags = append(tags, name+":"+value)
+	}
+	sort.Strings(tags)
+	return json.Marshal(datadogSeries{
+		Metric: sample.MetricName,
+		Type:   datadogGauge,
+		Points: []datadogPoint{{Timestamp: timestamp.Unix(), Value: *sample.Value}},
+		Tags:   tags,
+	})
+}
+
+// submit posts the batched series to the Datadog metrics intake
+func (d *Datadog) submit(ctx context.Context, batch [][]byte) error {
+	var body bytes.Buffer
+	body.WriteString(`{"series":[`)
+	body.Write(bytes.Join(batch, []byte(",")))
+	body.WriteString(`]}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", d.contentType)
+	req.Header.Set("DD-API-KEY", d.apiKey)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unexpected Datadog error: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected Datadog response %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Ping checks the Datadog site is reachable and accepts the API key through the validation endpoint
+func (d *Datadog) Ping(ctx context.Context) error {
+	return pingHTTP(ctx, "Datadog", d.client, strings.TrimSuffix(d.url, "/api/v2/series")+"/api/v1/validate", func(req *http.Request) {
+		req.Header.Set("DD-API-KEY", d.apiKey)
+	})
+}
+
+// Close closes the idle connections of the Datadog client
+func (d *Datadog) Close() error {
+	if d.client != nil {
+		d.client.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package indexers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for datadog.go", func() {
+	var indexerConfig IndexerConfig
+	var indexer Datadog
+	var server *httptest.Server
+	var requests []*http.Request
+	var payloads []map[string][]datadogSeries
+	BeforeEach(func() {
+		requests = nil
+		payloads = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string][]datadogSeries
+			Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+			requests = append(requests, r)
+			payloads = append(payloads, payload)
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}))
+		indexerConfig = IndexerConfig{Type: "datadog",
+			Servers: []string{server.URL},
+			Token:   "dd-api-key",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Tests for New()", func() {
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.url).To(Equal(server.URL + "/api/v2/series"))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("servers not specified"))
+		})
+
+		It("Returns err no API key", func() {
+			indexerConfig.Token = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("Datadog API key not specified"))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var timestamp time.Time
+		BeforeEach(func() {
+			timestamp = time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+		})
+
+		It("Submits the documents as series points", func() {
+			documents := []interface{}{
+				map[string]interface{}{"value": 2.5, "labels": map[string]string{"quantile": "P99"}, "timestamp": timestamp.Format(time.RFC3339)},
+				map[string]interface{}{"metricName": "up", "value": 1, "timestamp": timestamp.UnixMilli()},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "podLatency", JobName: "density"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/api/v2/series"))
+			Expect(requests[0].Header.Get("DD-API-KEY")).To(Equal("dd-api-key"))
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(payloads[0]["series"]).To(Equal([]datadogSeries{
+				{Metric: "podLatency", Type: datadogGauge, Points: []datadogPoint{{Timestamp: timestamp.Unix(), Value: 2.5}}, Tags: []string{"jobName:density", "quantile:P99"}},
+				{Metric: "up", Type: datadogGauge, Points: []datadogPoint{{Timestamp: timestamp.Unix(), Value: 1}}, Tags: []string{"jobName:density"}},
+			}))
+		})
+
+		It("Sends the configured content type", func() {
+			indexerConfig.ContentType = "application/vnd.datadog+json"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{MetricName: "podLatency"})
+			Expect(err).To(BeNil())
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/vnd.datadog+json"))
+		})
+
+		It("Batches the series up to the flush documents", func() {
+			indexerConfig.FlushDocs = 2
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			var documents []interface{}
+			for i := 0; i < 5; i++ {
+				documents = append(documents, map[string]interface{}{"value": i})
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "up"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(5))
+			Expect(payloads).To(HaveLen(3))
+			Expect(payloads[2]["series"]).To(HaveLen(1))
+		})
+
+		It("Batches the series up to the flush bytes", func() {
+			indexerConfig.FlushBytes = 150
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			var documents []interface{}
+			for i := 0; i < 5; i++ {
+				documents = append(documents, map[string]interface{}{"value": i})
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "up"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(5))
+			Expect(len(payloads)).To(BeNumerically(">", 1))
+		})
+
+		It("Skips the redundant documents", func() {
+			document := map[string]interface{}{"value": 1}
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{document, document}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Skipped).To(Equal(1))
+		})
+
+		It("Doesn't submit any series in dry-run mode", func() {
+			documents := []interface{}{map[string]interface{}{"value": 1}, map[string]interface{}{"value": 2}}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "up", DryRun: true})
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 2))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err documents without value", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"uuid": "1234"}}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(MatchError(ContainSubstring("value not found in sample")))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err no metric name", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{})
+			Expect(err).To(MatchError(ContainSubstring("metric name not found in sample")))
+		})
+
+		It("Returns err when the intake rejects the series", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"errors":["Forbidden"]}`, http.StatusForbidden)
+			})
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(MatchError(`Unexpected Datadog response 403: {"errors":["Forbidden"]}`))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Validates the API key", func() {
+			var validated *http.Request
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				validated = r
+				_, _ = w.Write([]byte(`{"valid":true}`))
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			Expect(validated.URL.Path).To(Equal("/api/v1/validate"))
+			Expect(validated.Header.Get("DD-API-KEY")).To(Equal("dd-api-key"))
+		})
+
+		It("Returns ErrUnauthorized when the API key is rejected", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnauthorized))
+		})
+	})
+})
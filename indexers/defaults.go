@@ -0,0 +1,131 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"io"
+	"reflect"
+)
+
+// defaultOptsIndexer indexer merging its default indexing options into the options of every indexing call
+type defaultOptsIndexer struct {
+	Indexer
+	defaults IndexingOpts
+}
+
+// defaultOptsStreamIndexer StreamIndexer merging the default indexing options into the options of every call
+type defaultOptsStreamIndexer struct {
+	StreamIndexer
+	defaults IndexingOpts
+}
+
+// defaultOptsNDJSONIndexer NDJSONIndexer merging the default indexing options into the options of every call
+type defaultOptsNDJSONIndexer struct {
+	NDJSONIndexer
+	defaults IndexingOpts
+}
+
+// newDefaultOptsIndexer returns the given indexer merging the default indexing options into the options of every
+// indexing call, implementing the same optional interfaces as the given indexer
+func newDefaultOptsIndexer(indexer Indexer, defaults IndexingOpts) Indexer {
+	d := &defaultOptsIndexer{Indexer: indexer, defaults: defaults}
+	streamIndexer, isStream := indexer.(StreamIndexer)
+	ndjsonIndexer, isNDJSON := indexer.(NDJSONIndexer)
+	reader, isReader := indexer.(DocumentReader)
+	s := &defaultOptsStreamIndexer{StreamIndexer: streamIndexer, defaults: defaults}
+	n := &defaultOptsNDJSONIndexer{NDJSONIndexer: ndjsonIndexer, defaults: defaults}
+	switch {
+	case isStream && isNDJSON && isReader:
+		return struct {
+			*defaultOptsIndexer
+			StreamIndexer
+			NDJSONIndexer
+			DocumentReader
+		}{d, s, n, reader}
+	case isStream && isNDJSON:
+		return struct {
+			*defaultOptsIndexer
+			StreamIndexer
+			NDJSONIndexer
+		}{d, s, n}
+	case isStream && isReader:
+		return struct {
+			*defaultOptsIndexer
+			StreamIndexer
+			DocumentReader
+		}{d, s, reader}
+	case isNDJSON && isReader:
+		return struct {
+			*defaultOptsIndexer
+			NDJSONIndexer
+			DocumentReader
+		}{d, n, reader}
+	case isStream:
+		return struct {
+			*defaultOptsIndexer
+			StreamIndexer
+		}{d, s}
+	case isNDJSON:
+		return struct {
+			*defaultOptsIndexer
+			NDJSONIndexer
+		}{d, n}
+	case isReader:
+		return struct {
+			*defaultOptsIndexer
+			DocumentReader
+		}{d, reader}
+	}
+	return d
+}
+
+// hasDefaultOpts reports whether any default indexing option is set
+func hasDefaultOpts(defaults IndexingOpts) bool {
+	return !reflect.ValueOf(defaults).IsZero()
+}
+
+// mergeOpts returns the given indexing options with their unset fields set to the default ones. As a field is
+// only unset when it holds its zero value, a boolean option set by default can't be turned off per call
+func mergeOpts(defaults, opts IndexingOpts) IndexingOpts {
+	merged := reflect.ValueOf(&opts).Elem()
+	defaultValues := reflect.ValueOf(defaults)
+	for i := 0; i < merged.NumField(); i++ {
+		if merged.Field(i).IsZero() {
+			merged.Field(i).Set(defaultValues.Field(i))
+		}
+	}
+	return opts
+}
+
+// Index indexes the documents with the default options merged into opts
+func (d *defaultOptsIndexer) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	return d.Indexer.Index(ctx, documents, mergeOpts(d.defaults, opts))
+}
+
+// IndexWithResult indexes the documents with the default options merged into opts
+func (d *defaultOptsIndexer) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	return d.Indexer.IndexWithResult(ctx, documents, mergeOpts(d.defaults, opts))
+}
+
+// IndexStream indexes the documents received from the channel with the default options merged into opts
+func (d *defaultOptsStreamIndexer) IndexStream(ctx context.Context, documents <-chan interface{}, opts IndexingOpts) (string, error) {
+	return d.StreamIndexer.IndexStream(ctx, documents, mergeOpts(d.defaults, opts))
+}
+
+// IndexNDJSON indexes the JSON lines read from r with the default options merged into opts
+func (d *defaultOptsNDJSONIndexer) IndexNDJSON(ctx context.Context, r io.Reader, opts IndexingOpts) (string, error) {
+	return d.NDJSONIndexer.IndexNDJSON(ctx, r, mergeOpts(d.defaults, opts))
+}
@@ -0,0 +1,85 @@
+package indexers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for defaults.go", func() {
+	Context("Tests for mergeOpts()", func() {
+		It("Uses the default options for the unset ones, the per-call ones taking precedence", func() {
+			defaults := IndexingOpts{MetricName: "podLatency", JobName: "node-density", LabelFields: []string{"node"}, AddTimestamp: true}
+			merged := mergeOpts(defaults, IndexingOpts{MetricName: "nodeStatus", DocumentIDField: "uuid"})
+			Expect(merged.MetricName).To(Equal("nodeStatus"))
+			Expect(merged.JobName).To(Equal("node-density"))
+			Expect(merged.DocumentIDField).To(Equal("uuid"))
+			Expect(merged.LabelFields).To(Equal([]string{"node"}))
+			Expect(merged.AddTimestamp).To(BeTrue())
+		})
+
+		It("Keeps the per-call options when there are no defaults", func() {
+			opts := IndexingOpts{MetricName: "nodeStatus", SkipDedup: true}
+			Expect(mergeOpts(IndexingOpts{}, opts)).To(Equal(opts))
+			Expect(hasDefaultOpts(IndexingOpts{})).To(BeFalse())
+			Expect(hasDefaultOpts(IndexingOpts{JobName: "node-density"})).To(BeTrue())
+		})
+	})
+
+	Context("Tests for NewIndexer() with default options", func() {
+		It("Merges the default options into the options of every indexing call", func() {
+			directory := GinkgoT().TempDir()
+			indexer, err := NewIndexer(IndexerConfig{Type: LocalIndexer, MetricsDirectory: directory, DefaultOpts: IndexingOpts{MetricName: "podLatency"}})
+			Expect(err).To(BeNil())
+			_, err = indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(path.Join(directory, "podLatency.json")).To(BeAnExistingFile())
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{map[string]interface{}{"value": 2}}, IndexingOpts{MetricName: "nodeStatus"})
+			Expect(err).To(BeNil())
+			Expect(path.Join(directory, "nodeStatus.json")).To(BeAnExistingFile())
+		})
+
+		It("Implements the optional interfaces of the configured indexer", func() {
+			var lines []map[string]interface{}
+			mockServer := httptest.NewServer(recordBulkLines(bulkMockHandler(func(n int) int { return http.StatusCreated }), &lines))
+			defer mockServer.Close()
+			indexer, err := NewIndexer(IndexerConfig{Type: OpenSearchIndexer, Servers: []string{mockServer.URL}, Index: "go-commons-test", DefaultOpts: IndexingOpts{DryRun: true}})
+			Expect(err).To(BeNil())
+			defer indexer.Close()
+			streamIndexer, ok := indexer.(StreamIndexer)
+			Expect(ok).To(BeTrue())
+			documents := make(chan interface{}, 2)
+			documents <- map[string]interface{}{"value": 1}
+			documents <- map[string]interface{}{"value": 2}
+			close(documents)
+			msg, err := streamIndexer.IndexStream(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("validated=2"))
+			ndjsonIndexer, ok := indexer.(NDJSONIndexer)
+			Expect(ok).To(BeTrue())
+			msg, err = ndjsonIndexer.IndexNDJSON(context.Background(), strings.NewReader("{\"value\":1}\n{\"value\":2}\n"), IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("validated=2"))
+			Expect(lines).To(BeEmpty())
+			reader, ok := indexer.(DocumentReader)
+			Expect(ok).To(BeTrue())
+			count, err := reader.Count(context.Background())
+			Expect(err).To(BeNil())
+			Expect(count).To(BeZero())
+		})
+
+		It("Doesn't implement the optional interfaces the configured indexer lacks", func() {
+			indexer, err := NewIndexer(IndexerConfig{Type: LocalIndexer, MetricsDirectory: GinkgoT().TempDir(), DefaultOpts: IndexingOpts{MetricName: "podLatency"}})
+			Expect(err).To(BeNil())
+			_, isStream := indexer.(StreamIndexer)
+			_, isNDJSON := indexer.(NDJSONIndexer)
+			_, isReader := indexer.(DocumentReader)
+			Expect([]bool{isStream, isNDJSON, isReader}).To(Equal([]bool{false, false, false}))
+		})
+	})
+})
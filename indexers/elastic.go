@@ -15,18 +15,7 @@
 package indexers
 
 import (
-	"bytes"
-	"context"
-	"crypto/sha256"
-	"crypto/tls"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"runtime"
-	"strings"
-	"sync"
-	"time"
 
 	elasticsearch "github.com/elastic/go-elasticsearch/v7"
 	"github.com/elastic/go-elasticsearch/v7/esutil"
@@ -36,112 +25,60 @@ const elastic = "elastic"
 
 // Elastic ElasticSearch instance
 type Elastic struct {
-	index string
+	elasticCore
+	compatibility *compatibilityTransport
 }
 
-// ESClient elasticsearch client instance
-var ESClient *elasticsearch.Client
+// elasticClient v7 client of the Elasticsearch indexer
+type elasticClient struct {
+	*elasticsearch.Client
+}
+
+// newBulkIndexer returns a bulk indexer with the given configuration using the client
+func (c elasticClient) newBulkIndexer(config esutil.BulkIndexerConfig) (esutil.BulkIndexer, error) {
+	config.Client = c.Client
+	return esutil.NewBulkIndexer(config)
+}
 
 // Init function
 func init() {
-	indexerMap[elastic] = &Elastic{}
+	Register(elastic, func() Indexer { return &Elastic{} })
 }
 
-// Returns new indexer for elastic search
-func (esIndexer *Elastic) new(indexerConfig IndexerConfig) error {
-	var err error
-	if indexerConfig.Index == "" {
-		return fmt.Errorf("index name not specified")
-	}
-	esIndex := strings.ToLower(indexerConfig.Index)
-	cfg := elasticsearch.Config{
-		Addresses: indexerConfig.Servers,
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: indexerConfig.InsecureSkipVerify}},
-	}
-	ESClient, err = elasticsearch.NewClient(cfg)
-	if err != nil {
-		return fmt.Errorf("error creating the ES client: %s", err)
-	}
-	r, err := ESClient.Cluster.Health()
+// Returns new indexer for elastic search, serving Elasticsearch as well as the OpenSearch and OSS clusters refused
+// by the product check of the v7 client
+func (esIndexer *Elastic) New(indexerConfig IndexerConfig) error {
+	esIndex, transport, err := esIndexer.configure(indexerConfig)
 	if err != nil {
-		return fmt.Errorf("ES health check failed: %s", err)
+		return err
 	}
-	if r.StatusCode != 200 {
-		return fmt.Errorf("unexpected ES status code: %d", r.StatusCode)
-	}
-	esIndexer.index = esIndex
-	r, _ = ESClient.Indices.Exists([]string{esIndex})
-	if r.IsError() {
-		r, _ = ESClient.Indices.Create(esIndex)
-		if r.IsError() {
-			return fmt.Errorf("error creating index %s on ES: %s", esIndex, r.String())
-		}
+	esIndexer.compatibility = &compatibilityTransport{Transport: transport}
+	transport = productCheckTransport{Transport: esIndexer.compatibility}
+	maxRetries, retryBackoff := retryPolicy(indexerConfig)
+	cfg := elasticsearch.Config{
+		RetryOnStatus:        retryOnStatus,
+		DisableRetry:         indexerConfig.MaxRetries < 0,
+		MaxRetries:           maxRetries,
+		RetryBackoff:         retryBackoff,
+		Addresses:            indexerConfig.Servers,
+		Transport:            transport,
+		CompressRequestBody:  indexerConfig.Compression,
+		UseResponseCheckOnly: true,
 	}
-	return nil
-}
-
-// Index uses bulkIndexer to index the documents in the given index
-func (esIndexer *Elastic) Index(documents []interface{}, opts IndexingOpts) (string, error) {
-	var statString string
-	var indexerStatsLock sync.Mutex
-	indexerStats := make(map[string]int)
-
-	if len(documents) <= 0 {
-		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), nil
+	if indexerConfig.APIKey != "" {
+		cfg.APIKey = indexerConfig.APIKey
+	} else {
+		cfg.Username = indexerConfig.Username
+		cfg.Password = indexerConfig.Password
 	}
-	hasher := sha256.New()
-	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
-		Client:     ESClient,
-		Index:      esIndexer.index,
-		FlushBytes: 5e+6,
-		NumWorkers: runtime.NumCPU(),
-		Timeout:    10 * time.Minute, // TODO: hardcoded
-	})
+	client, err := elasticsearch.NewClient(cfg)
 	if err != nil {
-		return "", fmt.Errorf("Error creating the indexer: %s", err)
-	}
-	start := time.Now().UTC()
-	docHash := make(map[string]bool)
-	redundantSkipped := 0
-	for _, document := range documents {
-		j, err := json.Marshal(document)
-		if err != nil {
-			return "", fmt.Errorf("Cannot encode document %s: %s", document, err)
-		}
-		hasher.Write(j)
-		docId := hex.EncodeToString(hasher.Sum(nil))
-		if _, exists := docHash[docId]; exists {
-			redundantSkipped += 1
-			continue
-		}
-		err = bi.Add(
-			context.Background(),
-			esutil.BulkIndexerItem{
-				Action:     "index",
-				Body:       bytes.NewReader(j),
-				DocumentID: docId,
-				OnSuccess: func(c context.Context, bii esutil.BulkIndexerItem, biri esutil.BulkIndexerResponseItem) {
-					indexerStatsLock.Lock()
-					defer indexerStatsLock.Unlock()
-					indexerStats[biri.Result]++
-				},
-			},
-		)
-		if err != nil {
-			return "", fmt.Errorf("Unexpected ES indexing error: %s", err)
-		}
-		docHash[docId] = true
-		hasher.Reset()
-	}
-	if err := bi.Close(context.Background()); err != nil {
-		return "", fmt.Errorf("Unexpected ES error: %s", err)
-	}
-	dur := time.Since(start)
-	for stat, val := range indexerStats {
-		statString += fmt.Sprintf(" %s=%d", stat, val)
+		return fmt.Errorf("error creating the ES client: %s", err)
 	}
-	if(redundantSkipped > 0){
-		statString += fmt.Sprintf(" redundantskipped=%d", redundantSkipped)
+	if err := esIndexer.connect(elasticClient{client}, transport, indexerConfig); err != nil {
+		return err
 	}
-	return fmt.Sprintf("Indexing finished in %v:%v", dur.Truncate(time.Millisecond), statString), nil
+	// The REST API compatibility with the v7 client is enabled on ES 8 and later
+	esIndexer.compatibility.enabled.Store(esIndexer.version.Major >= 8)
+	return esIndexer.setup(indexerConfig, esIndex)
 }
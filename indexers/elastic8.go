@@ -0,0 +1,141 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+	elasticsearch8 "github.com/elastic/go-elasticsearch/v8"
+	esutil8 "github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+const elastic8 = "elastic8"
+
+// Elastic8 ElasticSearch instance indexed with the v8 client, the bulk items being routed and versioned natively
+type Elastic8 struct {
+	elasticCore
+}
+
+// elastic8Client v8 client of the Elasticsearch indexer
+type elastic8Client struct {
+	*elasticsearch8.Client
+}
+
+// newBulkIndexer returns a v8 bulk indexer with the given configuration using the client
+func (c elastic8Client) newBulkIndexer(config esutil.BulkIndexerConfig) (esutil.BulkIndexer, error) {
+	bi, err := esutil8.NewBulkIndexer(esutil8.BulkIndexerConfig{
+		Client:        c.Client,
+		Index:         config.Index,
+		Refresh:       config.Refresh,
+		Pipeline:      config.Pipeline,
+		FlushBytes:    config.FlushBytes,
+		FlushInterval: config.FlushInterval,
+		NumWorkers:    config.NumWorkers,
+		Timeout:       config.Timeout,
+		OnError:       config.OnError,
+		OnFlushStart:  config.OnFlushStart,
+		OnFlushEnd:    config.OnFlushEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return elastic8BulkIndexer{bi}, nil
+}
+
+// elastic8BulkIndexer v8 bulk indexer adding the items of the shared indexing logic
+type elastic8BulkIndexer struct {
+	esutil8.BulkIndexer
+}
+
+// Add adds the item to the v8 bulk indexer, passing the original item to its callbacks
+func (b elastic8BulkIndexer) Add(ctx context.Context, item esutil.BulkIndexerItem) error {
+	body, ok := item.Body.(io.ReadSeeker)
+	if !ok && item.Body != nil {
+		j, err := io.ReadAll(item.Body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(j)
+	}
+	return b.BulkIndexer.Add(ctx, esutil8.BulkIndexerItem{
+		Index:           item.Index,
+		Action:          item.Action,
+		DocumentID:      item.DocumentID,
+		Routing:         item.Routing,
+		Version:         item.Version,
+		VersionType:     item.VersionType,
+		Body:            body,
+		RetryOnConflict: item.RetryOnConflict,
+		OnSuccess: func(ctx context.Context, _ esutil8.BulkIndexerItem, biri esutil8.BulkIndexerResponseItem) {
+			if item.OnSuccess != nil {
+				item.OnSuccess(ctx, item, esutil.BulkIndexerResponseItem(biri))
+			}
+		},
+		OnFailure: func(ctx context.Context, _ esutil8.BulkIndexerItem, biri esutil8.BulkIndexerResponseItem, err error) {
+			if item.OnFailure != nil {
+				item.OnFailure(ctx, item, esutil.BulkIndexerResponseItem(biri), err)
+			}
+		},
+	})
+}
+
+// Stats returns the statistics of the v8 bulk indexer
+func (b elastic8BulkIndexer) Stats() esutil.BulkIndexerStats {
+	return esutil.BulkIndexerStats(b.BulkIndexer.Stats())
+}
+
+// Init function
+func init() {
+	Register(elastic8, func() Indexer { return &Elastic8{} })
+}
+
+// Returns new indexer for elastic search 8. The v8 client only talks to servers sending the X-Elastic-Product
+// header, ES 7.14 and later
+func (esIndexer *Elastic8) New(indexerConfig IndexerConfig) error {
+	esIndex, transport, err := esIndexer.configure(indexerConfig)
+	if err != nil {
+		return err
+	}
+	maxRetries, retryBackoff := retryPolicy(indexerConfig)
+	cfg := elasticsearch8.Config{
+		RetryOnStatus:       retryOnStatus,
+		DisableRetry:        indexerConfig.MaxRetries < 0,
+		MaxRetries:          maxRetries,
+		RetryBackoff:        retryBackoff,
+		Addresses:           indexerConfig.Servers,
+		Transport:           transport,
+		CompressRequestBody: indexerConfig.Compression,
+	}
+	if indexerConfig.APIKey != "" {
+		cfg.APIKey = indexerConfig.APIKey
+	} else {
+		cfg.Username = indexerConfig.Username
+		cfg.Password = indexerConfig.Password
+	}
+	client, err := elasticsearch8.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("error creating the ES client: %s", err)
+	}
+	// The v8 client checks the X-Elastic-Product header of the first successful response, so the health check
+	// fails as well when the server isn't Elasticsearch
+	if err := esIndexer.connect(elastic8Client{client}, transport, indexerConfig); err != nil {
+		return err
+	}
+	return esIndexer.setup(indexerConfig, esIndex)
+}
@@ -0,0 +1,690 @@
+package indexers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for elastic8.go", func() {
+	Context("Tests for New()", func() {
+		var testcase newMethodTestcase
+		var indexer Elastic8
+		BeforeEach(func() {
+			testcase = newMethodTestcase{
+				indexerConfig: IndexerConfig{Type: "elastic8",
+					Servers:            []string{},
+					Index:              "go-commons-test",
+					InsecureSkipVerify: true,
+				},
+				mockServer: newES8MockServer(func(n int) int { return http.StatusCreated }),
+			}
+		})
+		AfterEach(func() {
+			testcase.mockServer.Close()
+		})
+
+		It("Returns nil as error and detects the ES version", func() {
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.index).To(Equal("go-commons-test"))
+			Expect(indexer.ServerVersion().Number).To(Equal("8.11.1"))
+			Expect(indexer.ServerVersion().Major).To(Equal(8))
+		})
+
+		It("Is registered as elastic8", func() {
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			indexer, err := NewIndexer(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer).To(BeAssignableToTypeOf(&Elastic8{}))
+			Expect(indexer.(*Elastic8).index).To(Equal("go-commons-test"))
+		})
+
+		It("Returns error status bad request", func() {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			}))
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("unexpected ES status code: 400")))
+		})
+
+		It("Returns err when the server isn't Elasticsearch", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err.Error()).To(ContainSubstring("the client noticed that the server is not Elasticsearch"))
+		})
+
+		It("Returns err no servers", func() {
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
+		It("Returns err no index name", func() {
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.Index = ""
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Sanitizes the index name when enabled", func() {
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.Index = "_Go Commons,Test"
+			testcase.indexerConfig.AutoSanitize = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.index).To(Equal("go_commons_test"))
+		})
+
+		It("Authenticates with basic auth", func() {
+			var authorization string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.Username = "user"
+			testcase.indexerConfig.Password = "secret"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(authorization).To(Equal("Basic dXNlcjpzZWNyZXQ="))
+		})
+
+		It("Authenticates with the API key over basic auth", func() {
+			var authorization string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.Username = "user"
+			testcase.indexerConfig.Password = "secret"
+			testcase.indexerConfig.APIKey = "YXBpLWtleQ=="
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(authorization).To(Equal("APIKey YXBpLWtleQ=="))
+		})
+
+		It("Creates the index with its mappings and lifecycle policy when it doesn't exist", func() {
+			var created []string
+			var body []byte
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case http.MethodPut:
+					created = append(created, r.URL.Path)
+					body, _ = io.ReadAll(r.Body)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.IndexMappings = json.RawMessage(`{"mappings":{"properties":{"value":{"type":"long"}}}}`)
+			testcase.indexerConfig.ILMPolicy = "go-commons"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(created).To(Equal([]string{"/go-commons-test"}))
+			Expect(body).To(MatchJSON(`{
+				"mappings":{"properties":{"value":{"type":"long"}}},
+				"settings":{"index.lifecycle.name":"go-commons"}
+			}`))
+		})
+
+		It("Creates a data stream and its index template when enabled", func() {
+			var created []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case http.MethodPut:
+					created = append(created, r.URL.Path)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.UseDataStream = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(created).To(Equal([]string{"/_index_template/go-commons-test", "/_data_stream/go-commons-test"}))
+		})
+
+		It("Returns err missing index when index creation is disabled", func() {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.SkipIndexCreation = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(MatchError("index go-commons-test not found on ES and index creation is disabled"))
+		})
+
+		It("Returns err negative number of documents per flush", func() {
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.FlushDocs = -1
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(MatchError("invalid number of documents per flush: -1"))
+		})
+
+		It("Returns err when the health check times out", func() {
+			release := make(chan struct{})
+			slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			}))
+			defer slowServer.Close()
+			defer close(release)
+			testcase.indexerConfig.Servers = []string{slowServer.URL}
+			testcase.indexerConfig.HealthCheckTimeout = 100 * time.Millisecond
+			start := time.Now()
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("ES health check failed: timed out after 100ms")))
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		})
+
+		It("Skips the health check when disabled", func() {
+			var paths []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				if r.URL.Path == "/_cluster/health" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.SkipHealthCheck = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(paths).NotTo(ContainElement("/_cluster/health"))
+			Expect(indexer.ServerVersion()).To(Equal(ServerVersion{}))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer Elastic8
+		var mockServer *httptest.Server
+		BeforeEach(func() {
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					3.14,
+					false,
+					struct {
+						Name string
+						Age  int
+					}{
+						Name: "John Doe",
+						Age:  25,
+					},
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+						"key3": true,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+			mockServer = newES8MockServer(func(n int) int { return http.StatusCreated })
+			DeferCleanup(mockServer.Close)
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+		})
+
+		It("No err returned", func() {
+			msg, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("created=6"))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(result.Stats).To(BeEmpty())
+		})
+
+		It("err returned docs not processed", func() {
+			testcase.documents = append(testcase.documents, make(chan string))
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err.Error()).To(ContainSubstring("Cannot encode document"))
+		})
+
+		It("Reports documents that failed to be indexed", func() {
+			failingServer := newES8MockServer(func(n int) int {
+				if n%2 == 0 {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			})
+			defer failingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{failingServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(result.Failed).To(Equal(3))
+			Expect(result.Stats).To(HaveKeyWithValue("mapper_parsing_exception", 3))
+		})
+
+		It("Returns err in fail-fast mode when a document fails to be indexed", func() {
+			failingServer := newES8MockServer(func(n int) int {
+				if n == 0 {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			})
+			defer failingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{failingServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.FailFast = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(ErrDocumentsFailed))
+			Expect(result.Failed).To(Equal(1))
+		})
+
+		It("Returns the context error when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := indexer.Index(ctx, testcase.documents, testcase.opts)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("Returns the structured indexing result and the bulk indexer stats", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(6))
+			Expect(result.Skipped).To(Equal(1))
+			Expect(result.Duration).To(BeNumerically(">", 0))
+			Expect(result.BulkStats.NumAdded).To(BeEquivalentTo(6))
+			Expect(result.BulkStats.NumFlushed).To(BeEquivalentTo(6))
+			Expect(result.BulkStats.NumRequests).To(BeNumerically(">", 0))
+			Expect(result.FlushLatency.Flushes).To(BeEquivalentTo(result.BulkStats.NumRequests))
+		})
+
+		It("Reports the stats of every call when reusing the bulk indexer", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", ReuseBulkIndexer: true})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents[:2], testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.BulkStats.NumAdded).To(BeEquivalentTo(2))
+			result, err = indexer.IndexWithResult(context.Background(), testcase.documents[2:], testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(4))
+			Expect(result.BulkStats.NumFlushed).To(BeEquivalentTo(4))
+			Expect(indexer.reused.indexers).To(HaveLen(1))
+			Expect(indexer.Close()).To(Succeed())
+			Expect(indexer.reused.indexers).To(BeEmpty())
+		})
+
+		It("Flushes the bulk indexer every FlushDocs documents", func() {
+			var queries []url.Values
+			countingServer := httptest.NewServer(recordBulkQueries(mockServer.Config.Handler, &queries))
+			defer countingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{countingServer.URL}, Index: "go-commons-test", FlushDocs: 4})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			Expect(queries).To(HaveLen(2))
+			Expect(result.BulkStats.NumRequests).To(BeEquivalentTo(2))
+		})
+
+		It("Uses the create action and sets the timestamp when indexing in a data stream", func() {
+			var lines []map[string]interface{}
+			recordingServer := httptest.NewServer(recordBulkLines(mockServer.Config.Handler, &lines))
+			defer recordingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{recordingServer.URL}, Index: "go-commons-test", UseDataStream: true})
+			Expect(err).To(BeNil())
+			testcase.documents = []interface{}{
+				map[string]interface{}{"value": 1},
+				map[string]interface{}{"value": 2, "@timestamp": "2023-06-01T10:00:00Z"},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(HaveKey("create"))
+			Expect(lines[1]).To(HaveKey("@timestamp"))
+			Expect(lines[3]).To(HaveKeyWithValue("@timestamp", "2023-06-01T10:00:00Z"))
+		})
+
+		It("Routes the documents with the configured routing field", func() {
+			var lines []map[string]interface{}
+			recordingServer := httptest.NewServer(recordBulkLines(mockServer.Config.Handler, &lines))
+			defer recordingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{recordingServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			testcase.documents = []interface{}{
+				map[string]interface{}{"shard": "a", "value": 1},
+				map[string]interface{}{"value": 2},
+			}
+			testcase.opts.RoutingField = "shard"
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.BulkStats.NumRequests).To(BeEquivalentTo(1))
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(HaveKeyWithValue("index", HaveKeyWithValue("routing", "a")))
+			Expect(lines[2]).To(HaveKeyWithValue("index", Not(HaveKey("routing"))))
+		})
+
+		It("Versions the documents with the configured version field", func() {
+			var lock sync.Mutex
+			var actions []map[string]map[string]interface{}
+			versions := make(map[string]int64)
+			versionServer := httptest.NewServer(es8Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+					mockServer.Config.Handler.ServeHTTP(w, r)
+					return
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				var items []string
+				scanner := bufio.NewScanner(r.Body)
+				for line := 0; scanner.Scan(); line++ {
+					if line%2 != 0 {
+						continue
+					}
+					var action map[string]map[string]interface{}
+					Expect(json.Unmarshal(scanner.Bytes(), &action)).To(Succeed())
+					actions = append(actions, action)
+					meta := action["index"]
+					id := meta["_id"].(string)
+					version := int64(meta["version"].(float64))
+					if stored, exists := versions[id]; exists && version <= stored {
+						items = append(items, fmt.Sprintf(`{"index":{"_id":"%s","status":409,"error":{"type":"version_conflict_engine_exception","reason":"version conflict"}}}`, id))
+						continue
+					}
+					versions[id] = version
+					items = append(items, fmt.Sprintf(`{"index":{"_id":"%s","status":201,"result":"created"}}`, id))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+			})))
+			defer versionServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{versionServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			opts := IndexingOpts{DocumentIDField: "uuid", VersionField: "generation"}
+			documents := []interface{}{map[string]interface{}{"uuid": "1234", "generation": 2}}
+			result, err := indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(actions[0]["index"]).To(HaveKeyWithValue("version", BeNumerically("==", 2)))
+			Expect(actions[0]["index"]).To(HaveKeyWithValue("version_type", "external"))
+			documents = []interface{}{map[string]interface{}{"uuid": "1234", "generation": 1}}
+			result, err = indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(1))
+			Expect(result.Stats).To(HaveKeyWithValue("version_conflict_engine_exception", 1))
+		})
+
+		It("Forwards the refresh and pipeline parameters to the bulk requests", func() {
+			var queries []url.Values
+			recordingServer := httptest.NewServer(recordBulkQueries(mockServer.Config.Handler, &queries))
+			defer recordingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{recordingServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.Refresh = "wait_for"
+			testcase.opts.Pipeline = "go-commons"
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(queries).NotTo(BeEmpty())
+			Expect(queries[0].Get("refresh")).To(Equal("wait_for"))
+			Expect(queries[0].Get("pipeline")).To(Equal("go-commons"))
+		})
+
+		It("Compresses the bulk requests when compression is enabled", func() {
+			var encodings []string
+			compressingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					encodings = append(encodings, r.Header.Get("Content-Encoding"))
+				}
+				mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer compressingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{compressingServer.URL}, Index: "go-commons-test", Compression: true})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			Expect(encodings).To(ConsistOf("gzip"))
+		})
+
+		It("Skips and counts the documents exceeding the maximum document size", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", MaxDocBytes: 64})
+			Expect(err).To(BeNil())
+			documents := []interface{}{
+				map[string]interface{}{"value": 1},
+				map[string]interface{}{"value": strings.Repeat("x", 64)},
+				map[string]interface{}{"value": 2},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.Stats).To(HaveKeyWithValue("oversized", 1))
+		})
+
+		It("Indexes the documents received from a channel", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushDocs: 10})
+			Expect(err).To(BeNil())
+			documents := make(chan interface{})
+			go func() {
+				defer close(documents)
+				for i := 0; i < 25; i++ {
+					documents <- map[string]interface{}{"value": i}
+				}
+			}()
+			var streamIndexer StreamIndexer = &indexer
+			msg, err := streamIndexer.IndexStream(context.Background(), documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("created=25"))
+			Expect(msg).To(ContainSubstring("requests=3"))
+		})
+
+		It("Indexes the raw JSON lines of an NDJSON reader", func() {
+			var ndjsonIndexer NDJSONIndexer = &indexer
+			msg, err := ndjsonIndexer.IndexNDJSON(context.Background(), strings.NewReader("{\"value\":1}\n\n{\"value\":2}\n{\"value\":1}"), IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("created=2"))
+			Expect(msg).To(ContainSubstring("skipped=1"))
+			_, err = indexer.IndexNDJSON(context.Background(), strings.NewReader("{\"value\":1}\n{\"value\":"), IndexingOpts{})
+			Expect(err).To(MatchError("invalid JSON document on line 2"))
+		})
+
+		It("Reads the indexed documents back", func() {
+			document := map[string]interface{}{"uuid": "1234", "value": 1}
+			_, err := indexer.Index(context.Background(), []interface{}{document}, IndexingOpts{DocumentIDField: "uuid"})
+			Expect(err).To(BeNil())
+			var reader DocumentReader = &indexer
+			source, err := reader.Get(context.Background(), "1234")
+			Expect(err).To(BeNil())
+			Expect(source).To(MatchJSON(`{"uuid":"1234","value":1}`))
+			count, err := reader.Count(context.Background())
+			Expect(err).To(BeNil())
+			Expect(count).To(Equal(1))
+			_, err = reader.Get(context.Background(), "5678")
+			Expect(err).To(MatchError(ErrDocumentNotFound))
+		})
+
+		It("Doesn't send any request in dry-run mode", func() {
+			var queries []url.Values
+			recordingServer := httptest.NewServer(recordBulkQueries(mockServer.Config.Handler, &queries))
+			defer recordingServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{recordingServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.DryRun = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(Equal(map[string]int{"validated": len(testcase.documents)}))
+			Expect(queries).To(BeEmpty())
+		})
+
+		It("Transforms and skips the unencodable documents before indexing them", func() {
+			documents := []interface{}{map[string]interface{}{"value": 1}, make(chan string), map[string]interface{}{"value": 2}}
+			opts := IndexingOpts{
+				SkipUnencodable: true,
+				Transform: func(document interface{}) (interface{}, error) {
+					if m, ok := document.(map[string]interface{}); ok && m["value"] == 2 {
+						return nil, fmt.Errorf("unexpected value")
+					}
+					return document, nil
+				},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Stats).To(HaveKeyWithValue("unencodable", 1))
+			Expect(result.Stats).To(HaveKeyWithValue("transformFailed", 1))
+		})
+
+		It("Captures the reasons of the failed documents", func() {
+			reason := "failed to parse field [value] of type [long], preview of field's value: '" + strings.Repeat("x", 300) + "'"
+			mockServer := httptest.NewServer(es8Handler(failingBulkHandler([]FailureReason{
+				{Status: http.StatusBadRequest, Type: "mapper_parsing_exception", Reason: reason},
+				{Status: http.StatusTooManyRequests, Type: "es_rejected_execution_exception", Reason: "rejected execution of coordinating operation"},
+				{Status: http.StatusConflict, Type: "version_conflict_engine_exception", Reason: "version conflict, document already exists"},
+			})))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(3))
+			Expect(result.FailureReasons).To(BeEmpty())
+			testcase.opts.MaxFailureReasons = 2
+			result, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(3))
+			Expect(result.FailureReasons).To(HaveLen(2))
+			Expect(result.FailureReasons[0].DocumentID).NotTo(BeEmpty())
+			Expect(result.FailureReasons[0].Status).To(Equal(http.StatusBadRequest))
+			Expect(result.FailureReasons[0].Type).To(Equal("mapper_parsing_exception"))
+			Expect(result.FailureReasons[0].Reason).To(HaveLen(256))
+			Expect(result.FailureReasons[0].Reason).To(HavePrefix("failed to parse field [value] of type [long]"))
+			Expect(result.FailureReasons[0].Reason).To(HaveSuffix("xxx..."))
+			Expect(result.FailureReasons[1]).To(Equal(FailureReason{
+				DocumentID: result.FailureReasons[1].DocumentID,
+				Status:     http.StatusTooManyRequests,
+				Type:       "es_rejected_execution_exception",
+				Reason:     "rejected execution of coordinating operation",
+			}))
+			testcase.opts.MaxFailureReasons = -1
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError("invalid maximum number of failure reasons: -1"))
+		})
+
+		Context("Flushing on interval", func() {
+			var ticks chan time.Time
+			BeforeEach(func() {
+				err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushInterval: time.Minute})
+				Expect(err).To(BeNil())
+				ticks = make(chan time.Time)
+				indexer.background.newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+					return ticks, func() {}
+				}
+			})
+
+			It("Queues the documents until Close", func() {
+				result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(BeNil())
+				Expect(result.Stats[queuedStat]).To(Equal(len(testcase.documents)))
+				Expect(result.Created).To(Equal(0))
+				count, err := indexer.Count(context.Background())
+				Expect(err).To(BeNil())
+				Expect(count).To(BeZero())
+				Expect(indexer.Close()).To(Succeed())
+				count, err = indexer.Count(context.Background())
+				Expect(err).To(BeNil())
+				Expect(count).To(Equal(len(testcase.documents)))
+			})
+
+			It("Returns err fail fast with a flush interval", func() {
+				testcase.opts.FailFast = true
+				_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(MatchError("fail fast isn't supported when flushing on interval"))
+			})
+
+			It("Returns err failure reasons with a flush interval", func() {
+				testcase.opts.MaxFailureReasons = 10
+				_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(MatchError("failure reasons aren't supported when flushing on interval"))
+			})
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer Elastic8
+		var status int
+		var mockServer *httptest.Server
+		BeforeEach(func() {
+			status = http.StatusOK
+			mockServer = httptest.NewServer(es8Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/_cluster/health" {
+					w.WriteHeader(status)
+				}
+				_, _ = w.Write(payload)
+			})))
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+		})
+		AfterEach(func() {
+			mockServer.Close()
+		})
+
+		It("Succeeds when the cluster is healthy", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Returns err unauthorized when the credentials are rejected", func() {
+			status = http.StatusUnauthorized
+			err := indexer.Ping(context.Background())
+			Expect(err).To(MatchError(ErrUnauthorized))
+			Expect(err).To(MatchError("ES backend rejected the credentials: status code 401"))
+		})
+
+		It("Returns err unhealthy when the cluster fails", func() {
+			status = http.StatusServiceUnavailable
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnhealthy))
+		})
+
+		It("Returns err unreachable when the cluster can't be reached", func() {
+			mockServer.Close()
+			err := indexer.Ping(context.Background())
+			Expect(err).To(MatchError(ErrUnreachable))
+		})
+	})
+})
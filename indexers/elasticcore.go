@@ -0,0 +1,883 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+)
+
+// esClient client of the Elasticsearch indexers, performing the REST API requests and creating the bulk indexers
+// whatever the version of the go-elasticsearch client behind it
+type esClient interface {
+	esapi.Transport
+	newBulkIndexer(config esutil.BulkIndexerConfig) (esutil.BulkIndexer, error)
+}
+
+// elasticCore indexing logic shared by the Elasticsearch indexers, which only set up their client
+type elasticCore struct {
+	index             string
+	bulkTimeout       time.Duration
+	flushBytes        int
+	flushDocs         int
+	maxDocBytes       int
+	numWorkers        int
+	skipIndexCreation bool
+	autoSanitize      bool
+	indexMappings     json.RawMessage
+	useDataStream     bool
+	indexAlias        string
+	atomicStats       bool
+	logger            Logger
+	client            esClient
+	transport         http.RoundTripper
+	breaker           *circuitBreaker
+	metrics           *indexingMetrics
+	flushes           flushSemaphore
+	responseStats     *compressionStats
+	version           ServerVersion
+	background        *backgroundBatch
+	bulkIndexer       esutil.BulkIndexer
+	bulkDocs          int
+	bulkStats         BulkStats
+	reused            *reusedBulkIndexers
+}
+
+// configure validates the given configuration and returns the index name along with the transport of the ES
+// client, which decompresses the responses when compression is enabled
+func (esIndexer *elasticCore) configure(indexerConfig IndexerConfig) (string, http.RoundTripper, error) {
+	var err error
+	if indexerConfig.Index == "" {
+		return "", nil, fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 && !indexerConfig.AllowEnvFallback {
+		return "", nil, fmt.Errorf("servers not specified")
+	}
+	esIndexer.logger = loggerOrNop(indexerConfig.Logger)
+	if indexerConfig.NumWorkers < 0 {
+		return "", nil, fmt.Errorf("invalid number of workers: %d", indexerConfig.NumWorkers)
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return "", nil, fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	if indexerConfig.FlushTimeout < 0 {
+		return "", nil, fmt.Errorf("invalid flush timeout: %s", indexerConfig.FlushTimeout)
+	}
+	if indexerConfig.MaxDocBytes < 0 {
+		return "", nil, fmt.Errorf("invalid maximum document size: %d", indexerConfig.MaxDocBytes)
+	}
+	if indexerConfig.MaxConcurrentFlushes < 0 {
+		return "", nil, fmt.Errorf("invalid number of concurrent flushes: %d", indexerConfig.MaxConcurrentFlushes)
+	}
+	if esIndexer.breaker, err = newCircuitBreaker(indexerConfig); err != nil {
+		return "", nil, err
+	}
+	if esIndexer.background, err = newBackgroundBatch(indexerConfig); err != nil {
+		return "", nil, err
+	}
+	esIndexer.bulkIndexer, esIndexer.bulkDocs, esIndexer.bulkStats = nil, 0, BulkStats{}
+	reuse, err := newBulkReuse(indexerConfig)
+	if err != nil {
+		return "", nil, err
+	}
+	esIndexer.reused = nil
+	if reuse != nil {
+		esIndexer.reused = &reusedBulkIndexers{bulkReuse: reuse, indexers: make(map[reusedBulkKey]esutil.BulkIndexer)}
+	}
+	esIndex := strings.ToLower(indexerConfig.Index)
+	if indexerConfig.AutoSanitize {
+		esIndex = sanitizeIndexName(esIndex)
+	}
+	if err := validateIndexName(esIndex); err != nil {
+		return "", nil, err
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return "", nil, err
+	}
+	// The client compresses the requests, the transport takes care of the responses
+	esIndexer.responseStats = nil
+	if indexerConfig.Compression {
+		esIndexer.responseStats = &compressionStats{}
+		transport = gzipResponseTransport{Transport: transport, stats: esIndexer.responseStats}
+	}
+	if indexerConfig.FlushTimeout > 0 {
+		transport = flushTimeoutTransport{Transport: transport, timeout: indexerConfig.FlushTimeout}
+	}
+	return esIndex, transport, nil
+}
+
+// connect checks the cluster health and detects its version with the given client, unless the health check is
+// skipped
+func (esIndexer *elasticCore) connect(client esClient, transport http.RoundTripper, indexerConfig IndexerConfig) error {
+	esIndexer.client, esIndexer.transport = client, transport
+	if indexerConfig.SkipHealthCheck {
+		esIndexer.logger.Debugf("ES health check skipped")
+		esIndexer.version = ServerVersion{}
+		return nil
+	}
+	if err := esIndexer.healthCheck(indexerConfig.HealthCheckTimeout); err != nil {
+		return err
+	}
+	return esIndexer.detectVersion(indexerConfig.HealthCheckTimeout)
+}
+
+// setup applies the bulk and index settings of the given configuration and creates the given index
+func (esIndexer *elasticCore) setup(indexerConfig IndexerConfig, esIndex string) error {
+	var err error
+	esIndexer.bulkTimeout = indexerConfig.BulkTimeout
+	if esIndexer.bulkTimeout == 0 {
+		esIndexer.bulkTimeout = defaultBulkTimeout
+	}
+	esIndexer.flushBytes = indexerConfig.FlushBytes
+	if esIndexer.flushBytes <= 0 {
+		esIndexer.flushBytes = defaultFlushBytes
+	}
+	esIndexer.flushDocs = indexerConfig.FlushDocs
+	esIndexer.maxDocBytes = indexerConfig.MaxDocBytes
+	esIndexer.atomicStats = indexerConfig.AtomicStats
+	esIndexer.flushes = newFlushSemaphore(indexerConfig.MaxConcurrentFlushes)
+	esIndexer.numWorkers = indexerConfig.NumWorkers
+	if esIndexer.numWorkers == 0 {
+		esIndexer.numWorkers = runtime.NumCPU()
+	}
+	esIndexer.skipIndexCreation = indexerConfig.SkipIndexCreation
+	esIndexer.autoSanitize = indexerConfig.AutoSanitize
+	if esIndexer.indexMappings, err = withLifecyclePolicy(indexerConfig.IndexMappings, "index.lifecycle.name", indexerConfig.ILMPolicy); err != nil {
+		return err
+	}
+	esIndexer.useDataStream = indexerConfig.UseDataStream
+	if indexerConfig.IndexAlias != "" && indexerConfig.UseDataStream {
+		return fmt.Errorf("index alias isn't supported with data streams")
+	}
+	esIndexer.indexAlias = indexerConfig.IndexAlias
+	if esIndexer.metrics, err = newIndexingMetrics(indexerConfig, esIndex); err != nil {
+		return err
+	}
+	// Data streams and composable index templates were introduced in ES 7.9
+	if esIndexer.useDataStream && esIndexer.version.Number != "" && !esIndexer.version.atLeast(7, 9) {
+		return fmt.Errorf("data streams require ES 7.9 or later, found %s", esIndexer.version.Number)
+	}
+	esIndexer.index = esIndex
+	return esIndexer.createIndex(context.Background(), esIndex)
+}
+
+// healthCheck checks the cluster health, giving up when the cluster doesn't answer within the timeout
+func (esIndexer *elasticCore) healthCheck(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	r, err := esapi.ClusterHealthRequest{}.Do(ctx, esIndexer.client)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		esIndexer.logger.Errorf("ES health check failed: %s", err)
+		return fmt.Errorf("ES health check failed: %s", err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != 200 {
+		esIndexer.logger.Errorf("ES health check failed with status code %d", r.StatusCode)
+		return fmt.Errorf("unexpected ES status code: %d", r.StatusCode)
+	}
+	esIndexer.logger.Debugf("ES health check succeeded: %s", r.String())
+	return nil
+}
+
+// detectVersion detects the cluster version
+func (esIndexer *elasticCore) detectVersion(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	r, err := esapi.InfoRequest{}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return fmt.Errorf("error detecting the ES version: %s", err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		return fmt.Errorf("error detecting the ES version: %s", r.String())
+	}
+	if esIndexer.version, err = decodeServerVersion(r.Body); err != nil {
+		return fmt.Errorf("error detecting the ES version: %s", err)
+	}
+	if esIndexer.version.Distribution != elasticsearchDistribution {
+		esIndexer.logger.Infof("ES indexer connected to %s %s", esIndexer.version.Distribution, esIndexer.version.Number)
+	}
+	esIndexer.logger.Debugf("ES version %s detected", esIndexer.version.Number)
+	return nil
+}
+
+// Ping checks the cluster is reachable by requesting its health
+func (esIndexer *elasticCore) Ping(ctx context.Context) error {
+	r, err := esapi.ClusterHealthRequest{}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return pingFailed("ES", ErrUnreachable, err)
+	}
+	defer r.Body.Close()
+	return pingStatus("ES", r.StatusCode)
+}
+
+// ServerVersion returns the version of the cluster detected when creating the indexer,
+// the zero value when the health check is skipped
+func (esIndexer *elasticCore) ServerVersion() ServerVersion {
+	return esIndexer.version
+}
+
+// createIndex creates the given index when it doesn't exist, unless index creation is disabled
+func (esIndexer *elasticCore) createIndex(ctx context.Context, index string) error {
+	logger := loggerOrNop(esIndexer.logger)
+	r, err := esapi.IndicesExistsRequest{Index: []string{index}}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return fmt.Errorf("error checking index %s on ES: %s", index, err)
+	}
+	r.Body.Close()
+	if r.IsError() {
+		if esIndexer.skipIndexCreation {
+			return fmt.Errorf("index %s not found on ES and index creation is disabled", index)
+		}
+		if esIndexer.useDataStream {
+			return esIndexer.createDataStream(ctx, index)
+		}
+		create := esapi.IndicesCreateRequest{Index: index}
+		if len(esIndexer.indexMappings) > 0 {
+			create.Body = bytes.NewReader(esIndexer.indexMappings)
+		}
+		r, err = create.Do(ctx, esIndexer.client)
+		if err != nil {
+			return fmt.Errorf("error creating index %s on ES: %s", index, err)
+		}
+		defer r.Body.Close()
+		if r.IsError() {
+			logger.Errorf("Error creating index %s on ES: %s", index, r.String())
+			return fmt.Errorf("error creating index %s on ES: %s", index, r.String())
+		}
+		logger.Infof("Index %s created on ES", index)
+		if esIndexer.indexAlias != "" && index == esIndexer.index {
+			return esIndexer.pointAlias(ctx, index)
+		}
+	}
+	return nil
+}
+
+// pointAlias points the index alias at the given index, moving it atomically when it's set on other indices
+func (esIndexer *elasticCore) pointAlias(ctx context.Context, index string) error {
+	logger := loggerOrNop(esIndexer.logger)
+	alias := esIndexer.indexAlias
+	r, err := esapi.IndicesGetAliasRequest{Name: []string{alias}}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return fmt.Errorf("error getting alias %s on ES: %s", alias, err)
+	}
+	defer r.Body.Close()
+	var aliases io.Reader
+	switch {
+	case r.StatusCode == http.StatusNotFound:
+	case r.IsError():
+		return fmt.Errorf("error getting alias %s on ES: %s", alias, r.String())
+	default:
+		aliases = r.Body
+	}
+	actions, err := aliasActions(alias, index, aliases)
+	if err != nil {
+		return err
+	}
+	r, err = esapi.IndicesUpdateAliasesRequest{Body: bytes.NewReader(actions)}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return fmt.Errorf("error pointing alias %s at index %s on ES: %s", alias, index, err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		logger.Errorf("Error pointing alias %s at index %s on ES: %s", alias, index, r.String())
+		return fmt.Errorf("error pointing alias %s at index %s on ES: %s", alias, index, r.String())
+	}
+	logger.Infof("Alias %s pointed at index %s on ES", alias, index)
+	return nil
+}
+
+// createDataStream creates the given data stream along with its backing index template
+func (esIndexer *elasticCore) createDataStream(ctx context.Context, name string) error {
+	logger := loggerOrNop(esIndexer.logger)
+	template, err := dataStreamTemplate(name, esIndexer.indexMappings)
+	if err != nil {
+		return err
+	}
+	r, err := esapi.IndicesPutIndexTemplateRequest{Name: name, Body: bytes.NewReader(template)}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return fmt.Errorf("error creating index template %s on ES: %s", name, err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		logger.Errorf("Error creating index template %s on ES: %s", name, r.String())
+		return fmt.Errorf("error creating index template %s on ES: %s", name, r.String())
+	}
+	r, err = esapi.IndicesCreateDataStreamRequest{Name: name}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return fmt.Errorf("error creating data stream %s on ES: %s", name, err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		logger.Errorf("Error creating data stream %s on ES: %s", name, r.String())
+		return fmt.Errorf("error creating data stream %s on ES: %s", name, r.String())
+	}
+	logger.Infof("Data stream %s created on ES", name)
+	return nil
+}
+
+// Index uses bulkIndexer to index the documents in the given index
+func (esIndexer *elasticCore) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := esIndexer.IndexWithResult(ctx, documents, opts)
+	return resultMessage(result, err)
+}
+
+// IndexWithResult uses bulkIndexer to index the documents in the given index and returns the indexing result. When
+// the indexing fails, the result of the documents indexed before the failure is returned along with the error
+func (esIndexer *elasticCore) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: make(map[string]int)}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, esIndexer.IndexWithResult)
+	}
+	return esIndexer.indexDocuments(ctx, encodeDocuments(ctx, documents, opts), opts)
+}
+
+// IndexStream uses bulkIndexer to index the documents received from the given channel until it's closed
+func (esIndexer *elasticCore) IndexStream(ctx context.Context, documents <-chan interface{}, opts IndexingOpts) (string, error) {
+	if err := validateSampleRate(opts.SampleRate); err != nil {
+		return "", err
+	}
+	transformFailed := 0
+	filter := &documentFilter{opts: opts}
+	next := transformingIterator(channelIterator(ctx, documents), opts.Transform, &transformFailed)
+	result, err := esIndexer.indexDocuments(ctx, encodingIterator(filteringIterator(next, filter), opts), opts)
+	addTransformFailed(&result, transformFailed)
+	filter.addStats(&result)
+	return resultMessage(result, err)
+}
+
+// IndexNDJSON uses bulkIndexer to index the JSON lines read from r as they are, without decoding them into documents,
+// their IDs being computed from their raw bytes. The Transform of the indexing options isn't supported
+func (esIndexer *elasticCore) IndexNDJSON(ctx context.Context, r io.Reader, opts IndexingOpts) (string, error) {
+	if opts.Transform != nil {
+		return "", fmt.Errorf("transform isn't supported when indexing NDJSON")
+	}
+	result, err := esIndexer.indexDocuments(ctx, ndjsonIterator(ctx, r), opts)
+	return resultMessage(result, err)
+}
+
+// indexDocuments indexes the documents returned by next through the circuit breaker and returns the indexing result
+func (esIndexer *elasticCore) indexDocuments(ctx context.Context, next encodedIterator, opts IndexingOpts) (IndexingResult, error) {
+	if opts.DryRun {
+		return dryRun(next, opts)
+	}
+	if err := validateRefresh(opts.Refresh); err != nil {
+		return IndexingResult{}, err
+	}
+	if opts.MaxFailureReasons < 0 {
+		return IndexingResult{}, fmt.Errorf("invalid maximum number of failure reasons: %d", opts.MaxFailureReasons)
+	}
+	var err error
+	if opts.Action, err = bulkAction(opts.Action, esIndexer.useDataStream); err != nil {
+		return IndexingResult{}, err
+	}
+	if opts.VersionType, err = versionType(opts); err != nil {
+		return IndexingResult{}, err
+	}
+	if esIndexer.background != nil && (opts.Refresh != "" || opts.Pipeline != "") {
+		return IndexingResult{}, fmt.Errorf("refresh and pipeline aren't supported when flushing on interval")
+	}
+	if esIndexer.background != nil && opts.FailFast {
+		return IndexingResult{}, fmt.Errorf("fail fast isn't supported when flushing on interval")
+	}
+	if esIndexer.background != nil && opts.MaxFailureReasons > 0 {
+		return IndexingResult{}, fmt.Errorf("failure reasons aren't supported when flushing on interval")
+	}
+	result, err := esIndexer.breaker.call(func() (IndexingResult, error) {
+		return esIndexer.bulkIndex(ctx, next, opts)
+	})
+	// The outcome of the queued documents is observed when flushing them
+	if esIndexer.background == nil || err != nil {
+		esIndexer.metrics.observe(result, err)
+	}
+	if err == nil && opts.FailFast {
+		err = failedDocumentsError(result)
+	}
+	return result, err
+}
+
+// bulkIndex uses bulkIndexer to index the documents returned by next in the given index and returns the indexing result
+func (esIndexer *elasticCore) bulkIndex(ctx context.Context, next encodedIterator, opts IndexingOpts) (IndexingResult, error) {
+	logger := loggerOrNop(esIndexer.logger)
+	indexerStats := newItemStats(esIndexer.atomicStats)
+	failures := newFailureReasons(opts.MaxFailureReasons)
+
+	now := time.Now()
+	index := esIndexer.index
+	if opts.TimeBasedSuffix != "" {
+		index = timeBasedIndex(index, opts.TimeBasedSuffix, now)
+		if err := esIndexer.createIndex(ctx, index); err != nil {
+			return IndexingResult{}, err
+		}
+	}
+	biConfig := esIndexer.bulkIndexerConfig()
+	biConfig.Index = index
+	biConfig.Refresh = opts.Refresh
+	biConfig.Pipeline = opts.Pipeline
+	latencies := &flushLatencies{}
+	var session *reusedBulkSession
+	if esIndexer.reused != nil {
+		// The reused bulk indexers record the flush durations themselves
+		latencies = &esIndexer.reused.latencies
+		session = esIndexer.reused.session(esIndexer.client, biConfig)
+	} else {
+		biConfig.OnFlushStart, biConfig.OnFlushEnd = latencies.hooks(biConfig.OnFlushStart, biConfig.OnFlushEnd)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	// Indices known to exist, target indices being created on demand
+	ensuredIndices := map[string]bool{index: true}
+	var bulkStats BulkStats
+	// A new bulk indexer is used every flushDocs documents, forcing a flush
+	var bi esutil.BulkIndexer
+	batchDocs := 0
+	if esIndexer.background != nil {
+		// The documents are queued in the bulk indexer held open between calls
+		indexerStats = esIndexer.background.stats
+	}
+	queued, oversized := 0, 0
+	// partial closes the pending bulk indexer and returns the result of the documents indexed so far along with err
+	partial := func(err error) (IndexingResult, error) {
+		if bi != nil {
+			_ = bi.Close(ctx)
+		}
+		if session != nil {
+			_ = session.close(ctx)
+			bulkStats = session.stats
+		}
+		if esIndexer.background != nil {
+			return IndexingResult{}, err
+		}
+		result := newIndexingResult(indexerStats.snapshot(), redundantSkipped, time.Since(start))
+		addOversized(&result, oversized)
+		result.FailureReasons = failures.list()
+		result.BulkStats = bulkStats
+		return result, err
+	}
+	for {
+		encoded, ok, err := next()
+		if err != nil {
+			return partial(err)
+		}
+		if !ok {
+			break
+		}
+		j, docHashKey := encoded.j, encoded.hash
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		docId := documentID(j, opts.DocumentIDField, docHashKey)
+		routing, _ := documentField(j, opts.RoutingField)
+		version, err := documentVersion(j, opts.VersionField)
+		if err != nil {
+			return partial(fmt.Errorf("Cannot version document %s: %s", docId, err))
+		}
+		itemVersionType := ""
+		if version != nil {
+			itemVersionType = opts.VersionType
+		}
+		itemIndex, exists, err := documentIndex(j, opts, esIndexer.autoSanitize, now)
+		if err != nil {
+			return partial(err)
+		}
+		if exists && !ensuredIndices[itemIndex] {
+			if err := esIndexer.createIndex(ctx, itemIndex); err != nil {
+				return partial(err)
+			}
+			ensuredIndices[itemIndex] = true
+		}
+		if j, err = decorateDocument(j, fields); err != nil {
+			return partial(err)
+		}
+		if esIndexer.useDataStream {
+			if j, err = withTimestamp(j, dataStreamTimestampField, time.Now()); err != nil {
+				return partial(err)
+			}
+		}
+		if opts.Action == "update" {
+			j = updateBody(j)
+		}
+		if esIndexer.maxDocBytes > 0 && len(j) > esIndexer.maxDocBytes {
+			logger.Errorf("Document %s skipped, its size of %d bytes exceeds %d bytes", docId, len(j), esIndexer.maxDocBytes)
+			oversized++
+			continue
+		}
+		reportDocumentID(opts, encoded.document, docId)
+		item := esutil.BulkIndexerItem{
+			Index:       itemIndex,
+			Action:      opts.Action,
+			Body:        bytes.NewReader(j),
+			DocumentID:  docId,
+			Routing:     routing,
+			Version:     version,
+			VersionType: itemVersionType,
+			OnSuccess: func(c context.Context, bii esutil.BulkIndexerItem, biri esutil.BulkIndexerResponseItem) {
+				indexerStats.add(biri.Result)
+			},
+			OnFailure: func(c context.Context, bii esutil.BulkIndexerItem, biri esutil.BulkIndexerResponseItem, err error) {
+				indexerStats.add("failed")
+				if biri.Error.Type != "" {
+					indexerStats.add(biri.Error.Type)
+				}
+				if err != nil {
+					failures.add(bii.DocumentID, 0, "", err.Error())
+					logger.Errorf("Error indexing document %s: %s", bii.DocumentID, err)
+				} else {
+					failures.add(bii.DocumentID, biri.Status, biri.Error.Type, biri.Error.Reason)
+					logger.Errorf("Error indexing document %s: %s: %s", bii.DocumentID, biri.Error.Type, biri.Error.Reason)
+				}
+			},
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		if esIndexer.background != nil {
+			if err := esIndexer.addBackground(ctx, item); err != nil {
+				return partial(err)
+			}
+			queued++
+			continue
+		}
+		if session != nil {
+			if err := session.add(ctx, item); err != nil {
+				return partial(err)
+			}
+			continue
+		}
+		if bi == nil {
+			if bi, err = esIndexer.newBulkIndexer(biConfig); err != nil {
+				return partial(fmt.Errorf("Error creating the indexer: %s", err))
+			}
+		}
+		if err := bi.Add(ctx, item); err != nil {
+			return partial(fmt.Errorf("Unexpected ES indexing error: %s", err))
+		}
+		if batchDocs++; batchDocs == esIndexer.flushDocs {
+			if err := bi.Close(ctx); err != nil {
+				bi = nil
+				return partial(fmt.Errorf("Unexpected ES error: %s", err))
+			}
+			bulkStats.add(BulkStats(bi.Stats()))
+			bi, batchDocs = nil, 0
+		}
+	}
+	if esIndexer.background != nil {
+		result := newIndexingResult(map[string]int{queuedStat: queued}, redundantSkipped, time.Since(start))
+		addOversized(&result, oversized)
+		return result, nil
+	}
+	if bi != nil {
+		if err := bi.Close(ctx); err != nil {
+			bi = nil
+			return partial(fmt.Errorf("Unexpected ES error: %s", err))
+		}
+		bulkStats.add(BulkStats(bi.Stats()))
+	}
+	if session != nil {
+		if err := session.close(ctx); err != nil {
+			return partial(err)
+		}
+		bulkStats = session.stats
+	}
+	result := newIndexingResult(indexerStats.snapshot(), redundantSkipped, time.Since(start))
+	addOversized(&result, oversized)
+	result.FailureReasons = failures.list()
+	result.FlushLatency = latencies.percentiles()
+	result.BulkStats = bulkStats
+	return result, nil
+}
+
+// reusedBulkIndexers bulk indexers reused across the indexing calls, one per index, refresh and pipeline, closed
+// on Close
+type reusedBulkIndexers struct {
+	*bulkReuse
+	indexers map[reusedBulkKey]esutil.BulkIndexer
+}
+
+// reusedBulkSession reused bulk indexer used by an indexing call, holding the lock of the reused bulk indexers until
+// closed
+type reusedBulkSession struct {
+	reused *reusedBulkIndexers
+	client esClient
+	config esutil.BulkIndexerConfig
+	bi     esutil.BulkIndexer
+	// start statistics of the bulk indexer
+	start  BulkStats
+	added  uint64
+	stats  BulkStats
+	closed bool
+}
+
+// session locks the reused bulk indexers for an indexing call adding its documents with the given client and
+// configuration
+func (r *reusedBulkIndexers) session(client esClient, config esutil.BulkIndexerConfig) *reusedBulkSession {
+	r.Lock()
+	// Discard the flush durations left by a failed call
+	r.latencies.percentiles()
+	return &reusedBulkSession{reused: r, client: client, config: config}
+}
+
+// add adds the item to the reused bulk indexer, creating it when needed
+func (s *reusedBulkSession) add(ctx context.Context, item esutil.BulkIndexerItem) error {
+	if s.bi == nil {
+		key := reusedBulkKey{index: s.config.Index, refresh: s.config.Refresh, pipeline: s.config.Pipeline}
+		bi, exists := s.reused.indexers[key]
+		if !exists {
+			config := s.config
+			config.FlushInterval = reusedFlushInterval
+			config.OnFlushStart, config.OnFlushEnd = s.reused.hooks(config.OnFlushStart, config.OnFlushEnd)
+			var err error
+			if bi, err = s.client.newBulkIndexer(config); err != nil {
+				return fmt.Errorf("Error creating the indexer: %s", err)
+			}
+			s.reused.indexers[key] = bi
+		}
+		s.bi, s.start = bi, BulkStats(bi.Stats())
+	}
+	if err := s.bi.Add(ctx, item); err != nil {
+		return fmt.Errorf("Unexpected ES indexing error: %s", err)
+	}
+	s.added++
+	return nil
+}
+
+// close waits for the documents added to be flushed, accumulates the statistics of the call and releases the lock.
+// The bulk indexers are discarded when the wait fails, being closed in the background
+func (s *reusedBulkSession) close(ctx context.Context) error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	defer s.reused.Unlock()
+	if s.bi == nil {
+		return nil
+	}
+	err := s.reused.wait(ctx, func() bool {
+		stats := bulkStatsDelta(BulkStats(s.bi.Stats()), s.start)
+		return stats.NumFlushed+stats.NumFailed >= s.added
+	})
+	s.stats = bulkStatsDelta(BulkStats(s.bi.Stats()), s.start)
+	if err != nil {
+		for key, bi := range s.reused.indexers {
+			go bi.Close(context.Background())
+			delete(s.reused.indexers, key)
+		}
+		return fmt.Errorf("Unexpected ES error: %s", err)
+	}
+	return nil
+}
+
+// close closes the reused bulk indexers, flushing their documents
+func (r *reusedBulkIndexers) close(ctx context.Context) error {
+	r.Lock()
+	defer r.Unlock()
+	var err error
+	for key, bi := range r.indexers {
+		if closeErr := bi.Close(ctx); closeErr != nil && err == nil {
+			err = fmt.Errorf("Unexpected ES error: %s", closeErr)
+		}
+		delete(r.indexers, key)
+	}
+	return err
+}
+
+// newBulkIndexer returns a bulk indexer with the given configuration created by the client, the bulk indexer of the
+// zero value indexer falling back to the default v7 client
+func (esIndexer *elasticCore) newBulkIndexer(config esutil.BulkIndexerConfig) (esutil.BulkIndexer, error) {
+	if esIndexer.client == nil {
+		return esutil.NewBulkIndexer(config)
+	}
+	return esIndexer.client.newBulkIndexer(config)
+}
+
+// bulkIndexerConfig returns the configuration used to create the bulk indexer, the client being set when creating it
+func (esIndexer *elasticCore) bulkIndexerConfig() esutil.BulkIndexerConfig {
+	logger := loggerOrNop(esIndexer.logger)
+	return esutil.BulkIndexerConfig{
+		Index:      esIndexer.index,
+		FlushBytes: esIndexer.flushBytes,
+		NumWorkers: esIndexer.numWorkers,
+		Timeout:    esIndexer.bulkTimeout,
+		OnError: func(ctx context.Context, err error) {
+			logger.Errorf("Bulk indexer error: %s", err)
+		},
+		OnFlushStart: func(ctx context.Context) context.Context {
+			logger.Debugf("Bulk indexer flush started")
+			return esIndexer.flushes.acquire(ctx)
+		},
+		OnFlushEnd: func(ctx context.Context) {
+			esIndexer.flushes.release(ctx)
+			logger.Debugf("Bulk indexer flush finished")
+		},
+	}
+}
+
+// addBackground adds the item to the bulk indexer held open between the indexing calls, starting the interval flusher.
+// The bulk indexer is closed, forcing a flush, every flushDocs documents
+func (esIndexer *elasticCore) addBackground(ctx context.Context, item esutil.BulkIndexerItem) error {
+	esIndexer.background.Lock()
+	defer esIndexer.background.Unlock()
+	if esIndexer.bulkIndexer == nil {
+		biConfig := esIndexer.bulkIndexerConfig()
+		biConfig.OnFlushStart, biConfig.OnFlushEnd = esIndexer.background.latencies.hooks(biConfig.OnFlushStart, biConfig.OnFlushEnd)
+		bi, err := esIndexer.newBulkIndexer(biConfig)
+		if err != nil {
+			return fmt.Errorf("Error creating the indexer: %s", err)
+		}
+		esIndexer.bulkIndexer = bi
+	}
+	esIndexer.background.startFlusher(func() { _ = esIndexer.flushBackground() })
+	if err := esIndexer.bulkIndexer.Add(ctx, item); err != nil {
+		return fmt.Errorf("Unexpected ES indexing error: %s", err)
+	}
+	if esIndexer.bulkDocs++; esIndexer.bulkDocs == esIndexer.flushDocs {
+		return esIndexer.closeBulkIndexer(ctx)
+	}
+	return nil
+}
+
+// closeBulkIndexer closes the bulk indexer held open between the indexing calls and accumulates its statistics.
+// Must be called holding the background lock
+func (esIndexer *elasticCore) closeBulkIndexer(ctx context.Context) error {
+	bi := esIndexer.bulkIndexer
+	esIndexer.bulkIndexer, esIndexer.bulkDocs = nil, 0
+	if err := bi.Close(ctx); err != nil {
+		return fmt.Errorf("Unexpected ES error: %s", err)
+	}
+	esIndexer.bulkStats.add(BulkStats(bi.Stats()))
+	return nil
+}
+
+// flushBackground flushes the bulk indexer held open between the indexing calls, reporting the outcome of its documents
+func (esIndexer *elasticCore) flushBackground() error {
+	logger := loggerOrNop(esIndexer.logger)
+	esIndexer.background.Lock()
+	defer esIndexer.background.Unlock()
+	var err error
+	if esIndexer.bulkIndexer != nil {
+		err = esIndexer.closeBulkIndexer(context.Background())
+	}
+	if esIndexer.bulkStats.NumAdded == 0 && err == nil {
+		return nil
+	}
+	result := esIndexer.background.result()
+	result.BulkStats = esIndexer.bulkStats
+	esIndexer.bulkStats = BulkStats{}
+	esIndexer.metrics.observe(result, err)
+	if err != nil {
+		logger.Errorf("ES background flush failed: %s", err)
+		return err
+	}
+	logger.Debugf("ES background flush: %s", result)
+	return nil
+}
+
+// Get returns the source of the document with the given ID from the configured index, ErrDocumentNotFound when it
+// doesn't exist. The get API being real-time, the document is returned as soon as it's indexed. Data streams aren't
+// supported, as their documents are only read from their backing indices
+func (esIndexer *elasticCore) Get(ctx context.Context, id string) (json.RawMessage, error) {
+	if esIndexer.useDataStream {
+		return nil, fmt.Errorf("reading documents by ID isn't supported on data streams")
+	}
+	r, err := esapi.GetRequest{Index: esIndexer.index, DocumentID: id}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return nil, fmt.Errorf("error getting document %s from ES: %s", id, err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode == http.StatusNotFound {
+		return nil, ErrDocumentNotFound
+	}
+	if r.IsError() {
+		return nil, fmt.Errorf("error getting document %s from ES: %s", id, r.String())
+	}
+	var document struct {
+		Source json.RawMessage `json:"_source"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&document); err != nil {
+		return nil, fmt.Errorf("error decoding document %s from ES: %s", id, err)
+	}
+	return document.Source, nil
+}
+
+// Count returns the number of documents of the configured index. The documents indexed are only counted once the
+// index is refreshed, as with the wait_for refresh option
+func (esIndexer *elasticCore) Count(ctx context.Context) (int, error) {
+	r, err := esapi.CountRequest{Index: []string{esIndexer.index}}.Do(ctx, esIndexer.client)
+	if err != nil {
+		return 0, fmt.Errorf("error counting documents on ES: %s", err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		return 0, fmt.Errorf("error counting documents on ES: %s", r.String())
+	}
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&count); err != nil {
+		return 0, fmt.Errorf("error decoding document count from ES: %s", err)
+	}
+	return count.Count, nil
+}
+
+// CompressionStats returns the statistics of the gzip encoded responses received, when compression is enabled
+func (esIndexer *elasticCore) CompressionStats() CompressionStats {
+	return esIndexer.responseStats.snapshot()
+}
+
+// Close flushes the queued documents, closes the reused bulk indexers and closes the idle connections of the ES
+// client transport
+func (esIndexer *elasticCore) Close() error {
+	var err error
+	if esIndexer.background != nil {
+		esIndexer.background.stop()
+		err = esIndexer.flushBackground()
+	}
+	if esIndexer.reused != nil {
+		if closeErr := esIndexer.reused.close(context.Background()); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	closeIdleConnections(esIndexer.transport)
+	return err
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	// EnvIndexerType indexer type, i.e. elastic, opensearch or local
+	EnvIndexerType = "INDEXER_TYPE"
+	// EnvIndexerServers comma-separated list of servers
+	EnvIndexerServers = "INDEXER_SERVERS"
+	// EnvIndexerIndex index to send the documents to
+	EnvIndexerIndex = "INDEXER_INDEX"
+	// EnvIndexerUsername username used for basic authentication
+	EnvIndexerUsername = "INDEXER_USERNAME"
+	// EnvIndexerPassword password used for basic authentication
+	EnvIndexerPassword = "INDEXER_PASSWORD"
+	// EnvIndexerAPIKey base64 encoded API key
+	EnvIndexerAPIKey = "INDEXER_API_KEY"
+	// EnvIndexerInsecureSkipVerify disable TLS certificate verification when true
+	EnvIndexerInsecureSkipVerify = "INDEXER_INSECURE_SKIP_VERIFY"
+	// EnvIndexerCACertPath path of the PEM encoded CA certificates
+	EnvIndexerCACertPath = "INDEXER_CA_CERT_PATH"
+	// EnvIndexerClientCertPath path of the PEM encoded client certificate used for mutual TLS
+	EnvIndexerClientCertPath = "INDEXER_CLIENT_CERT_PATH"
+	// EnvIndexerClientKeyPath path of the PEM encoded private key of the client certificate
+	EnvIndexerClientKeyPath = "INDEXER_CLIENT_KEY_PATH"
+	// EnvIndexerMetricsDirectory directory of the local indexer
+	EnvIndexerMetricsDirectory = "INDEXER_METRICS_DIRECTORY"
+)
+
+// ConfigFromEnv builds an IndexerConfig from the INDEXER_* environment variables, unset variables leave
+// the corresponding fields empty
+func ConfigFromEnv() (IndexerConfig, error) {
+	indexerConfig := IndexerConfig{
+		Type:             IndexerType(os.Getenv(EnvIndexerType)),
+		Index:            os.Getenv(EnvIndexerIndex),
+		Username:         os.Getenv(EnvIndexerUsername),
+		Password:         os.Getenv(EnvIndexerPassword),
+		APIKey:           os.Getenv(EnvIndexerAPIKey),
+		CACertPath:       os.Getenv(EnvIndexerCACertPath),
+		ClientCertPath:   os.Getenv(EnvIndexerClientCertPath),
+		ClientKeyPath:    os.Getenv(EnvIndexerClientKeyPath),
+		MetricsDirectory: os.Getenv(EnvIndexerMetricsDirectory),
+	}
+	if indexerConfig.Type == "" {
+		return indexerConfig, fmt.Errorf("%s not specified", EnvIndexerType)
+	}
+	for _, server := range strings.Split(os.Getenv(EnvIndexerServers), ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			indexerConfig.Servers = append(indexerConfig.Servers, server)
+		}
+	}
+	if insecure := os.Getenv(EnvIndexerInsecureSkipVerify); insecure != "" {
+		var err error
+		indexerConfig.InsecureSkipVerify, err = strconv.ParseBool(insecure)
+		if err != nil {
+			return indexerConfig, fmt.Errorf("invalid %s value %q: %s", EnvIndexerInsecureSkipVerify, insecure, err)
+		}
+	}
+	return indexerConfig, nil
+}
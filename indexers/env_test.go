@@ -0,0 +1,52 @@
+package indexers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for env.go", func() {
+	Context("Tests for ConfigFromEnv()", func() {
+		BeforeEach(func() {
+			setEnv(map[string]string{
+				EnvIndexerType:               "opensearch",
+				EnvIndexerServers:            "https://os-1:9200, https://os-2:9200,",
+				EnvIndexerIndex:              "go-commons-test",
+				EnvIndexerUsername:           "user",
+				EnvIndexerPassword:           "secret",
+				EnvIndexerAPIKey:             "",
+				EnvIndexerInsecureSkipVerify: "true",
+				EnvIndexerCACertPath:         "",
+				EnvIndexerClientCertPath:     "",
+				EnvIndexerClientKeyPath:      "",
+				EnvIndexerMetricsDirectory:   "",
+			})
+		})
+
+		It("Returns the config from the environment", func() {
+			indexerConfig, err := ConfigFromEnv()
+			Expect(err).To(BeNil())
+			Expect(indexerConfig).To(Equal(IndexerConfig{
+				Type:               OpenSearchIndexer,
+				Servers:            []string{"https://os-1:9200", "https://os-2:9200"},
+				Index:              "go-commons-test",
+				Username:           "user",
+				Password:           "secret",
+				InsecureSkipVerify: true,
+			}))
+		})
+
+		It("Returns err no indexer type", func() {
+			setEnv(map[string]string{EnvIndexerType: ""})
+			_, err := ConfigFromEnv()
+			Expect(err).To(MatchError("INDEXER_TYPE not specified"))
+		})
+
+		It("Returns err invalid insecure skip verify value", func() {
+			setEnv(map[string]string{EnvIndexerInsecureSkipVerify: "maybe"})
+			_, err := ConfigFromEnv()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid INDEXER_INSECURE_SKIP_VERIFY value"))
+		})
+	})
+})
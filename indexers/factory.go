@@ -16,22 +16,77 @@ package indexers
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 )
 
-var indexerMap = make(map[IndexerType]Indexer)
+// indexerMap holds the constructors of the available indexers, so every NewIndexer call gets its own instance
+var indexerMap = make(map[IndexerType]func() Indexer)
 
-// NewIndexer creates a new Indexer with the specified IndexerConfig
-func NewIndexer(indexerConfig IndexerConfig) (*Indexer, error) {
-	var indexer Indexer
-	var exists bool
-	cfg := indexerConfig
-	if indexer, exists = indexerMap[cfg.Type]; exists {
-		err := indexer.new(indexerConfig)
+// Register makes an indexer available to NewIndexer under the given name, factory must return a new
+// instance on every call. Register panics when the name is already registered
+func Register(name string, factory func() Indexer) {
+	if factory == nil {
+		panic("indexers: Register factory is nil")
+	}
+	if _, exists := indexerMap[IndexerType(name)]; exists {
+		panic(fmt.Sprintf("indexers: Register called twice for indexer %s", name))
+	}
+	indexerMap[IndexerType(name)] = factory
+}
+
+// NewIndexer creates a new Indexer with the specified IndexerConfig. When default indexing options are configured,
+// the returned indexer merges them into the options of every indexing call
+func NewIndexer(indexerConfig IndexerConfig) (Indexer, error) {
+	newIndexer, exists := indexerMap[indexerConfig.Type]
+	if !exists {
+		return nil, fmt.Errorf("Indexer not found: %s", indexerConfig.Type)
+	}
+	indexerConfig, err := readSecretFiles(indexerConfig)
+	if err != nil {
+		return nil, err
+	}
+	indexer := newIndexer()
+	if err := indexer.New(indexerConfig); err != nil {
+		return nil, err
+	}
+	if hasDefaultOpts(indexerConfig.DefaultOpts) {
+		return newDefaultOptsIndexer(indexer, indexerConfig.DefaultOpts), nil
+	}
+	return indexer, nil
+}
+
+// RegisteredIndexers returns the sorted names of the available indexers
+func RegisteredIndexers() []string {
+	names := make([]string, 0, len(indexerMap))
+	for name := range indexerMap {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readSecretFiles returns the given configuration with the credentials read from the secret files, overriding the
+// inline ones. The trailing newline of the files is dropped
+func readSecretFiles(indexerConfig IndexerConfig) (IndexerConfig, error) {
+	for _, secret := range []struct {
+		path  string
+		value *string
+		name  string
+	}{
+		{indexerConfig.UsernameFile, &indexerConfig.Username, "username"},
+		{indexerConfig.PasswordFile, &indexerConfig.Password, "password"},
+		{indexerConfig.APIKeyFile, &indexerConfig.APIKey, "API key"},
+	} {
+		if secret.path == "" {
+			continue
+		}
+		content, err := os.ReadFile(secret.path)
 		if err != nil {
-			return &indexer, err
+			return indexerConfig, fmt.Errorf("error reading %s file: %s", secret.name, err)
 		}
-	} else {
-		return &indexer, fmt.Errorf("Indexer not found: %s", cfg.Type)
+		*secret.value = strings.TrimRight(string(content), "\r\n")
 	}
-	return &indexer, nil
+	return indexerConfig, nil
 }
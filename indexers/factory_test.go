@@ -1,10 +1,14 @@
 package indexers
 
 import (
+	"encoding/base64"
 	"errors"
+	"log"
 	"net/http"
 	"net/http/httptest"
-	"log"
+	"os"
+	"path"
+	"sort"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -60,7 +64,7 @@ var _ = Describe("Factory.go Unit Tests: NewIndexer()", func() {
 	})
 })
 
-// Unit Test to call opensearch.new()
+// Unit Test to call opensearch.New()
 var _ = Describe("Factory.go Unit Tests: NewIndexer()", func() {
 	var testcase newMethodTestcase
 	BeforeEach(func() {
@@ -88,5 +92,101 @@ var _ = Describe("Factory.go Unit Tests: NewIndexer()", func() {
 			Expect(err).To(BeNil())
 		})
 
+		It("returns an initialized opensearch indexer", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			indexer, err := NewIndexer(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer).To(BeAssignableToTypeOf(&OpenSearch{}))
+			Expect(indexer.(*OpenSearch).index).To(Equal("go-commons-test"))
+		})
+
+		It("returns a new indexer on every call", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			first, err := NewIndexer(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			second, err := NewIndexer(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(first).ToNot(BeIdenticalTo(second))
+		})
+
+		It("returns nil indexer and err unknown indexer", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Type = "Unknown"
+			indexer, err := NewIndexer(testcase.indexerConfig)
+			Expect(indexer).To(BeNil())
+			Expect(err).To(BeEquivalentTo(errors.New("Indexer not found: Unknown")))
+		})
+
+	})
+})
+
+var _ = Describe("Factory.go Unit Tests: RegisteredIndexers()", func() {
+	It("returns the sorted names of the available indexers", func() {
+		names := RegisteredIndexers()
+		Expect(names).To(ContainElements("elastic", "kafka", "local", "opensearch", "prometheus", "stdout"))
+		Expect(sort.StringsAreSorted(names)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Factory.go Unit Tests: readSecretFiles()", func() {
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "go-commons-test")
+		Expect(err).To(BeNil())
+		DeferCleanup(os.RemoveAll, dir)
+	})
+
+	writeSecret := func(name, content string) string {
+		secretPath := path.Join(dir, name)
+		Expect(os.WriteFile(secretPath, []byte(content), 0600)).To(Succeed())
+		return secretPath
+	}
+
+	It("overrides the inline credentials with the content of the files", func() {
+		indexerConfig, err := readSecretFiles(IndexerConfig{
+			Username:     "inline",
+			Password:     "inline",
+			UsernameFile: writeSecret("username", "elastic\n"),
+			PasswordFile: writeSecret("password", "s3cr3t\r\n"),
+			APIKeyFile:   writeSecret("api-key", "a2V5"),
+		})
+		Expect(err).To(BeNil())
+		Expect(indexerConfig.Username).To(Equal("elastic"))
+		Expect(indexerConfig.Password).To(Equal("s3cr3t"))
+		Expect(indexerConfig.APIKey).To(Equal("a2V5"))
+	})
+
+	It("keeps the inline credentials without files", func() {
+		indexerConfig, err := readSecretFiles(IndexerConfig{Username: "elastic", Password: "inline"})
+		Expect(err).To(BeNil())
+		Expect(indexerConfig.Username).To(Equal("elastic"))
+		Expect(indexerConfig.Password).To(Equal("inline"))
+	})
+
+	It("returns err missing secret file", func() {
+		_, err := readSecretFiles(IndexerConfig{PasswordFile: path.Join(dir, "missing")})
+		Expect(err).To(MatchError(HavePrefix("error reading password file: open " + path.Join(dir, "missing"))))
+	})
+
+	It("authenticates NewIndexer with the credentials of the files", func() {
+		var authorization string
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(payload)
+		}))
+		defer mockServer.Close()
+		_, err := NewIndexer(IndexerConfig{
+			Type:         "elastic",
+			Servers:      []string{mockServer.URL},
+			Index:        "go-commons-test",
+			UsernameFile: writeSecret("username", "elastic\n"),
+			PasswordFile: writeSecret("password", "s3cr3t\n"),
+		})
+		Expect(err).To(BeNil())
+		Expect(authorization).To(Equal("Basic " + base64.StdEncoding.EncodeToString([]byte("elastic:s3cr3t"))))
 	})
 })
@@ -0,0 +1,273 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const gelfIndexer = "gelf"
+
+// defaultGELFTimestampField document field holding the message timestamp when no TimestampField is configured
+const defaultGELFTimestampField = "timestamp"
+
+// gelfChunkSize maximum size of the UDP packets, chunk headers included, keeping them below the usual MTU
+const gelfChunkSize = 1420
+
+// gelfChunkHeaderSize size of the header of every chunk: magic bytes, message ID, sequence number and count
+const gelfChunkHeaderSize = 12
+
+// gelfMaxChunks maximum number of chunks of a message accepted by Graylog
+const gelfMaxChunks = 128
+
+// gelfFieldNameReplacer matches the characters not allowed in the names of the GELF additional fields
+var gelfFieldNameReplacer = regexp.MustCompile(`[^\w.\-]`)
+
+// GELF Graylog Extended Log Format instance
+type GELF struct {
+	network        string
+	address        string
+	conn           net.Conn
+	hostname       string
+	timestampField string
+	logger         Logger
+}
+
+// Init function
+func init() {
+	Register(gelfIndexer, func() Indexer { return &GELF{} })
+}
+
+// Returns new indexer for GELF, sending the messages to the Servers[0] input, given as a udp://host:port or
+// tcp://host:port address, UDP being used when no scheme is given
+func (g *GELF) New(indexerConfig IndexerConfig) error {
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	network, address := "udp", indexerConfig.Servers[0]
+	if scheme, rest, found := strings.Cut(address, "://"); found {
+		if scheme != "udp" && scheme != "tcp" {
+			return fmt.Errorf("invalid GELF address: %s", indexerConfig.Servers[0])
+		}
+		network, address = scheme, rest
+	}
+	timeout := indexerConfig.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to GELF server %s: %s", indexerConfig.Servers[0], err)
+	}
+	g.hostname, _ = os.Hostname()
+	g.network = network
+	g.address = address
+	g.conn = conn
+	g.timestampField = indexerConfig.TimestampField
+	if g.timestampField == "" {
+		g.timestampField = defaultGELFTimestampField
+	}
+	g.logger = loggerOrNop(indexerConfig.Logger)
+	return nil
+}
+
+// Index sends the documents as GELF messages
+func (g *GELF) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := g.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult sends every document as a GELF message and returns the indexing result. The message host is the
+// job name, defaulting to the hostname. UDP messages larger than a packet are chunked, those exceeding the maximum
+// number of chunks being skipped and counted in the oversized stat. TCP messages are null byte delimited
+func (g *GELF) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, g.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	host := opts.JobName
+	if host == "" {
+		host = g.hostname
+	}
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	for _, document := range documents {
+		if err := ctx.Err(); err != nil {
+			return IndexingResult{}, err
+		}
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		decorated, err := decorateDocument(j, fields)
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		message, err := gelfMessage(decorated, string(j), host, g.timestampField, time.Now())
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		sent, err := g.send(message)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Unexpected GELF error: %s", err)
+		}
+		if !sent {
+			g.logger.Errorf("GELF message skipped, its size of %d bytes exceeds %d chunks", len(message), gelfMaxChunks)
+			indexerStats["oversized"]++
+			continue
+		}
+		indexerStats["created"]++
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// gelfMessage returns the GELF message of the given encoded document. Its message or short_message field is used as
+// short message, defaulting to shortMessage, and its timestamp is read from timestampField, defaulting to now. The
+// other fields are set as additional fields, the objects and arrays being encoded as JSON strings
+func gelfMessage(j []byte, shortMessage, host, timestampField string, now time.Time) ([]byte, error) {
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		document = nil
+	}
+	for _, field := range []string{"short_message", "message"} {
+		if value, ok := document[field].(string); ok && value != "" {
+			shortMessage = value
+			delete(document, field)
+			break
+		}
+	}
+	timestamp := now
+	if value, exists := document[timestampField]; exists {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if timestamp, err = sampleTimestamp(raw, now); err != nil {
+			return nil, err
+		}
+		delete(document, timestampField)
+	}
+	message := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": shortMessage,
+		"timestamp":     float64(timestamp.UnixMilli()) / 1000,
+	}
+	for field, value := range document {
+		name := "_" + gelfFieldNameReplacer.ReplaceAllString(field, "_")
+		// The _id field is reserved by Graylog
+		if name == "_id" {
+			name = "_id_"
+		}
+		switch value.(type) {
+		case string, json.Number:
+			message[name] = value
+		default:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			message[name] = string(encoded)
+		}
+	}
+	return json.Marshal(message)
+}
+
+// send sends the message, reporting false when it's too large to be chunked
+func (g *GELF) send(message []byte) (bool, error) {
+	if g.network == "tcp" {
+		_, err := g.conn.Write(append(message, 0))
+		return true, err
+	}
+	if len(message) <= gelfChunkSize {
+		_, err := g.conn.Write(message)
+		return true, err
+	}
+	chunkData := gelfChunkSize - gelfChunkHeaderSize
+	chunks := (len(message) + chunkData - 1) / chunkData
+	if chunks > gelfMaxChunks {
+		return false, nil
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return true, err
+	}
+	for i := 0; i < chunks; i++ {
+		last := (i + 1) * chunkData
+		if last > len(message) {
+			last = len(message)
+		}
+		chunk := append([]byte{0x1e, 0x0f}, id...)
+		chunk = append(chunk, byte(i), byte(chunks))
+		if _, err := g.conn.Write(append(chunk, message[i*chunkData:last]...)); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Ping checks the GELF TCP input accepts connections. It always succeeds with UDP, which can't tell whether the
+// server is listening
+func (g *GELF) Ping(ctx context.Context) error {
+	if g.network != "tcp" {
+		return nil
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, g.network, g.address)
+	if err != nil {
+		return pingFailed("GELF", ErrUnreachable, err)
+	}
+	return conn.Close()
+}
+
+// Close closes the GELF connection
+func (g *GELF) Close() error {
+	if g.conn != nil {
+		return g.conn.Close()
+	}
+	return nil
+}
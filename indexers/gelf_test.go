@@ -0,0 +1,158 @@
+package indexers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for gelf.go", func() {
+	Context("Tests for New()", func() {
+		var indexer GELF
+
+		It("Returns err no servers", func() {
+			err := indexer.New(IndexerConfig{Type: "gelf"})
+			Expect(err).To(MatchError("servers not specified"))
+		})
+
+		It("Returns err invalid address", func() {
+			err := indexer.New(IndexerConfig{Type: "gelf", Servers: []string{"http://localhost:12201"}})
+			Expect(err).To(MatchError("invalid GELF address: http://localhost:12201"))
+		})
+
+		It("Returns err when the TCP input can't be reached", func() {
+			err := indexer.New(IndexerConfig{Type: "gelf", Servers: []string{"tcp://127.0.0.1:1"}})
+			Expect(err.Error()).To(HavePrefix("error connecting to GELF server tcp://127.0.0.1:1"))
+		})
+	})
+
+	Context("Tests for Index() over UDP", func() {
+		var indexer GELF
+		var listener net.PacketConn
+		BeforeEach(func() {
+			var err error
+			listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			Expect(indexer.New(IndexerConfig{Type: "gelf", Servers: []string{listener.LocalAddr().String()}})).To(Succeed())
+		})
+		AfterEach(func() {
+			indexer.Close()
+			listener.Close()
+		})
+
+		It("Sends every document as a GELF message", func() {
+			documents := []interface{}{
+				map[string]interface{}{"message": "pod scheduled", "timestamp": "2023-05-01T10:00:00.5Z", "latency": 42, "labels": map[string]string{"node": "worker-0"}, "id": "1234", "pod name": "nginx"},
+				42,
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{JobName: "node-density"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			packets := receivePackets(listener)
+			Expect(packets).To(HaveLen(2))
+			Expect(packets[0]).To(MatchJSON(`{
+				"version": "1.1",
+				"host": "node-density",
+				"short_message": "pod scheduled",
+				"timestamp": 1682935200.5,
+				"_latency": 42,
+				"_labels": "{\"node\":\"worker-0\"}",
+				"_id_": "1234",
+				"_pod_name": "nginx",
+				"_jobName": "node-density"
+			}`))
+			var message map[string]interface{}
+			Expect(json.Unmarshal([]byte(packets[1]), &message)).To(Succeed())
+			Expect(message).To(HaveKeyWithValue("short_message", "42"))
+			Expect(message).To(HaveKeyWithValue("_document", BeNumerically("==", 42)))
+			Expect(message).To(HaveKey("timestamp"))
+		})
+
+		It("Sets the hostname as host when no job name is given", func() {
+			_, err := indexer.IndexWithResult(context.Background(), []interface{}{"example document"}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			packets := receivePackets(listener)
+			Expect(packets).To(HaveLen(1))
+			var message map[string]interface{}
+			Expect(json.Unmarshal([]byte(packets[0]), &message)).To(Succeed())
+			Expect(message).To(HaveKeyWithValue("host", indexer.hostname))
+			Expect(message).To(HaveKeyWithValue("short_message", `"example document"`))
+		})
+
+		It("Chunks the messages larger than a packet", func() {
+			document := map[string]interface{}{"message": strings.Repeat("x", 3000)}
+			_, err := indexer.IndexWithResult(context.Background(), []interface{}{document}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			packets := receivePackets(listener)
+			Expect(packets).To(HaveLen(3))
+			var message []byte
+			for i, packet := range packets {
+				Expect(len(packet)).To(BeNumerically("<=", gelfChunkSize))
+				Expect([]byte(packet[:2])).To(Equal([]byte{0x1e, 0x0f}))
+				Expect(packet[2:10]).To(Equal(packets[0][2:10]))
+				Expect([]byte(packet[10:12])).To(Equal([]byte{byte(i), 3}))
+				message = append(message, packet[12:]...)
+			}
+			Expect(json.Valid(message)).To(BeTrue())
+			Expect(string(message)).To(ContainSubstring(strings.Repeat("x", 3000)))
+		})
+
+		It("Skips the messages exceeding the maximum number of chunks", func() {
+			document := map[string]interface{}{"message": strings.Repeat("x", gelfMaxChunks*gelfChunkSize)}
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{document}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("oversized", 1))
+			Expect(receivePackets(listener)).To(BeEmpty())
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, IndexingOpts{})
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+	})
+
+	Context("Tests for Index() over TCP", func() {
+		It("Sends null byte delimited messages", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			defer listener.Close()
+			received := make(chan []string)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := listener.Accept()
+				Expect(err).To(BeNil())
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				var messages []string
+				reader := bufio.NewReader(conn)
+				for {
+					message, err := reader.ReadBytes(0)
+					if err != nil {
+						break
+					}
+					messages = append(messages, string(bytes.TrimSuffix(message, []byte{0})))
+				}
+				received <- messages
+			}()
+			var indexer GELF
+			Expect(indexer.New(IndexerConfig{Type: "gelf", Servers: []string{"tcp://" + listener.Addr().String()}})).To(Succeed())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{"first", "second"}, IndexingOpts{JobName: "node-density"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(indexer.Close()).To(Succeed())
+			var messages []string
+			Eventually(received).Should(Receive(&messages))
+			Expect(messages).To(HaveLen(2))
+			Expect(messages[1]).To(ContainSubstring(`"short_message":"\"second\""`))
+		})
+	})
+})
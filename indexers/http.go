@@ -0,0 +1,227 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const webhookIndexer = "webhook"
+
+// Webhook batch formats
+const (
+	// webhookJSON batches sent as a JSON array of documents
+	webhookJSON = "json"
+	// webhookNDJSON batches sent as newline delimited JSON documents
+	webhookNDJSON = "ndjson"
+)
+
+// Webhook generic HTTP webhook instance
+type Webhook struct {
+	url         string
+	client      *http.Client
+	username    string
+	password    string
+	apiKey      string
+	headers     map[string]string
+	format      string
+	contentType string
+	flushDocs   int
+	flushBytes  int
+}
+
+// Init function
+func init() {
+	Register(webhookIndexer, func() Indexer { return &Webhook{} })
+}
+
+// Returns new indexer for webhook, posting the batches of documents to the Servers[0] URL
+func (w *Webhook) New(indexerConfig IndexerConfig) error {
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	w.format = indexerConfig.WebhookFormat
+	switch w.format {
+	case "":
+		w.format = webhookJSON
+	case webhookJSON, webhookNDJSON:
+	default:
+		return fmt.Errorf("invalid webhook format: %s", indexerConfig.WebhookFormat)
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	w.url = indexerConfig.Servers[0]
+	w.client = &http.Client{Transport: transport}
+	w.username = indexerConfig.Username
+	w.password = indexerConfig.Password
+	w.apiKey = indexerConfig.APIKey
+	w.headers = indexerConfig.Headers
+	w.contentType = contentType(indexerConfig)
+	if indexerConfig.ContentType == "" && w.format == webhookNDJSON {
+		w.contentType = "application/x-ndjson"
+	}
+	w.flushDocs = indexerConfig.FlushDocs
+	w.flushBytes = indexerConfig.FlushBytes
+	if w.flushBytes <= 0 {
+		w.flushBytes = defaultFlushBytes
+	}
+	return nil
+}
+
+// Index posts the documents to the webhook
+func (w *Webhook) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := w.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult posts the documents to the webhook and returns the indexing result. Documents are batched in
+// requests of up to flushDocs documents and flushBytes, sent as a JSON array or as newline delimited JSON
+func (w *Webhook) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, w.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var batch [][]byte
+	batchBytes := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := w.post(ctx, batch); err != nil {
+			return err
+		}
+		indexerStats["created"] += len(batch)
+		batch = nil
+		batchBytes = 0
+		return nil
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		if batchBytes+len(j) > w.flushBytes {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		batch = append(batch, j)
+		batchBytes += len(j) + 1
+		if len(batch) == w.flushDocs {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if err := flush(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// post posts the batched documents to the webhook
+func (w *Webhook) post(ctx context.Context, batch [][]byte) error {
+	var body bytes.Buffer
+	if w.format == webhookNDJSON {
+		for _, j := range batch {
+			body.Write(j)
+			body.WriteByte('\n')
+		}
+	} else {
+		body.WriteByte('[')
+		body.Write(bytes.Join(batch, []byte(",")))
+		body.WriteByte(']')
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.contentType)
+	w.authorize(req)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unexpected webhook error: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected webhook response %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// authorize sets the credentials of the request, the API key as bearer token taking precedence over basic
+// authentication, and the extra headers, which override any header set by the indexer
+func (w *Webhook) authorize(req *http.Request) {
+	if w.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+w.apiKey)
+	} else if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// Ping checks the webhook is reachable and accepts the credentials, sending a GET request to its URL. As most
+// webhooks only accept POST requests, the 405 status code is considered healthy along with the 2xx ones
+func (w *Webhook) Ping(ctx context.Context) error {
+	return pingHTTP(ctx, "webhook", w.client, w.url, w.authorize, http.StatusMethodNotAllowed)
+}
+
+// Close closes the idle connections of the webhook client
+func (w *Webhook) Close() error {
+	if w.client != nil {
+		w.client.CloseIdleConnections()
+	}
+	return nil
+}
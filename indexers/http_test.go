@@ -0,0 +1,227 @@
+package indexers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for http.go", func() {
+	var indexerConfig IndexerConfig
+	var indexer Webhook
+	var server *httptest.Server
+	var requests []*http.Request
+	var bodies [][]byte
+	BeforeEach(func() {
+		requests = nil
+		bodies = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			requests = append(requests, r)
+			bodies = append(bodies, body)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		indexerConfig = IndexerConfig{Type: "webhook",
+			Servers: []string{server.URL + "/ingest"},
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Tests for New()", func() {
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.url).To(Equal(server.URL + "/ingest"))
+			Expect(indexer.format).To(Equal("json"))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("servers not specified"))
+		})
+
+		It("Returns err invalid format", func() {
+			indexerConfig.WebhookFormat = "xml"
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("invalid webhook format: xml"))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		BeforeEach(func() {
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+		})
+
+		It("Posts the documents as a JSON array", func() {
+			documents := []interface{}{
+				map[string]interface{}{"uuid": "1234", "value": 2.5},
+				42,
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "podLatency"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Method).To(Equal(http.MethodPost))
+			Expect(requests[0].URL.Path).To(Equal("/ingest"))
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(bodies[0]).To(MatchJSON(`[
+				{"uuid": "1234", "value": 2.5, "metricName": "podLatency"},
+				{"document": 42, "metricName": "podLatency"}
+			]`))
+		})
+
+		It("Posts the documents as newline delimited JSON", func() {
+			indexerConfig.WebhookFormat = "ndjson"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			documents := []interface{}{map[string]interface{}{"value": 1}, map[string]interface{}{"value": 2}}
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/x-ndjson"))
+			var lines []string
+			scanner := bufio.NewScanner(bytes.NewReader(bodies[0]))
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[0]).To(MatchJSON(`{"value": 1}`))
+			Expect(lines[1]).To(MatchJSON(`{"value": 2}`))
+		})
+
+		It("Sends the credentials and the extra headers", func() {
+			indexerConfig.Username = "user"
+			indexerConfig.Password = "secret"
+			indexerConfig.ContentType = "application/vnd.ingest+json"
+			indexerConfig.Headers = map[string]string{"X-Tenant": "perf", "X-Source": "go-commons"}
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{1}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			username, password, ok := requests[0].BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("user"))
+			Expect(password).To(Equal("secret"))
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/vnd.ingest+json"))
+			Expect(requests[0].Header.Get("X-Tenant")).To(Equal("perf"))
+			Expect(requests[0].Header.Get("X-Source")).To(Equal("go-commons"))
+		})
+
+		It("Sends the API key as bearer token", func() {
+			indexerConfig.APIKey = "secret"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{1}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer secret"))
+		})
+
+		It("Batches the documents up to the flush documents", func() {
+			indexerConfig.FlushDocs = 2
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			var documents []interface{}
+			for i := 0; i < 5; i++ {
+				documents = append(documents, map[string]interface{}{"value": i})
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(5))
+			Expect(requests).To(HaveLen(3))
+			var batch []json.RawMessage
+			Expect(json.Unmarshal(bodies[2], &batch)).To(Succeed())
+			Expect(batch).To(HaveLen(1))
+		})
+
+		It("Skips the redundant documents", func() {
+			document := map[string]interface{}{"value": 1}
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{document, document}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Skipped).To(Equal(1))
+		})
+
+		It("Skips the unencodable documents", func() {
+			documents := []interface{}{map[string]interface{}{"value": 1}, make(chan string), map[string]interface{}{"value": 2}}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{SkipUnencodable: true})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.Stats).To(HaveKeyWithValue("unencodable", 1))
+			Expect(bodies[0]).To(MatchJSON(`[{"value": 1}, {"value": 2}]`))
+		})
+
+		It("Reports the unencodable documents when none can be encoded", func() {
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{make(chan string)}, IndexingOpts{SkipUnencodable: true})
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(Equal(map[string]int{"unencodable": 1}))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Indexes a sample of the documents", func() {
+			var documents []interface{}
+			for i := 0; i < 1000; i++ {
+				documents = append(documents, map[string]interface{}{"value": i})
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{SampleRate: 0.1})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(BeNumerically("~", 100, 40))
+			Expect(result.Stats).To(HaveKeyWithValue("sampledout", 1000-result.Created))
+			var batch []json.RawMessage
+			Expect(json.Unmarshal(bodies[0], &batch)).To(Succeed())
+			Expect(batch).To(HaveLen(result.Created))
+			_, err = indexer.IndexWithResult(context.Background(), documents, IndexingOpts{SampleRate: 2})
+			Expect(err).To(MatchError("invalid sample rate: 2"))
+		})
+
+		It("Returns err on error responses", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte("invalid batch\n"))
+			})
+			_, err := indexer.Index(context.Background(), []interface{}{1}, IndexingOpts{})
+			Expect(err).To(MatchError("Unexpected webhook response 400: invalid batch"))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, IndexingOpts{})
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Accepts the webhooks only allowing POST requests", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Returns ErrUnauthorized when the credentials are rejected", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			err := indexer.Ping(context.Background())
+			Expect(errors.Is(err, ErrUnauthorized)).To(BeTrue())
+		})
+
+		It("Returns ErrUnhealthy when the webhook isn't found", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			err := indexer.Ping(context.Background())
+			Expect(err).To(MatchError(ErrUnhealthy))
+			Expect(err).To(MatchError(ContainSubstring("status code 404")))
+		})
+	})
+})
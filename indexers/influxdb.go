@@ -0,0 +1,243 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const influxDBIndexer = "influxdb"
+
+// defaultInfluxDBTimestampField document field holding the point timestamp when no TimestampField is configured
+const defaultInfluxDBTimestampField = "timestamp"
+
+// InfluxDB line protocol indexer instance
+type InfluxDB struct {
+	url            string
+	client         *http.Client
+	username       string
+	password       string
+	apiKey         string
+	timestampField string
+}
+
+// Init function
+func init() {
+	Register(influxDBIndexer, func() Indexer { return &InfluxDB{} })
+}
+
+// Returns new indexer for InfluxDB
+func (i *InfluxDB) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	writeURL, err := url.Parse(strings.TrimSuffix(indexerConfig.Servers[0], "/") + "/write")
+	if err != nil {
+		return fmt.Errorf("invalid InfluxDB server %s: %s", indexerConfig.Servers[0], err)
+	}
+	writeURL.RawQuery = url.Values{"db": []string{indexerConfig.Index}, "precision": []string{"ns"}}.Encode()
+	i.url = writeURL.String()
+	i.client = &http.Client{Transport: transport}
+	i.username = indexerConfig.Username
+	i.password = indexerConfig.Password
+	i.apiKey = indexerConfig.APIKey
+	i.timestampField = indexerConfig.TimestampField
+	if i.timestampField == "" {
+		i.timestampField = defaultInfluxDBTimestampField
+	}
+	return nil
+}
+
+// Index writes the documents as points to the InfluxDB database
+func (i *InfluxDB) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := i.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult writes the documents as points to the InfluxDB database and returns the indexing result
+func (i *InfluxDB) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, i.IndexWithResult)
+	}
+	if opts.MetricName == "" {
+		return IndexingResult{}, fmt.Errorf("MetricName shouldn't be empty")
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var body bytes.Buffer
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		line, err := lineProtocol(j, opts.MetricName, i.timestampField, time.Now())
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		indexerStats["created"]++
+	}
+	if err := i.write(ctx, body.Bytes()); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// write posts the points to the InfluxDB write endpoint
+func (i *InfluxDB) write(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	i.authorize(req)
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unexpected InfluxDB error: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected InfluxDB response %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Ping checks the InfluxDB server is reachable through its ping endpoint
+func (i *InfluxDB) Ping(ctx context.Context) error {
+	pingURL, err := url.Parse(i.url)
+	if err != nil {
+		return pingFailed("InfluxDB", ErrUnreachable, err)
+	}
+	pingURL.Path = strings.TrimSuffix(pingURL.Path, "/write") + "/ping"
+	pingURL.RawQuery = ""
+	return pingHTTP(ctx, "InfluxDB", i.client, pingURL.String(), i.authorize)
+}
+
+// authorize sets the credentials of the request, the API key taking precedence over basic auth
+func (i *InfluxDB) authorize(req *http.Request) {
+	if i.apiKey != "" {
+		req.Header.Set("Authorization", "Token "+i.apiKey)
+	} else if i.username != "" {
+		req.SetBasicAuth(i.username, i.password)
+	}
+}
+
+// Close closes the idle connections of the InfluxDB client
+func (i *InfluxDB) Close() error {
+	if i.client != nil {
+		i.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// lineProtocol returns the line protocol point of the given JSON document, its string fields being used as tags
+// and its numeric and boolean fields as fields. The timestamp is read from timestampField, defaulting to now
+func lineProtocol(j []byte, measurement, timestampField string, now time.Time) (string, error) {
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil || document == nil {
+		return "", fmt.Errorf("document %s is not a JSON object", j)
+	}
+	timestamp := now
+	if raw, exists := document[timestampField]; exists {
+		rawJSON, _ := json.Marshal(raw)
+		var err error
+		if timestamp, err = sampleTimestamp(rawJSON, now); err != nil {
+			return "", err
+		}
+		delete(document, timestampField)
+	}
+	names := make([]string, 0, len(document))
+	for name := range document {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var tags, fields []string
+	for _, name := range names {
+		switch value := document[name].(type) {
+		case string:
+			tags = append(tags, escapeLineProtocol(name, ",= ")+"="+escapeLineProtocol(value, ",= "))
+		case json.Number:
+			field := value.String()
+			if _, err := strconv.ParseInt(field, 10, 64); err == nil {
+				field += "i"
+			}
+			fields = append(fields, escapeLineProtocol(name, ",= ")+"="+field)
+		case bool:
+			fields = append(fields, escapeLineProtocol(name, ",= ")+"="+strconv.FormatBool(value))
+		}
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("document %s has no numeric or boolean fields", j)
+	}
+	point := escapeLineProtocol(measurement, ", ")
+	if len(tags) > 0 {
+		point += "," + strings.Join(tags, ",")
+	}
+	return fmt.Sprintf("%s %s %d", point, strings.Join(fields, ","), timestamp.UnixNano()), nil
+}
+
+// escapeLineProtocol escapes the given characters with a backslash
+func escapeLineProtocol(s, chars string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
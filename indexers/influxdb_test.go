@@ -0,0 +1,224 @@
+package indexers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// influxPoint point parsed from a line protocol body
+type influxPoint struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]string
+	timestamp   string
+}
+
+// splitUnescaped splits s around the separators not escaped with a backslash
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// parseLineProtocol parses the points of the given line protocol body, leaving the keys and values escaped
+func parseLineProtocol(body string) []influxPoint {
+	var points []influxPoint
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		parts := splitUnescaped(line, ' ')
+		Expect(parts).To(HaveLen(3))
+		point := influxPoint{tags: map[string]string{}, fields: map[string]string{}, timestamp: parts[2]}
+		series := splitUnescaped(parts[0], ',')
+		point.measurement = series[0]
+		for _, tag := range series[1:] {
+			kv := strings.SplitN(tag, "=", 2)
+			point.tags[kv[0]] = kv[1]
+		}
+		for _, field := range splitUnescaped(parts[1], ',') {
+			kv := strings.SplitN(field, "=", 2)
+			point.fields[kv[0]] = kv[1]
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+var _ = Describe("Tests for influxdb.go", func() {
+	var indexerConfig IndexerConfig
+	var indexer InfluxDB
+	var server *httptest.Server
+	var requests []*http.Request
+	var points []influxPoint
+	BeforeEach(func() {
+		requests = nil
+		points = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			requests = append(requests, r)
+			points = append(points, parseLineProtocol(string(body))...)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		indexerConfig = IndexerConfig{Type: "influxdb",
+			Servers: []string{server.URL},
+			Index:   "go-commons-test",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Tests for New()", func() {
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.url).To(Equal(server.URL + "/write?db=go-commons-test&precision=ns"))
+			Expect(indexer.timestampField).To(Equal("timestamp"))
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("index name not specified"))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("servers not specified"))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var timestamp time.Time
+		BeforeEach(func() {
+			timestamp = time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+		})
+
+		It("Writes the documents as line protocol points", func() {
+			documents := []interface{}{
+				map[string]interface{}{
+					"uuid":      "1234",
+					"quantile":  "P 99",
+					"value":     2.5,
+					"pods":      10,
+					"ready":     true,
+					"labels":    map[string]string{"ignored": "true"},
+					"timestamp": timestamp.Format(time.RFC3339),
+				},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "podLatency"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/write"))
+			Expect(requests[0].URL.Query()).To(Equal(url.Values{"db": {"go-commons-test"}, "precision": {"ns"}}))
+			Expect(points).To(Equal([]influxPoint{{
+				measurement: "podLatency",
+				tags:        map[string]string{"quantile": `P\ 99`, "uuid": "1234"},
+				fields:      map[string]string{"value": "2.5", "pods": "10i", "ready": "true"},
+				timestamp:   "1685613600000000000",
+			}}))
+		})
+
+		It("Reads the timestamp from the configured field", func() {
+			indexerConfig.TimestampField = "ts"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			documents := []interface{}{map[string]interface{}{"value": 1, "ts": timestamp.UnixMilli()}}
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{MetricName: "up"})
+			Expect(err).To(BeNil())
+			Expect(points).To(HaveLen(1))
+			Expect(points[0].timestamp).To(Equal("1685613600000000000"))
+			Expect(points[0].fields).To(Equal(map[string]string{"value": "1i"}))
+		})
+
+		It("Sends the API key as token", func() {
+			indexerConfig.APIKey = "secret"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(BeNil())
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("Token secret"))
+		})
+
+		It("Skips the redundant documents", func() {
+			document := map[string]interface{}{"value": 1, "timestamp": timestamp.UnixMilli()}
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{document, document}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Skipped).To(Equal(1))
+		})
+
+		It("Doesn't write any point in dry-run mode", func() {
+			documents := []interface{}{map[string]interface{}{"value": 1}, map[string]interface{}{"value": 2}}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "up", DryRun: true})
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 2))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err no metric name", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{})
+			Expect(err).To(MatchError("MetricName shouldn't be empty"))
+		})
+
+		It("Returns err documents without fields", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"uuid": "1234"}}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(HaveOccurred())
+			_, err = indexer.Index(context.Background(), []interface{}{42}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err when the server rejects the points", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "database not found", http.StatusNotFound)
+			})
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{MetricName: "up"})
+			Expect(err).To(MatchError(ContainSubstring("database not found")))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Checks the ping endpoint", func() {
+			var pinged *http.Request
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pinged = r
+				w.WriteHeader(http.StatusNoContent)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			Expect(pinged.URL.Path).To(Equal("/ping"))
+		})
+
+		It("Returns ErrUnhealthy when the server fails", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnhealthy))
+		})
+
+		It("Returns ErrUnreachable when the server is down", func() {
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			server.Close()
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
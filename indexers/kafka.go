@@ -0,0 +1,161 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const kafkaIndexer = "kafka"
+
+// kafkaWriter produces messages to a Kafka topic
+type kafkaWriter interface {
+	WriteMessages(context.Context, ...kafka.Message) error
+	Close() error
+}
+
+// Kafka Kafka instance
+type Kafka struct {
+	topic   string
+	writer  kafkaWriter
+	brokers []string
+	dialer  *kafka.Dialer
+}
+
+// Init function
+func init() {
+	Register(kafkaIndexer, func() Indexer { return &Kafka{} })
+}
+
+// Returns new indexer for Kafka, the brokers being reached over TLS when their addresses use the tls:// scheme or
+// any TLS option is configured
+func (k *Kafka) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("kafka brokers not specified")
+	}
+	useTLS := indexerConfig.InsecureSkipVerify || indexerConfig.CACertPath != "" ||
+		len(indexerConfig.ClientCert) > 0 || indexerConfig.ClientCertPath != ""
+	brokers := make([]string, len(indexerConfig.Servers))
+	for i, server := range indexerConfig.Servers {
+		if strings.HasPrefix(server, "tls://") {
+			useTLS = true
+		}
+		brokers[i] = strings.TrimPrefix(server, "tls://")
+	}
+	transport := &kafka.Transport{}
+	if useTLS {
+		tlsClientConfig, err := tlsConfig(indexerConfig)
+		if err != nil {
+			return err
+		}
+		transport.TLS = tlsClientConfig
+	}
+	k.topic = indexerConfig.Index
+	k.brokers = brokers
+	k.dialer = &kafka.Dialer{TLS: transport.TLS}
+	k.writer = &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  k.topic,
+		Transport:              transport,
+		AllowAutoTopicCreation: true,
+	}
+	return nil
+}
+
+// Index produces the documents to the configured topic
+func (k *Kafka) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := k.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult produces the documents to the configured topic and returns the indexing result
+func (k *Kafka) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, k.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var messages []kafka.Message
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		key := documentID(j, opts.DocumentIDField, docHashKey)
+		reportDocumentID(opts, document, key)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		messages = append(messages, kafka.Message{
+			Key:   []byte(key),
+			Value: j,
+		})
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if err := k.writer.WriteMessages(ctx, messages...); err != nil {
+		return IndexingResult{}, fmt.Errorf("Unexpected Kafka error: %s", err)
+	}
+	indexerStats["created"] = len(messages)
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// Ping checks any of the Kafka brokers is reachable
+func (k *Kafka) Ping(ctx context.Context) error {
+	var err error
+	for _, broker := range k.brokers {
+		var conn *kafka.Conn
+		if conn, err = k.dialer.DialContext(ctx, "tcp", broker); err == nil {
+			return conn.Close()
+		}
+	}
+	return pingFailed("Kafka", ErrUnreachable, err)
+}
+
+// Close flushes the pending messages and closes the Kafka writer
+func (k *Kafka) Close() error {
+	if k.writer == nil {
+		return nil
+	}
+	return k.writer.Close()
+}
@@ -0,0 +1,220 @@
+package indexers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/segmentio/kafka-go"
+)
+
+// mockKafkaWriter records the produced messages
+type mockKafkaWriter struct {
+	sync.Mutex
+	messages []kafka.Message
+	err      error
+	closed   bool
+}
+
+func (m *mockKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msgs...)
+	return nil
+}
+
+func (m *mockKafkaWriter) Close() error {
+	m.closed = true
+	return nil
+}
+
+var _ = Describe("Tests for kafka.go", func() {
+	Context("Tests for New()", func() {
+		var indexerConfig IndexerConfig
+		var indexer Kafka
+		BeforeEach(func() {
+			indexerConfig = IndexerConfig{Type: "kafka",
+				Servers: []string{"localhost:9092"},
+				Index:   "go-commons-test",
+			}
+		})
+
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.topic).To(Equal("go-commons-test"))
+		})
+
+		It("Reaches the brokers in plaintext when TLS isn't configured", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.dialer.TLS).To(BeNil())
+			Expect(indexer.writer.(*kafka.Writer).Transport.(*kafka.Transport).TLS).To(BeNil())
+		})
+
+		It("Reaches the brokers over TLS with the tls:// scheme", func() {
+			indexerConfig.Servers = []string{"tls://localhost:9093"}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.brokers).To(Equal([]string{"localhost:9093"}))
+			Expect(indexer.dialer.TLS).NotTo(BeNil())
+			Expect(indexer.dialer.TLS.InsecureSkipVerify).To(BeFalse())
+			Expect(indexer.writer.(*kafka.Writer).Transport.(*kafka.Transport).TLS).To(BeIdenticalTo(indexer.dialer.TLS))
+		})
+
+		It("Reaches the brokers over TLS with the configured CA and client certificate", func() {
+			server := httptest.NewTLSServer(http.NotFoundHandler())
+			defer server.Close()
+			caCertPath := writeCACert(server)
+			defer os.Remove(caCertPath)
+			indexerConfig.CACertPath = caCertPath
+			indexerConfig.ClientCert, indexerConfig.ClientKey = newClientCertificate()
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.dialer.TLS.RootCAs).NotTo(BeNil())
+			Expect(indexer.dialer.TLS.Certificates).To(HaveLen(1))
+		})
+
+		It("Returns err invalid TLS configuration", func() {
+			indexerConfig.ClientCert, _ = newClientCertificate()
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("client certificate and key must be specified together"))
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no brokers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("kafka brokers not specified")))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer Kafka
+		var writer *mockKafkaWriter
+		BeforeEach(func() {
+			writer = &mockKafkaWriter{}
+			indexer = Kafka{topic: "go-commons-test", writer: writer}
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Produces a message per document", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(writer.messages).To(HaveLen(3))
+			Expect(writer.messages[1].Value).To(MatchJSON(`{"document":42,"metricName":"placeholder"}`))
+			Expect(string(writer.messages[1].Key)).To(Equal(hashDocument([]byte("42"))))
+		})
+
+		It("Keys the documents with the content hash they're deduplicated on", func() {
+			documents := []interface{}{map[string]interface{}{"value": 1}, map[string]interface{}{"value": 1.0}}
+			result, err := indexer.IndexWithResult(context.Background(), documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(writer.messages).To(HaveLen(1))
+			_, hash, err := encodeDocument(documents[1], testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(string(writer.messages[0].Key)).To(Equal(hash))
+		})
+
+		It("Sets the indexing timestamp on every document", func() {
+			testcase.opts.AddTimestamp = true
+			_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(writer.messages).To(HaveLen(3))
+			var document map[string]interface{}
+			Expect(json.Unmarshal(writer.messages[1].Value, &document)).To(Succeed())
+			Expect(document).To(HaveKeyWithValue("document", BeEquivalentTo(42)))
+			Expect(document).To(HaveKeyWithValue("metadata", HaveKey("timestamp")))
+			Expect(string(writer.messages[1].Key)).To(Equal(hashDocument([]byte("42"))))
+		})
+
+		It("Doesn't produce any message in dry-run mode", func() {
+			testcase.opts.DryRun = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 3))
+			Expect(writer.messages).To(BeEmpty())
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(writer.messages).To(HaveLen(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Returns err when the producer fails", func() {
+			writer.err = errors.New("broker not available")
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeEquivalentTo(errors.New("Unexpected Kafka error: broker not available")))
+		})
+
+		It("err returned docs not processed", func() {
+			testcase.documents = append(testcase.documents, make(chan string))
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err.Error()).To(ContainSubstring("Cannot encode document"))
+		})
+
+		It("Closes the writer", func() {
+			Expect(indexer.Close()).To(BeNil())
+			Expect(writer.closed).To(BeTrue())
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer Kafka
+
+		It("Connects to a broker", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			defer listener.Close()
+			Expect(indexer.New(IndexerConfig{Servers: []string{"127.0.0.1:1", listener.Addr().String()}, Index: "go-commons-test"})).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Returns ErrUnreachable when no broker accepts the connection", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			address := listener.Addr().String()
+			listener.Close()
+			Expect(indexer.New(IndexerConfig{Servers: []string{address}, Index: "go-commons-test"})).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
@@ -0,0 +1,182 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/ingest"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+)
+
+const kustoIndexer = "kusto"
+
+// kustoIngestor queues data for ingestion into a Kusto table
+type kustoIngestor interface {
+	FromReader(ctx context.Context, reader io.Reader, options ...ingest.FileOption) (*ingest.Result, error)
+	Close() error
+}
+
+// Kusto Azure Data Explorer instance
+type Kusto struct {
+	client    *kusto.Client
+	ingestor  kustoIngestor
+	database  string
+	flushDocs int
+}
+
+// Init function
+func init() {
+	Register(kustoIndexer, func() Indexer { return &Kusto{} })
+}
+
+// Returns new indexer for Azure Data Explorer, Servers[0] being the cluster URL. The indexer authenticates as the
+// service principal of the Username application ID and Password client secret in the TenantID tenant, and
+// queues the documents for ingestion into the Index table of the configured database
+func (k *Kusto) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if indexerConfig.Database == "" {
+		return fmt.Errorf("database not specified")
+	}
+	if indexerConfig.Username == "" || indexerConfig.Password == "" || indexerConfig.TenantID == "" {
+		return fmt.Errorf("service principal credentials not specified")
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	kcsb := kusto.NewConnectionStringBuilder(indexerConfig.Servers[0]).
+		WithAadAppKey(indexerConfig.Username, indexerConfig.Password, indexerConfig.TenantID)
+	client, err := kusto.New(kcsb)
+	if err != nil {
+		return fmt.Errorf("error creating the Kusto client: %s", err)
+	}
+	ingestor, err := ingest.New(client, indexerConfig.Database, indexerConfig.Index)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("error creating the Kusto ingestion client: %s", err)
+	}
+	k.client = client
+	k.ingestor = ingestor
+	k.database = indexerConfig.Database
+	k.flushDocs = indexerConfig.FlushDocs
+	return nil
+}
+
+// Index queues the documents for ingestion into the configured table
+func (k *Kusto) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := k.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult queues the documents as JSON lines for ingestion into the configured table, in batches of up to
+// flushDocs documents, and returns the indexing result. Kusto ingests the queued batches asynchronously, so the
+// documents are reported in the queued stat
+func (k *Kusto) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, k.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var batch bytes.Buffer
+	batchDocs := 0
+	flush := func() error {
+		if batchDocs == 0 {
+			return nil
+		}
+		if _, err := k.ingestor.FromReader(ctx, bytes.NewReader(batch.Bytes()), ingest.FileFormat(ingest.JSON)); err != nil {
+			return fmt.Errorf("Unexpected Kusto error: %s", err)
+		}
+		indexerStats[queuedStat] += batchDocs
+		batch.Reset()
+		batchDocs = 0
+		return nil
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		// Kusto maps the properties of JSON objects to the table columns
+		if j, err = decorateDocument(objectDocument(j), fields); err != nil {
+			return IndexingResult{}, err
+		}
+		batch.Write(j)
+		batch.WriteByte('\n')
+		if batchDocs++; batchDocs == k.flushDocs {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if err := flush(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// Ping checks the Kusto cluster can be queried
+func (k *Kusto) Ping(ctx context.Context) error {
+	rows, err := k.client.Mgmt(ctx, k.database, kql.New(".show version"))
+	if err != nil {
+		return pingFailed("Kusto", ErrUnreachable, err)
+	}
+	rows.Stop()
+	return nil
+}
+
+// Close stops the ingestion client and closes the Kusto client
+func (k *Kusto) Close() error {
+	if k.ingestor != nil {
+		if err := k.ingestor.Close(); err != nil {
+			return err
+		}
+	}
+	if k.client != nil {
+		return k.client.Close()
+	}
+	return nil
+}
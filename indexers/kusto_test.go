@@ -0,0 +1,192 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/ingest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// mockKustoIngestor records the batches queued for ingestion
+type mockKustoIngestor struct {
+	batches []string
+	err     error
+	closed  bool
+}
+
+func (m *mockKustoIngestor) FromReader(ctx context.Context, reader io.Reader, options ...ingest.FileOption) (*ingest.Result, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	batch, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	m.batches = append(m.batches, string(batch))
+	return &ingest.Result{}, nil
+}
+
+func (m *mockKustoIngestor) Close() error {
+	m.closed = true
+	return nil
+}
+
+var _ = Describe("Tests for kusto.go", func() {
+	Context("Tests for New()", func() {
+		var indexerConfig IndexerConfig
+		var indexer Kusto
+		BeforeEach(func() {
+			indexerConfig = IndexerConfig{Type: "kusto",
+				Servers:  []string{"https://perf.westeurope.kusto.windows.net"},
+				Index:    "Metrics",
+				Database: "perf",
+				Username: "00000000-0000-0000-0000-000000000000",
+				Password: "secret",
+				TenantID: "11111111-1111-1111-1111-111111111111",
+			}
+		})
+
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.Close()).To(BeNil())
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = nil
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
+		It("Returns err no database", func() {
+			indexerConfig.Database = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("database not specified")))
+		})
+
+		It("Returns err no service principal", func() {
+			indexerConfig.TenantID = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("service principal credentials not specified")))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer Kusto
+		var ingestor *mockKustoIngestor
+		BeforeEach(func() {
+			ingestor = &mockKustoIngestor{}
+			indexer = Kusto{ingestor: ingestor}
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Queues the documents as JSON objects", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue(queuedStat, 3))
+			Expect(ingestor.batches).To(HaveLen(1))
+			lines := strings.Split(strings.TrimSuffix(ingestor.batches[0], "\n"), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0]).To(MatchJSON(`{"document":"example document","metricName":"placeholder"}`))
+			Expect(lines[1]).To(MatchJSON(`{"document":42,"metricName":"placeholder"}`))
+			Expect(lines[2]).To(MatchJSON(`{"key1":"value1","key2":123,"metricName":"placeholder"}`))
+		})
+
+		It("Splits the batches every flushDocs documents", func() {
+			indexer.flushDocs = 2
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue(queuedStat, 3))
+			Expect(ingestor.batches).To(HaveLen(2))
+			Expect(strings.Count(ingestor.batches[1], "\n")).To(Equal(1))
+		})
+
+		It("Doesn't queue any batch in dry-run mode", func() {
+			testcase.opts.DryRun = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 3))
+			Expect(ingestor.batches).To(BeEmpty())
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(result.Stats).To(HaveKeyWithValue(queuedStat, 3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Returns err when the ingestion fails", func() {
+			ingestor.err = errors.New("table not found")
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeEquivalentTo(errors.New("Unexpected Kusto error: table not found")))
+		})
+
+		It("Closes the ingestion client", func() {
+			Expect(indexer.Close()).To(BeNil())
+			Expect(ingestor.closed).To(BeTrue())
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer Kusto
+		var status int
+		var paths []string
+		BeforeEach(func() {
+			status, paths = http.StatusOK, nil
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"BuildVersion","DataType":"String","ColumnType":"string"}],"Rows":[["1.0.0"]]}]}`))
+			}))
+			DeferCleanup(server.Close)
+			client, err := kusto.New(kusto.NewConnectionStringBuilder(server.URL).WithApplicationToken("go-commons", "token"), kusto.WithHttpClient(server.Client()))
+			Expect(err).To(BeNil())
+			DeferCleanup(client.Close)
+			indexer = Kusto{client: client, database: "perf"}
+		})
+
+		It("Queries the cluster version", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			Expect(paths).To(ContainElement("/v1/rest/mgmt"))
+		})
+
+		It("Returns ErrUnreachable when the cluster can't be queried", func() {
+			status = http.StatusServiceUnavailable
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
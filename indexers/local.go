@@ -15,10 +15,13 @@
 package indexers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"time"
 )
 
 const local = "local"
@@ -26,38 +29,140 @@ const local = "local"
 // Local indexer instance
 type Local struct {
 	metricsDirectory string
+	filename         string
 }
 
 // Init function
 func init() {
-	indexerMap[local] = &Local{}
+	Register(local, func() Indexer { return &Local{} })
 }
 
 // Prepares local indexing directory
-func (l *Local) new(indexerConfig IndexerConfig) error {
+func (l *Local) New(indexerConfig IndexerConfig) error {
 	if indexerConfig.MetricsDirectory == "" {
 		return fmt.Errorf("directory name not specified")
 	}
 	l.metricsDirectory = indexerConfig.MetricsDirectory
-	err := os.MkdirAll(l.metricsDirectory, 0744)
-	return err
+	l.filename = ""
+	if err := os.MkdirAll(l.metricsDirectory, 0744); err != nil {
+		return err
+	}
+	if indexerConfig.LineDelimited {
+		if indexerConfig.Index == "" {
+			return fmt.Errorf("index name not specified")
+		}
+		l.filename = path.Join(l.metricsDirectory, fmt.Sprintf("%s.json", indexerConfig.Index))
+		f, err := os.Create(l.filename)
+		if err != nil {
+			return fmt.Errorf("Error creating metrics file %s: %s", l.filename, err)
+		}
+		return f.Close()
+	}
+	return nil
 }
 
 // Index uses generates a local file with the given name and metrics
-func (l *Local) Index(documents []interface{}, opts IndexingOpts) (string, error) {
+func (l *Local) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	result, err := l.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.DryRun {
+		return result.String(), nil
+	}
+	if l.filename != "" {
+		return fmt.Sprintf("File %s appended with %d documents", l.filename, result.Created), nil
+	}
+	return fmt.Sprintf("File %s created with %d documents", l.metricsFile(opts), result.Created), nil
+}
+
+// IndexWithResult generates a local file with the given name and metrics and returns the indexing result
+func (l *Local) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, l.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	batch, redundantSkipped, err := encodeBatch(ctx, documents, opts)
+	if err != nil {
+		return IndexingResult{}, err
+	}
+	if _, err := l.writeDocuments(batch, opts); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(map[string]int{"created": len(batch)}, redundantSkipped, time.Since(start)), nil
+}
+
+// writeDocuments writes the encoded documents to a local file as a JSON array and returns its name
+func (l *Local) writeDocuments(batch [][]byte, opts IndexingOpts) (string, error) {
+	if l.filename != "" {
+		return l.appendDocuments(batch)
+	}
 	if opts.MetricName == "" {
 		return "", fmt.Errorf("MetricName shouldn't be empty")
 	}
-	metricName := fmt.Sprintf("%s.json", opts.MetricName)
-	filename := path.Join(l.metricsDirectory, metricName)
-	f, err := os.Create(filename)
-	if err != nil {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, j := range batch {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(j)
+	}
+	buf.WriteByte(']')
+	if opts.Indent {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+			return "", fmt.Errorf("JSON encoding error: %s", err)
+		}
+		buf = indented
+	}
+	buf.WriteByte('\n')
+	filename := l.metricsFile(opts)
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
 		return "", fmt.Errorf("Error creating metrics file %s: %s", filename, err)
 	}
+	return filename, nil
+}
+
+// metricsFile returns the name of the file the documents of the given metric are written to
+func (l *Local) metricsFile(opts IndexingOpts) string {
+	return path.Join(l.metricsDirectory, fmt.Sprintf("%s.json", opts.MetricName))
+}
+
+// appendDocuments appends the encoded documents as JSON lines to the indexer file and returns its name
+func (l *Local) appendDocuments(batch [][]byte) (string, error) {
+	var buf bytes.Buffer
+	for _, j := range batch {
+		buf.Write(j)
+		buf.WriteByte('\n')
+	}
+	f, err := os.OpenFile(l.filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return "", fmt.Errorf("Error opening metrics file %s: %s", l.filename, err)
+	}
 	defer f.Close()
-	jsonEnc := json.NewEncoder(f)
-	if err := jsonEnc.Encode(documents); err != nil {
-		return "", fmt.Errorf("JSON encoding error: %s", err)
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return "", fmt.Errorf("Error writing metrics file %s: %s", l.filename, err)
+	}
+	return l.filename, nil
+}
+
+// Ping checks the metrics directory exists
+func (l *Local) Ping(ctx context.Context) error {
+	info, err := os.Stat(l.metricsDirectory)
+	if err != nil {
+		return pingFailed("local", ErrUnreachable, err)
+	}
+	if !info.IsDir() {
+		return pingFailed("local", ErrUnreachable, fmt.Sprintf("%s isn't a directory", l.metricsDirectory))
 	}
-	return fmt.Sprintf("File %s created with %d documents", filename, len(documents)), nil
+	return nil
+}
+
+// Close is a no-op, the metrics files are closed after every write
+func (l *Local) Close() error {
+	return nil
 }
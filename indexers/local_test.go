@@ -1,9 +1,14 @@
 package indexers
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -11,7 +16,7 @@ import (
 
 // testing local.go
 var _ = Describe("Tests for local.go", func() {
-	Context("Default behavior of local.go, new()", func() {
+	Context("Default behavior of local.go, New()", func() {
 		type newtestcase struct {
 			indexerconfig IndexerConfig
 		}
@@ -29,13 +34,13 @@ var _ = Describe("Tests for local.go", func() {
 		})
 
 		It("returns err no metrics directory", func() {
-			err := localIndexer.new(testcase.indexerconfig)
+			err := localIndexer.New(testcase.indexerconfig)
 			Expect(err).To(BeEquivalentTo(errors.New("directory name not specified")))
 		})
 
 		It("returns nil as error", func() {
 			testcase.indexerconfig.MetricsDirectory = "placeholder"
-			err := localIndexer.new(testcase.indexerconfig)
+			err := localIndexer.New(testcase.indexerconfig)
 			Expect(err).To(BeNil())
 		})
 	})
@@ -43,8 +48,8 @@ var _ = Describe("Tests for local.go", func() {
 	Context("Default behaviour of local.go, Index()", func() {
 		var testcase indexMethodTestcase
 		var indexer Local
-		indexer.metricsDirectory = "placeholder"
 		BeforeEach(func() {
+			indexer = Local{metricsDirectory: "placeholder"}
 			err := os.MkdirAll(indexer.metricsDirectory, 0744)
 			if err != nil {
 				log.Fatal(err)
@@ -80,25 +85,184 @@ var _ = Describe("Tests for local.go", func() {
 		})
 
 		It("No err is returned", func() {
-			_, err := indexer.Index(testcase.documents, testcase.opts)
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
 			Expect(err).To(BeNil())
 		})
 
 		It("No err is returned", func() {
-			_, err := indexer.Index(testcase.documents, testcase.opts)
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
 			Expect(err).To(BeNil())
 		})
 
+		It("Returns the context error when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := indexer.Index(ctx, testcase.documents, testcase.opts)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("Returns the structured indexing result", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			Expect(result.Failed).To(Equal(0))
+		})
+
+		It("Indents the documents when requested", func() {
+			testcase.opts.Indent = true
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			content, err := os.ReadFile(path.Join(indexer.metricsDirectory, "placeholder.json"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(HavePrefix("[\n  {\n    \"document\": \"example document\",\n    \"metricName\": \"placeholder\"\n  },\n"))
+		})
+
 		It("Err is returned metricsdirectory has fault", func() {
 			indexer.metricsDirectory = "abc"
-			_, err := indexer.Index(testcase.documents, testcase.opts)
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
 			Expect(err).To(BeEquivalentTo(errors.New("Error creating metrics file abc/placeholder.json: open abc/placeholder.json: no such file or directory")))
 		})
 
 		It("Err is returned by documents not processed", func() {
 			testcase.documents = append(testcase.documents, make(chan string))
-			_, err := indexer.Index(testcase.documents, testcase.opts)
-			Expect(err).To(BeEquivalentTo(errors.New("JSON encoding error: json: unsupported type: chan string")))
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err.Error()).To(ContainSubstring("Cannot encode document"))
+		})
+
+		It("Sets the indexing timestamp on every document", func() {
+			testcase.opts.AddTimestamp = true
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			content, err := os.ReadFile(path.Join(indexer.metricsDirectory, "placeholder.json"))
+			Expect(err).To(BeNil())
+			var written []map[string]interface{}
+			Expect(json.Unmarshal(content, &written)).To(Succeed())
+			Expect(written).To(HaveLen(len(testcase.documents)))
+			for _, document := range written {
+				Expect(document).To(HaveKeyWithValue("metadata", HaveKey("timestamp")))
+			}
+		})
+
+		It("Projects the documents down to the included fields", func() {
+			testcase.documents = []interface{}{map[string]interface{}{"uuid": "abc", "value": 1, "labels": map[string]string{"node": "worker"}}}
+			testcase.opts.IncludeFields = []string{"uuid", "value"}
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			content, err := os.ReadFile(path.Join(indexer.metricsDirectory, "placeholder.json"))
+			Expect(err).To(BeNil())
+			Expect(content).To(MatchJSON(`[{"uuid":"abc","value":1,"metricName":"placeholder"}]`))
+		})
+
+		It("Flattens the nested documents", func() {
+			testcase.documents = []interface{}{map[string]interface{}{"uuid": "abc", "labels": map[string]string{"node": "worker"}}}
+			testcase.opts.Flatten = true
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			content, err := os.ReadFile(path.Join(indexer.metricsDirectory, "placeholder.json"))
+			Expect(err).To(BeNil())
+			Expect(content).To(MatchJSON(`[{"uuid":"abc","labels.node":"worker","metricName":"placeholder"}]`))
+		})
+
+		It("Doesn't write the file in dry-run mode", func() {
+			testcase.opts.DryRun = true
+			msg, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("validated"))
+			_, err = os.Stat(path.Join(indexer.metricsDirectory, "placeholder.json"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("Skips the unencodable documents", func() {
+			testcase.documents = append(testcase.documents, make(chan string))
+			testcase.opts.SkipUnencodable = true
+			msg, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(msg).To(Equal("File placeholder/placeholder.json created with 6 documents"))
+			content, err := os.ReadFile(path.Join(indexer.metricsDirectory, "placeholder.json"))
+			Expect(err).To(BeNil())
+			var written []interface{}
+			Expect(json.Unmarshal(content, &written)).To(Succeed())
+			Expect(written).To(HaveLen(6))
+		})
+
+		It("Samples the documents", func() {
+			testcase.documents = nil
+			for i := 0; i < 100; i++ {
+				testcase.documents = append(testcase.documents, map[string]interface{}{"value": i})
+			}
+			testcase.opts.SampleRate = 0.25
+			kept := (&documentFilter{opts: testcase.opts}).documents(testcase.documents)
+			Expect(len(kept)).To(BeNumerically("<", len(testcase.documents)))
+			msg, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(msg).To(Equal(fmt.Sprintf("File placeholder/placeholder.json created with %d documents", len(kept))))
+			content, err := os.ReadFile(path.Join(indexer.metricsDirectory, "placeholder.json"))
+			Expect(err).To(BeNil())
+			var written []interface{}
+			Expect(json.Unmarshal(content, &written)).To(Succeed())
+			Expect(written).To(HaveLen(len(kept)))
+			testcase.opts.SampleRate = 2
+			_, err = indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError("invalid sample rate: 2"))
+		})
+	})
+
+	Context("Line delimited mode of local.go", func() {
+		var indexer Local
+		var indexerConfig IndexerConfig
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = os.MkdirTemp("", "go-commons-test")
+			Expect(err).To(BeNil())
+			indexerConfig = IndexerConfig{Type: "local",
+				Index:            "go-commons-test",
+				MetricsDirectory: dir,
+				LineDelimited:    true,
+			}
+		})
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Truncates the file on New()", func() {
+			filename := path.Join(dir, "go-commons-test.json")
+			Expect(os.WriteFile(filename, []byte("stale\n"), 0644)).To(Succeed())
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+			content, err := os.ReadFile(filename)
+			Expect(err).To(BeNil())
+			Expect(content).To(BeEmpty())
+		})
+
+		It("Appends a JSON line per document", func() {
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+			msg, err := indexer.Index(context.Background(), []interface{}{"example document", 42}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("with 2 documents"))
+			_, err = indexer.Index(context.Background(), []interface{}{map[string]interface{}{"key1": "value1"}}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			content, err := os.ReadFile(path.Join(dir, "go-commons-test.json"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(Equal("\"example document\"\n42\n{\"key1\":\"value1\"}\n"))
+		})
+
+		It("Tags the documents with the metric and job names they lack", func() {
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+			documents := []interface{}{42, map[string]interface{}{"metricName": "podLatency"}}
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{MetricName: "placeholder", JobName: "cluster-density"})
+			Expect(err).To(BeNil())
+			content, err := os.ReadFile(path.Join(dir, "go-commons-test.json"))
+			Expect(err).To(BeNil())
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[0]).To(MatchJSON(`{"document":42,"metricName":"placeholder","jobName":"cluster-density"}`))
+			Expect(lines[1]).To(MatchJSON(`{"metricName":"podLatency","jobName":"cluster-density"}`))
 		})
 	})
 })
@@ -0,0 +1,39 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+// Logger interface used by the indexers to report their activity
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// nopLogger discards all the messages
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+
+func (nopLogger) Infof(format string, args ...interface{}) {}
+
+func (nopLogger) Errorf(format string, args ...interface{}) {}
+
+// loggerOrNop returns the given logger, or a no-op logger when nil
+func loggerOrNop(logger Logger) Logger {
+	if logger != nil {
+		return logger
+	}
+	return nopLogger{}
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const lokiIndexer = "loki"
+
+// defaultLokiTimestampField document field holding the log line timestamp when no TimestampField is configured
+const defaultLokiTimestampField = "timestamp"
+
+// lokiStream log stream of the Loki push API
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiPushRequest body of the Loki push API
+type lokiPushRequest struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+// Loki log-push indexer instance
+type Loki struct {
+	url            string
+	client         *http.Client
+	index          string
+	username       string
+	password       string
+	apiKey         string
+	timestampField string
+	contentType    string
+}
+
+// Init function
+func init() {
+	Register(lokiIndexer, func() Indexer { return &Loki{} })
+}
+
+// Returns new indexer for Loki
+func (l *Loki) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	l.url = strings.TrimSuffix(indexerConfig.Servers[0], "/") + "/loki/api/v1/push"
+	l.client = &http.Client{Transport: transport}
+	l.index = indexerConfig.Index
+	l.username = indexerConfig.Username
+	l.password = indexerConfig.Password
+	l.apiKey = indexerConfig.APIKey
+	l.contentType = contentType(indexerConfig)
+	l.timestampField = indexerConfig.TimestampField
+	if l.timestampField == "" {
+		l.timestampField = defaultLokiTimestampField
+	}
+	return nil
+}
+
+// Index pushes the documents as log lines to Loki
+func (l *Loki) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := l.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult pushes the documents as log lines to Loki and returns the indexing result. Documents are grouped
+// in streams labelled with the index, the metric and job names and the values of the label fields
+func (l *Loki) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, l.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	streams := make(map[string]*lokiStream)
+	var push lokiPushRequest
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		timestamp, err := l.documentTimestamp(j, time.Now())
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		labels := l.streamLabels(j, opts)
+		key := streamKey(labels)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		stream, exists := streams[key]
+		if !exists {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			push.Streams = append(push.Streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(timestamp.UnixNano(), 10), string(j)})
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		indexerStats["created"]++
+	}
+	if err := l.push(ctx, push); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// documentTimestamp returns the timestamp read from the timestamp field of the document, defaulting to now
+func (l *Loki) documentTimestamp(j []byte, now time.Time) (time.Time, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(j, &fields); err != nil {
+		return now, nil
+	}
+	return sampleTimestamp(fields[l.timestampField], now)
+}
+
+// streamLabels returns the labels of the stream of the given document
+func (l *Loki) streamLabels(j []byte, opts IndexingOpts) map[string]string {
+	labels := map[string]string{"index": l.index}
+	if opts.MetricName != "" {
+		labels["metricName"] = opts.MetricName
+	}
+	if opts.JobName != "" {
+		labels["jobName"] = opts.JobName
+	}
+	for _, field := range opts.LabelFields {
+		if value, exists := documentField(j, field); exists {
+			labels[field] = value
+		}
+	}
+	return labels
+}
+
+// streamKey returns a key identifying the stream with the given labels
+func streamKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, strconv.Quote(name)+"="+strconv.Quote(value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// push posts the streams to the Loki push endpoint
+func (l *Loki) push(ctx context.Context, push lokiPushRequest) error {
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", l.contentType)
+	l.authorize(req)
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unexpected Loki error: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected Loki response %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Ping checks Loki is reachable and ready through its readiness endpoint
+func (l *Loki) Ping(ctx context.Context) error {
+	return pingHTTP(ctx, "Loki", l.client, strings.TrimSuffix(l.url, "/loki/api/v1/push")+"/ready", l.authorize)
+}
+
+// authorize sets the credentials of the request, the API key taking precedence over basic auth
+func (l *Loki) authorize(req *http.Request) {
+	if l.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.apiKey)
+	} else if l.username != "" {
+		req.SetBasicAuth(l.username, l.password)
+	}
+}
+
+// Close closes the idle connections of the Loki client
+func (l *Loki) Close() error {
+	if l.client != nil {
+		l.client.CloseIdleConnections()
+	}
+	return nil
+}
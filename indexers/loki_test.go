@@ -0,0 +1,178 @@
+package indexers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for loki.go", func() {
+	var indexerConfig IndexerConfig
+	var indexer Loki
+	var server *httptest.Server
+	var requests []*http.Request
+	var pushes []lokiPushRequest
+	BeforeEach(func() {
+		requests = nil
+		pushes = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var push lokiPushRequest
+			Expect(json.NewDecoder(r.Body).Decode(&push)).To(Succeed())
+			requests = append(requests, r)
+			pushes = append(pushes, push)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		indexerConfig = IndexerConfig{Type: "loki",
+			Servers: []string{server.URL},
+			Index:   "go-commons-test",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Tests for New()", func() {
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.url).To(Equal(server.URL + "/loki/api/v1/push"))
+			Expect(indexer.timestampField).To(Equal("timestamp"))
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("index name not specified"))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("servers not specified"))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var timestamp time.Time
+		BeforeEach(func() {
+			timestamp = time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+		})
+
+		It("Groups the documents in streams by label", func() {
+			documents := []interface{}{
+				map[string]interface{}{"namespace": "a", "value": 1, "timestamp": timestamp.Format(time.RFC3339)},
+				map[string]interface{}{"namespace": "b", "value": 2, "timestamp": timestamp.UnixMilli()},
+				map[string]interface{}{"namespace": "a", "value": 3, "timestamp": timestamp.Format(time.RFC3339)},
+			}
+			opts := IndexingOpts{MetricName: "podLatency", JobName: "density", LabelFields: []string{"namespace"}}
+			result, err := indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/loki/api/v1/push"))
+			streams := pushes[0].Streams
+			Expect(streams).To(HaveLen(2))
+			Expect(streams[0].Stream).To(Equal(map[string]string{"index": "go-commons-test", "metricName": "podLatency", "jobName": "density", "namespace": "a"}))
+			Expect(streams[0].Values).To(HaveLen(2))
+			Expect(streams[1].Stream).To(HaveKeyWithValue("namespace", "b"))
+			Expect(streams[1].Values).To(HaveLen(1))
+			Expect(streams[1].Values[0][0]).To(Equal("1685613600000000000"))
+			Expect(streams[1].Values[0][1]).To(MatchJSON(`{"namespace":"b","value":2,"timestamp":1685613600000,"metricName":"podLatency","jobName":"density"}`))
+		})
+
+		It("Defaults the timestamp to now", func() {
+			before := time.Now()
+			_, err := indexer.Index(context.Background(), []interface{}{42}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(pushes).To(HaveLen(1))
+			Expect(pushes[0].Streams[0].Stream).To(Equal(map[string]string{"index": "go-commons-test"}))
+			value := pushes[0].Streams[0].Values[0]
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+			Expect(err).To(BeNil())
+			Expect(ns).To(BeNumerically(">=", before.UnixNano()))
+			Expect(value[1]).To(Equal("42"))
+		})
+
+		It("Reads the timestamp from the configured field", func() {
+			indexerConfig.TimestampField = "ts"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"ts": timestamp.UnixMilli()}}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(pushes[0].Streams[0].Values[0][0]).To(Equal("1685613600000000000"))
+		})
+
+		It("Sends the API key as bearer token", func() {
+			indexerConfig.APIKey = "secret"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{1}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer secret"))
+		})
+
+		It("Sends the configured content type", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{1}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+			indexerConfig.ContentType = "application/json; charset=utf-8"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err = indexer.Index(context.Background(), []interface{}{2}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(requests[1].Header.Get("Content-Type")).To(Equal("application/json; charset=utf-8"))
+		})
+
+		It("Skips the redundant documents", func() {
+			document := map[string]interface{}{"value": 1}
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{document, document}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Skipped).To(Equal(1))
+		})
+
+		It("Returns err invalid timestamp", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"timestamp": "yesterday"}}, IndexingOpts{})
+			Expect(err).To(MatchError(ContainSubstring("invalid sample timestamp yesterday")))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err when Loki rejects the streams", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "entry too far behind", http.StatusBadRequest)
+			})
+			_, err := indexer.Index(context.Background(), []interface{}{1}, IndexingOpts{})
+			Expect(err).To(MatchError("Unexpected Loki response 400: entry too far behind"))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Checks the readiness endpoint with the credentials", func() {
+			var readiness *http.Request
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				readiness = r
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnauthorized))
+			indexerConfig.APIKey = "secret"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			Expect(readiness.URL.Path).To(Equal("/ready"))
+		})
+
+		It("Returns err unhealthy when Loki isn't ready", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Ingester not ready", http.StatusServiceUnavailable)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError("Loki backend unhealthy: status code 503"))
+		})
+	})
+})
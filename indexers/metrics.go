@@ -0,0 +1,99 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// indexingMetrics Prometheus metrics of the indexing calls, a nil value discarding them
+type indexingMetrics struct {
+	documents *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	labels    prometheus.Labels
+}
+
+// newIndexingMetrics registers the indexing metrics in the configured registerer, returning nil when it's not set.
+// Indexers sharing a registerer share the metrics, telling their series apart by their indexer and index labels
+func newIndexingMetrics(indexerConfig IndexerConfig, index string) (*indexingMetrics, error) {
+	if indexerConfig.MetricsRegisterer == nil {
+		return nil, nil
+	}
+	labelNames := []string{"indexer", "index"}
+	documents := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "go_commons_indexer_documents_total",
+		Help: "Number of documents sent to the indexer, by result: created, updated or failed",
+	}, append(labelNames, "result"))
+	indexingErrors := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "go_commons_indexer_errors_total",
+		Help: "Number of indexing calls that returned an error",
+	}, labelNames)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "go_commons_indexer_bulk_duration_seconds",
+		Help:    "Duration of the bulk indexing calls",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	}, labelNames)
+	existing, err := registerCollector(indexerConfig.MetricsRegisterer, documents)
+	if err != nil {
+		return nil, err
+	}
+	documents = existing.(*prometheus.CounterVec)
+	if existing, err = registerCollector(indexerConfig.MetricsRegisterer, indexingErrors); err != nil {
+		return nil, err
+	}
+	indexingErrors = existing.(*prometheus.CounterVec)
+	if existing, err = registerCollector(indexerConfig.MetricsRegisterer, duration); err != nil {
+		return nil, err
+	}
+	duration = existing.(*prometheus.HistogramVec)
+	return &indexingMetrics{
+		documents: documents,
+		errors:    indexingErrors,
+		duration:  duration,
+		labels:    prometheus.Labels{"indexer": string(indexerConfig.Type), "index": index},
+	}, nil
+}
+
+// registerCollector registers the given collector, returning the one already registered by another indexer if any
+func registerCollector(registerer prometheus.Registerer, collector prometheus.Collector) (prometheus.Collector, error) {
+	err := registerer.Register(collector)
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		return alreadyRegistered.ExistingCollector, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error registering the indexing metrics: %s", err)
+	}
+	return collector, nil
+}
+
+// observe records the outcome of an indexing call
+func (m *indexingMetrics) observe(result IndexingResult, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.errors.With(m.labels).Inc()
+		return
+	}
+	for outcome, count := range map[string]int{"created": result.Created, "updated": result.Updated, "failed": result.Failed} {
+		m.documents.MustCurryWith(m.labels).WithLabelValues(outcome).Add(float64(count))
+	}
+	m.duration.With(m.labels).Observe(result.Duration.Seconds())
+}
@@ -0,0 +1,76 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Tests for metrics.go", func() {
+	Context("Tests for newIndexingMetrics()", func() {
+		It("Returns nil without registerer", func() {
+			metrics, err := newIndexingMetrics(IndexerConfig{Type: ElasticIndexer}, "go-commons-test")
+			Expect(err).To(BeNil())
+			Expect(metrics).To(BeNil())
+			metrics.observe(IndexingResult{Created: 1}, nil)
+		})
+
+		It("Shares the metrics between the indexers of a registerer", func() {
+			registry := prometheus.NewRegistry()
+			first, err := newIndexingMetrics(IndexerConfig{Type: ElasticIndexer, MetricsRegisterer: registry}, "first")
+			Expect(err).To(BeNil())
+			second, err := newIndexingMetrics(IndexerConfig{Type: ElasticIndexer, MetricsRegisterer: registry}, "second")
+			Expect(err).To(BeNil())
+			first.observe(IndexingResult{Created: 2}, nil)
+			second.observe(IndexingResult{Created: 3}, nil)
+			second.observe(IndexingResult{}, errors.New("connection refused"))
+			Expect(testutil.ToFloat64(first.documents.WithLabelValues("elastic", "first", "created"))).To(Equal(2.0))
+			Expect(testutil.ToFloat64(first.documents.WithLabelValues("elastic", "second", "created"))).To(Equal(3.0))
+			Expect(testutil.ToFloat64(first.errors.WithLabelValues("elastic", "second"))).To(Equal(1.0))
+		})
+	})
+
+	Context("Tests for the indexing metrics", func() {
+		var registry *prometheus.Registry
+		BeforeEach(func() {
+			registry = prometheus.NewRegistry()
+		})
+
+		It("Counts the documents indexed in ES", func() {
+			var indexer Elastic
+			mockServer := newBulkMockServer(func(n int) int {
+				if n == 0 {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			})
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Type: ElasticIndexer, Servers: []string{mockServer.URL}, Index: "go-commons-test", MetricsRegisterer: registry})
+			Expect(err).To(BeNil())
+			documents := []interface{}{map[string]interface{}{"value": 1}, map[string]interface{}{"value": 2}, map[string]interface{}{"value": 3}}
+			_, err = indexer.IndexWithResult(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(testutil.ToFloat64(indexer.metrics.documents.WithLabelValues("elastic", "go-commons-test", "created"))).To(Equal(2.0))
+			Expect(testutil.ToFloat64(indexer.metrics.documents.WithLabelValues("elastic", "go-commons-test", "failed"))).To(Equal(1.0))
+			Expect(testutil.CollectAndCount(registry, "go_commons_indexer_bulk_duration_seconds")).To(Equal(1))
+		})
+
+		It("Counts the OpenSearch indexing errors", func() {
+			var indexer OpenSearch
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Type: OpenSearchIndexer, Servers: []string{mockServer.URL}, Index: "go-commons-test", MetricsRegisterer: registry})
+			Expect(err).To(BeNil())
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = indexer.IndexWithResult(ctx, []interface{}{1}, IndexingOpts{})
+			Expect(err).ToNot(BeNil())
+			Expect(testutil.ToFloat64(indexer.metrics.errors.WithLabelValues("opensearch", "go-commons-test"))).To(Equal(1.0))
+		})
+	})
+})
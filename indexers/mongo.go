@@ -0,0 +1,189 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+const mongoDBIndexer = "mongodb"
+
+// defaultMongoDBDatabase database used when the connection string doesn't specify one
+const defaultMongoDBDatabase = "go-commons"
+
+// MongoDB error codes reporting rejected credentials
+const (
+	mongoUnauthorized         = 13
+	mongoAuthenticationFailed = 18
+)
+
+// mongoCollection writes documents to a MongoDB collection
+type mongoCollection interface {
+	BulkWrite(context.Context, []mongo.WriteModel, ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+}
+
+// MongoDB MongoDB instance
+type MongoDB struct {
+	client     *mongo.Client
+	collection mongoCollection
+}
+
+// Init function
+func init() {
+	Register(mongoDBIndexer, func() Indexer { return &MongoDB{} })
+}
+
+// Returns new indexer for MongoDB
+func (m *MongoDB) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	uri := indexerConfig.Servers[0]
+	cs, err := connstring.ParseAndValidate(uri)
+	if err != nil {
+		return fmt.Errorf("invalid MongoDB URI: %s", err)
+	}
+	clientOptions := options.Client().ApplyURI(uri)
+	if indexerConfig.Username != "" {
+		clientOptions.SetAuth(options.Credential{Username: indexerConfig.Username, Password: indexerConfig.Password})
+	}
+	if indexerConfig.InsecureSkipVerify || indexerConfig.CACertPath != "" {
+		tlsClientConfig, err := tlsConfig(indexerConfig)
+		if err != nil {
+			return err
+		}
+		clientOptions.SetTLSConfig(tlsClientConfig)
+	}
+	// Connect doesn't reach the servers, connections are established on the first operation
+	client, err := mongo.Connect(context.Background(), clientOptions)
+	if err != nil {
+		return fmt.Errorf("error creating the MongoDB client: %s", err)
+	}
+	database := cs.Database
+	if database == "" {
+		database = defaultMongoDBDatabase
+	}
+	m.client = client
+	m.collection = client.Database(database).Collection(indexerConfig.Index)
+	return nil
+}
+
+// Index upserts the documents into the configured collection
+func (m *MongoDB) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := m.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult upserts the documents into the configured collection and returns the indexing result.
+// Documents are keyed by their ID, so indexing the same document twice replaces it
+func (m *MongoDB) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, m.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var models []mongo.WriteModel
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		docId := documentID(j, opts.DocumentIDField, docHashKey)
+		reportDocumentID(opts, document, docId)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		mongoDoc, err := mongoDocument(j, docId)
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": docId}).
+			SetReplacement(mongoDoc).
+			SetUpsert(true))
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	res, err := m.collection.BulkWrite(ctx, models)
+	if err != nil {
+		return IndexingResult{}, fmt.Errorf("Unexpected MongoDB error: %s", err)
+	}
+	indexerStats["created"] = int(res.UpsertedCount)
+	indexerStats["updated"] = int(res.MatchedCount)
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// Ping checks the MongoDB servers are reachable, telling the rejected credentials apart
+func (m *MongoDB) Ping(ctx context.Context) error {
+	err := m.client.Ping(ctx, nil)
+	if err == nil {
+		return nil
+	}
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.HasErrorCode(mongoUnauthorized) || cmdErr.HasErrorCode(mongoAuthenticationFailed)) {
+		return pingFailed("MongoDB", ErrUnauthorized, err)
+	}
+	return pingFailed("MongoDB", ErrUnreachable, err)
+}
+
+// Close disconnects the MongoDB client
+func (m *MongoDB) Close() error {
+	if m.client == nil {
+		return nil
+	}
+	return m.client.Disconnect(context.Background())
+}
+
+// mongoDocument converts the JSON document to a BSON document with the given _id
+func mongoDocument(j []byte, docId string) (bson.M, error) {
+	j = objectDocument(j)
+	var doc bson.M
+	if err := bson.UnmarshalExtJSON(j, false, &doc); err != nil {
+		return nil, fmt.Errorf("Cannot decode document %s: %s", j, err)
+	}
+	doc["_id"] = docId
+	return doc, nil
+}
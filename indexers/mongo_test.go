@@ -0,0 +1,185 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mockMongoCollection keeps the upserted documents in memory, keyed by _id
+type mockMongoCollection struct {
+	sync.Mutex
+	documents map[string]bson.M
+	err       error
+}
+
+func (m *mockMongoCollection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	result := &mongo.BulkWriteResult{}
+	for _, model := range models {
+		replace := model.(*mongo.ReplaceOneModel)
+		id := replace.Filter.(bson.M)["_id"].(string)
+		if _, exists := m.documents[id]; exists {
+			result.MatchedCount++
+		} else {
+			result.UpsertedCount++
+		}
+		m.documents[id] = replace.Replacement.(bson.M)
+	}
+	return result, nil
+}
+
+var _ = Describe("Tests for mongo.go", func() {
+	Context("Tests for New()", func() {
+		var indexerConfig IndexerConfig
+		var indexer MongoDB
+		BeforeEach(func() {
+			indexerConfig = IndexerConfig{Type: "mongodb",
+				Servers: []string{"mongodb://localhost:27017/perf"},
+				Index:   "go-commons-test",
+			}
+		})
+
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			collection := indexer.collection.(*mongo.Collection)
+			Expect(collection.Name()).To(Equal("go-commons-test"))
+			Expect(collection.Database().Name()).To(Equal("perf"))
+			Expect(indexer.Close()).To(BeNil())
+		})
+
+		It("Uses the default database", func() {
+			indexerConfig.Servers = []string{"mongodb://localhost:27017"}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.collection.(*mongo.Collection).Database().Name()).To(Equal(defaultMongoDBDatabase))
+			Expect(indexer.Close()).To(BeNil())
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
+		It("Returns err invalid URI", func() {
+			indexerConfig.Servers = []string{"localhost:27017"}
+			err := indexer.New(indexerConfig)
+			Expect(err.Error()).To(ContainSubstring("invalid MongoDB URI"))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer MongoDB
+		var collection *mockMongoCollection
+		BeforeEach(func() {
+			collection = &mockMongoCollection{documents: make(map[string]bson.M)}
+			indexer = MongoDB{collection: collection}
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Upserts a document per document ID", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(collection.documents).To(HaveLen(3))
+			id := hashDocument([]byte("42"))
+			Expect(collection.documents).To(HaveKey(id))
+			Expect(collection.documents[id]).To(HaveKeyWithValue("document", BeEquivalentTo(42)))
+			Expect(collection.documents[id]).To(HaveKeyWithValue("metricName", "placeholder"))
+			Expect(collection.documents[id]).To(HaveKeyWithValue("_id", id))
+		})
+
+		It("Replaces the documents indexed twice", func() {
+			_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(0))
+			Expect(result.Updated).To(Equal(3))
+			Expect(collection.documents).To(HaveLen(3))
+		})
+
+		It("Wraps scalar documents without tags", func() {
+			_, err := indexer.IndexWithResult(context.Background(), testcase.documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(collection.documents[hashDocument([]byte(`"example document"`))]).To(HaveKeyWithValue("document", "example document"))
+		})
+
+		It("Doesn't write any document in dry-run mode", func() {
+			testcase.opts.DryRun = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 3))
+			Expect(collection.documents).To(BeEmpty())
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(collection.documents).To(HaveLen(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Returns err when the write fails", func() {
+			collection.err = errors.New("server selection timeout")
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeEquivalentTo(errors.New("Unexpected MongoDB error: server selection timeout")))
+		})
+
+		It("err returned docs not processed", func() {
+			testcase.documents = append(testcase.documents, make(chan string))
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err.Error()).To(ContainSubstring("Cannot encode document"))
+		})
+
+		It("Close is a no-op without client", func() {
+			Expect(indexer.Close()).To(BeNil())
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Returns ErrUnreachable when no server answers", func() {
+			var indexer MongoDB
+			Expect(indexer.New(IndexerConfig{Servers: []string{"mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100"}, Index: "go-commons-test"})).To(BeNil())
+			defer indexer.Close()
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
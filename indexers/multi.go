@@ -0,0 +1,157 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const multiIndexer = "multi"
+
+// Multi indexer teeing the documents to its child indexers
+type Multi struct {
+	indexers        []Indexer
+	types           []IndexerType
+	continueOnError bool
+}
+
+// Init function
+func init() {
+	Register(multiIndexer, func() Indexer { return &Multi{} })
+}
+
+// Returns new multi indexer, creating its child indexers from the Indexers configurations
+func (m *Multi) New(indexerConfig IndexerConfig) error {
+	if len(indexerConfig.Indexers) == 0 {
+		return fmt.Errorf("child indexers not specified")
+	}
+	m.indexers, m.types = nil, nil
+	for _, childConfig := range indexerConfig.Indexers {
+		child, err := NewIndexer(childConfig)
+		if err != nil {
+			m.Close()
+			return fmt.Errorf("error creating %s indexer: %s", childConfig.Type, err)
+		}
+		m.indexers = append(m.indexers, child)
+		m.types = append(m.types, childConfig.Type)
+	}
+	m.continueOnError = indexerConfig.ContinueOnError
+	return nil
+}
+
+// Index sends the documents to every child indexer
+func (m *Multi) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := m.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult sends the documents to the child indexers in turn and returns the sum of their indexing results.
+// The first child indexer failing stops the indexing, unless continueOnError is set, in which case the errors of
+// all the failed child indexers are returned together. The results accumulated so far, including the one of the
+// failed child indexers, are returned along with the error
+func (m *Multi) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: make(map[string]int)}, ErrNoDocuments
+	}
+	start := time.Now()
+	result := IndexingResult{Stats: make(map[string]int)}
+	var errs multiError
+	for i, indexer := range m.indexers {
+		childResult, err := indexer.IndexWithResult(ctx, documents, opts)
+		addResult(&result, childResult)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error indexing in %s indexer: %w", m.types[i], err))
+			if !m.continueOnError {
+				break
+			}
+		}
+	}
+	result.Duration = time.Since(start)
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
+}
+
+// multiError errors of the failed child indexers, matching errors.Is and errors.As against any of them
+type multiError []error
+
+func (e multiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the wrapped errors
+func (e multiError) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the wrapped errors matches target, for the releases of errors.Is not unwrapping
+// multiple errors
+func (e multiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// addResult adds the counters of the given indexing result to the total
+func addResult(total *IndexingResult, result IndexingResult) {
+	total.Created += result.Created
+	total.Updated += result.Updated
+	total.Skipped += result.Skipped
+	total.Failed += result.Failed
+	for stat, val := range result.Stats {
+		total.Stats[stat] += val
+	}
+	total.BulkStats.add(result.BulkStats)
+	total.FailureReasons = append(total.FailureReasons, result.FailureReasons...)
+}
+
+// Ping pings every child indexer, returning the error of the first one failing
+func (m *Multi) Ping(ctx context.Context) error {
+	for i, indexer := range m.indexers {
+		if err := indexer.Ping(ctx); err != nil {
+			return fmt.Errorf("error pinging %s indexer: %w", m.types[i], err)
+		}
+	}
+	return nil
+}
+
+// Close closes every child indexer, returning the first error
+func (m *Multi) Close() error {
+	var firstErr error
+	for _, indexer := range m.indexers {
+		if err := indexer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
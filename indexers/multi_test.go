@@ -0,0 +1,165 @@
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// mockChildIndexer records the documents sent to a child indexer of the multi indexer
+type mockChildIndexer struct {
+	documents []interface{}
+	err       error
+	pingErr   error
+	closed    bool
+}
+
+func (m *mockChildIndexer) New(indexerConfig IndexerConfig) error {
+	return nil
+}
+
+func (m *mockChildIndexer) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	result, err := m.IndexWithResult(ctx, documents, opts)
+	return result.String(), err
+}
+
+func (m *mockChildIndexer) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	if m.err != nil {
+		return newIndexingResult(map[string]int{"failed": len(documents)}, 0, 0), m.err
+	}
+	m.documents = append(m.documents, documents...)
+	return newIndexingResult(map[string]int{"created": len(documents)}, 0, 0), nil
+}
+
+func (m *mockChildIndexer) Ping(ctx context.Context) error {
+	return m.pingErr
+}
+
+func (m *mockChildIndexer) Close() error {
+	m.closed = true
+	return nil
+}
+
+var _ = Describe("Tests for multi.go", func() {
+	Context("Tests for New()", func() {
+		var indexer Multi
+
+		It("Creates the child indexers", func() {
+			var first, second bytes.Buffer
+			err := indexer.New(IndexerConfig{Indexers: []IndexerConfig{
+				{Type: StdoutIndexer, Writer: &first},
+				{Type: StdoutIndexer, Writer: &second},
+			}})
+			Expect(err).To(BeNil())
+			_, err = indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(first.String()).To(ContainSubstring(`"value": 1`))
+			Expect(second.String()).To(Equal(first.String()))
+			Expect(indexer.Close()).To(Succeed())
+		})
+
+		It("Returns err no child indexers", func() {
+			err := indexer.New(IndexerConfig{})
+			Expect(err).To(BeEquivalentTo(errors.New("child indexers not specified")))
+		})
+
+		It("Returns err when a child indexer can't be created", func() {
+			err := indexer.New(IndexerConfig{Indexers: []IndexerConfig{{Type: StdoutIndexer}, {Type: LocalIndexer}}})
+			Expect(err).To(BeEquivalentTo(errors.New("error creating local indexer: directory name not specified")))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer Multi
+		var first, second *mockChildIndexer
+		BeforeEach(func() {
+			first, second = &mockChildIndexer{}, &mockChildIndexer{}
+			indexer = Multi{
+				indexers: []Indexer{first, second},
+				types:    []IndexerType{OpenSearchIndexer, LocalIndexer},
+			}
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Sends the documents to every child indexer", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(first.documents).To(Equal(testcase.documents))
+			Expect(second.documents).To(Equal(testcase.documents))
+			Expect(result.Created).To(Equal(6))
+			Expect(result.Stats).To(HaveKeyWithValue("created", 6))
+		})
+
+		It("Stops at the first child indexer failing", func() {
+			first.err = errors.New("cluster unavailable")
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError("error indexing in opensearch indexer: cluster unavailable"))
+			Expect(second.documents).To(BeEmpty())
+		})
+
+		It("Returns the result of the first child indexer failing", func() {
+			second.err = fmt.Errorf("%w: 3 of 3 documents failed", ErrDocumentsFailed)
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(ErrDocumentsFailed))
+			Expect(result.Created).To(Equal(3))
+			Expect(result.Failed).To(Equal(3))
+		})
+
+		It("Keeps sending the documents to the other child indexers when configured", func() {
+			indexer.continueOnError = true
+			first.err = errors.New("cluster unavailable")
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError("error indexing in opensearch indexer: cluster unavailable"))
+			Expect(second.documents).To(Equal(testcase.documents))
+			Expect(result.Created).To(Equal(3))
+			Expect(result.Failed).To(Equal(3))
+		})
+
+		It("Returns the errors of all the failed child indexers", func() {
+			indexer.continueOnError = true
+			first.err = errors.New("cluster unavailable")
+			second.err = fmt.Errorf("%w: 3 of 3 documents failed", ErrDocumentsFailed)
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(ErrDocumentsFailed))
+			Expect(err).To(MatchError("error indexing in opensearch indexer: cluster unavailable; " +
+				"error indexing in local indexer: documents failed to be indexed: 3 of 3 documents failed"))
+			Expect(result.Failed).To(Equal(6))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Pings every child indexer", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			second.pingErr = fmt.Errorf("local %w: no such directory", ErrUnreachable)
+			err := indexer.Ping(context.Background())
+			Expect(err).To(MatchError(ErrUnreachable))
+			Expect(err).To(MatchError("error pinging local indexer: local backend unreachable: no such directory"))
+		})
+
+		It("Closes every child indexer", func() {
+			Expect(indexer.Close()).To(Succeed())
+			Expect(first.closed).To(BeTrue())
+			Expect(second.closed).To(BeTrue())
+		})
+	})
+})
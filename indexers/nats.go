@@ -0,0 +1,214 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const natsIndexer = "nats"
+
+// natsDuplicateStat stat counting the messages acknowledged as duplicates by JetStream, already stored under their ID
+const natsDuplicateStat = "duplicate"
+
+// natsJetStream publishes messages to the JetStream streams
+type natsJetStream interface {
+	PublishMsg(*nats.Msg, ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// NATS JetStream instance
+type NATS struct {
+	subject    string
+	conn       *nats.Conn
+	js         natsJetStream
+	maxRetries int
+	backoff    func(int) time.Duration
+	logger     Logger
+}
+
+// Init function
+func init() {
+	Register(natsIndexer, func() Indexer { return &NATS{} })
+}
+
+// Returns new indexer for NATS JetStream, publishing to the Index subject, which must be bound to a stream, through
+// the Servers[0] server
+func (n *NATS) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if strings.ContainsAny(indexerConfig.Index, " \t\r\n*>") {
+		return fmt.Errorf("invalid NATS subject: %s", indexerConfig.Index)
+	}
+	options := []nats.Option{nats.Name("go-commons")}
+	if indexerConfig.HealthCheckTimeout > 0 {
+		options = append(options, nats.Timeout(indexerConfig.HealthCheckTimeout))
+	}
+	if indexerConfig.Username != "" {
+		options = append(options, nats.UserInfo(indexerConfig.Username, indexerConfig.Password))
+	} else if indexerConfig.APIKey != "" {
+		options = append(options, nats.Token(indexerConfig.APIKey))
+	}
+	if indexerConfig.InsecureSkipVerify || indexerConfig.CACertPath != "" {
+		tlsClientConfig, err := tlsConfig(indexerConfig)
+		if err != nil {
+			return err
+		}
+		options = append(options, nats.Secure(tlsClientConfig))
+	}
+	if indexerConfig.SkipHealthCheck {
+		// The connection is established in the background when the server can't be reached yet
+		options = append(options, nats.RetryOnFailedConnect(true))
+	}
+	conn, err := nats.Connect(indexerConfig.Servers[0], options...)
+	if err != nil {
+		return fmt.Errorf("error connecting to NATS server %s: %s", indexerConfig.Servers[0], err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error creating the JetStream context: %s", err)
+	}
+	n.subject = indexerConfig.Index
+	n.conn = conn
+	n.js = js
+	n.maxRetries, n.backoff = retryPolicy(indexerConfig)
+	n.logger = loggerOrNop(indexerConfig.Logger)
+	return nil
+}
+
+// Index publishes the documents to the configured subject
+func (n *NATS) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := n.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult publishes every document as a JSON message to the configured subject and returns the indexing
+// result. Messages are delivered at least once: every publish waits for the JetStream ack and is retried with
+// backoff when it fails, the document ID being set as message ID so JetStream drops the duplicates. The messages
+// failing after the retries are counted as failed
+func (n *NATS) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, n.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		docId := documentID(j, opts.DocumentIDField, docHashKey)
+		reportDocumentID(opts, document, docId)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		ack, err := n.publish(ctx, docId, j)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return IndexingResult{}, fmt.Errorf("Unexpected NATS error: %s", err)
+			}
+			n.logger.Errorf("Error publishing document %s: %s", docId, err)
+			indexerStats["failed"]++
+		case ack.Duplicate:
+			indexerStats[natsDuplicateStat]++
+		default:
+			indexerStats["created"]++
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	result := newIndexingResult(indexerStats, redundantSkipped, time.Since(start))
+	if opts.FailFast {
+		return result, failedDocumentsError(result)
+	}
+	return result, nil
+}
+
+// publish publishes the message, waiting for its ack, retrying with backoff up to maxRetries times
+func (n *NATS) publish(ctx context.Context, id string, data []byte) (*nats.PubAck, error) {
+	for attempt := 0; ; attempt++ {
+		msg := &nats.Msg{Subject: n.subject, Data: data, Header: nats.Header{}}
+		msg.Header.Set(nats.MsgIdHdr, id)
+		ack, err := n.js.PublishMsg(msg, nats.Context(ctx))
+		if err == nil {
+			return ack, nil
+		}
+		if attempt == n.maxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+		n.logger.Debugf("NATS publish failed, retrying: %s", err)
+		select {
+		case <-time.After(n.backoff(attempt + 1)):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// Ping checks the NATS server answers a round trip, telling the rejected credentials apart
+func (n *NATS) Ping(ctx context.Context) error {
+	// FlushWithContext refuses the contexts without deadline, which Flush bounds with its default timeout
+	var err error
+	if _, ok := ctx.Deadline(); ok {
+		err = n.conn.FlushWithContext(ctx)
+	} else {
+		err = n.conn.Flush()
+	}
+	if err == nil {
+		return nil
+	}
+	if errors.Is(n.conn.LastError(), nats.ErrAuthorization) {
+		return pingFailed("NATS", ErrUnauthorized, n.conn.LastError())
+	}
+	return pingFailed("NATS", ErrUnreachable, err)
+}
+
+// Close closes the NATS connection, the published messages being acknowledged already
+func (n *NATS) Close() error {
+	if n.conn != nil {
+		n.conn.Close()
+	}
+	return nil
+}
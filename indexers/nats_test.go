@@ -0,0 +1,197 @@
+package indexers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// mockNATSJetStream records the published messages, acking them as JetStream does
+type mockNATSJetStream struct {
+	messages []*nats.Msg
+	ids      map[string]bool
+	// failures number of publishes failing before the next one succeeds
+	failures int
+	err      error
+}
+
+func (m *mockNATSJetStream) PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	if m.failures > 0 || m.err != nil {
+		if m.failures > 0 {
+			m.failures--
+		}
+		return nil, errors.New("nats: timeout")
+	}
+	m.messages = append(m.messages, msg)
+	id := msg.Header.Get(nats.MsgIdHdr)
+	duplicate := m.ids[id]
+	m.ids[id] = true
+	return &nats.PubAck{Stream: "GO_COMMONS", Sequence: uint64(len(m.messages)), Duplicate: duplicate}, nil
+}
+
+// newMockNATSServer returns a listener speaking enough of the NATS protocol to accept client connections and
+// answer their pings
+func newMockNATSServer() net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = conn.Write([]byte("INFO {\"server_id\":\"go-commons-test\",\"max_payload\":1048576}\r\n"))
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.HasPrefix(line, "PING") {
+						_, _ = conn.Write([]byte("PONG\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+var _ = Describe("Tests for nats.go", func() {
+	Context("Tests for New()", func() {
+		var indexerConfig IndexerConfig
+		var indexer NATS
+		BeforeEach(func() {
+			indexerConfig = IndexerConfig{Type: "nats",
+				Servers: []string{"nats://127.0.0.1:1"},
+				Index:   "go-commons.test",
+			}
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
+		It("Returns err invalid subject", func() {
+			indexerConfig.Index = "go-commons.*"
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("invalid NATS subject: go-commons.*")))
+		})
+
+		It("Returns err when the server can't be reached", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError(ContainSubstring("error connecting to NATS server nats://127.0.0.1:1")))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer NATS
+		var js *mockNATSJetStream
+		BeforeEach(func() {
+			js = &mockNATSJetStream{ids: make(map[string]bool)}
+			indexer = NATS{subject: "go-commons.test", js: js, maxRetries: 2, backoff: func(int) time.Duration { return 0 }, logger: nopLogger{}}
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Publishes every document to the subject with its ID as message ID", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(js.messages).To(HaveLen(3))
+			Expect(js.messages[1].Subject).To(Equal("go-commons.test"))
+			Expect(js.messages[1].Data).To(MatchJSON(`{"document":42,"metricName":"placeholder"}`))
+			Expect(js.messages[1].Header.Get(nats.MsgIdHdr)).To(Equal(hashDocument([]byte("42"))))
+		})
+
+		It("Counts the messages acked as duplicates", func() {
+			_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(0))
+			Expect(result.Stats).To(HaveKeyWithValue("duplicate", 3))
+		})
+
+		It("Retries the failed publishes", func() {
+			js.failures = 2
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+		})
+
+		It("Counts the publishes failing after the retries", func() {
+			js.err = errors.New("nats: timeout")
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(3))
+			testcase.opts.FailFast = true
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(ErrDocumentsFailed))
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(js.messages).To(HaveLen(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer NATS
+		var server net.Listener
+		BeforeEach(func() {
+			server = newMockNATSServer()
+			DeferCleanup(server.Close)
+			conn, err := nats.Connect("nats://" + server.Addr().String())
+			Expect(err).To(BeNil())
+			DeferCleanup(conn.Close)
+			indexer = NATS{subject: "go-commons.test", conn: conn}
+		})
+
+		It("Completes a round trip with the server", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Returns ErrUnreachable when the connection is closed", func() {
+			indexer.conn.Close()
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
@@ -17,131 +17,902 @@ package indexers
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
-	"crypto/tls"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"runtime"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	opensearch "github.com/opensearch-project/opensearch-go"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
 	opensearchutil "github.com/opensearch-project/opensearch-go/opensearchutil"
+	requestsigner "github.com/opensearch-project/opensearch-go/signer/aws"
 )
 
 const indexer = "opensearch"
 
-// OSClient OpenSearch client instance
-var OSClient *opensearch.Client
-
 // OpenSearch OpenSearch instance
 type OpenSearch struct {
-	index string
+	index             string
+	bulkTimeout       time.Duration
+	flushBytes        int
+	flushDocs         int
+	maxDocBytes       int
+	numWorkers        int
+	skipIndexCreation bool
+	autoSanitize      bool
+	indexMappings     json.RawMessage
+	useDataStream     bool
+	indexAlias        string
+	atomicStats       bool
+	logger            Logger
+	client            *opensearch.Client
+	transport         http.RoundTripper
+	breaker           *circuitBreaker
+	metrics           *indexingMetrics
+	flushes           flushSemaphore
+	version           ServerVersion
+	background        *backgroundBatch
+	bulkIndexer       opensearchutil.BulkIndexer
+	bulkDocs          int
+	bulkStats         BulkStats
+	reused            *reusedOpenSearchBulkIndexers
+	responseStats     *compressionStats
 }
 
 // Init function
 func init() {
-	indexerMap[indexer] = &OpenSearch{}
+	Register(indexer, func() Indexer { return &OpenSearch{} })
 }
 
 // Returns new indexer for OpenSearch
-func (OpenSearchIndexer *OpenSearch) new(indexerConfig IndexerConfig) error {
+func (OpenSearchIndexer *OpenSearch) New(indexerConfig IndexerConfig) error {
 	var err error
 	if indexerConfig.Index == "" {
 		return fmt.Errorf("index name not specified")
 	}
+	if len(indexerConfig.Servers) == 0 && !indexerConfig.AllowEnvFallback {
+		return fmt.Errorf("servers not specified")
+	}
+	OpenSearchIndexer.logger = loggerOrNop(indexerConfig.Logger)
+	if indexerConfig.NumWorkers < 0 {
+		return fmt.Errorf("invalid number of workers: %d", indexerConfig.NumWorkers)
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	if indexerConfig.FlushTimeout < 0 {
+		return fmt.Errorf("invalid flush timeout: %s", indexerConfig.FlushTimeout)
+	}
+	if indexerConfig.MaxDocBytes < 0 {
+		return fmt.Errorf("invalid maximum document size: %d", indexerConfig.MaxDocBytes)
+	}
+	if indexerConfig.MaxConcurrentFlushes < 0 {
+		return fmt.Errorf("invalid number of concurrent flushes: %d", indexerConfig.MaxConcurrentFlushes)
+	}
+	if OpenSearchIndexer.breaker, err = newCircuitBreaker(indexerConfig); err != nil {
+		return err
+	}
+	if OpenSearchIndexer.background, err = newBackgroundBatch(indexerConfig); err != nil {
+		return err
+	}
+	OpenSearchIndexer.bulkIndexer, OpenSearchIndexer.bulkDocs, OpenSearchIndexer.bulkStats = nil, 0, BulkStats{}
+	reuse, err := newBulkReuse(indexerConfig)
+	if err != nil {
+		return err
+	}
+	OpenSearchIndexer.reused = nil
+	if reuse != nil {
+		OpenSearchIndexer.reused = &reusedOpenSearchBulkIndexers{bulkReuse: reuse, indexers: make(map[reusedBulkKey]opensearchutil.BulkIndexer)}
+	}
 	OpenSearchIndex := strings.ToLower(indexerConfig.Index)
+	if indexerConfig.AutoSanitize {
+		OpenSearchIndex = sanitizeIndexName(OpenSearchIndex)
+	}
+	if err := validateIndexName(OpenSearchIndex); err != nil {
+		return err
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	// The client compresses the requests, the transport takes care of the responses
+	OpenSearchIndexer.responseStats = nil
+	if indexerConfig.Compression {
+		OpenSearchIndexer.responseStats = &compressionStats{}
+		transport = gzipResponseTransport{Transport: transport, stats: OpenSearchIndexer.responseStats}
+	}
+	if indexerConfig.FlushTimeout > 0 {
+		transport = flushTimeoutTransport{Transport: transport, timeout: indexerConfig.FlushTimeout}
+	}
+	maxRetries, retryBackoff := retryPolicy(indexerConfig)
 	cfg := opensearch.Config{
-		Addresses: indexerConfig.Servers,
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: indexerConfig.InsecureSkipVerify}},
+		RetryOnStatus:       retryOnStatus,
+		DisableRetry:        indexerConfig.MaxRetries < 0,
+		MaxRetries:          maxRetries,
+		RetryBackoff:        retryBackoff,
+		Addresses:           indexerConfig.Servers,
+		Transport:           transport,
+		CompressRequestBody: indexerConfig.Compression,
+	}
+	if indexerConfig.AWSSigV4 {
+		awsConfig := aws.Config{}
+		if indexerConfig.Region != "" {
+			awsConfig.Region = aws.String(indexerConfig.Region)
+		}
+		cfg.Signer, err = requestsigner.NewSigner(session.Options{Config: awsConfig, SharedConfigState: session.SharedConfigEnable})
+		if err != nil {
+			return fmt.Errorf("error creating the AWS SigV4 signer: %s", err)
+		}
 	}
-	OSClient, err = opensearch.NewClient(cfg)
+	// The OpenSearch client doesn't support API keys, so the authorization header is set instead
+	if indexerConfig.APIKey != "" {
+		cfg.Header = http.Header{"Authorization": []string{"ApiKey " + indexerConfig.APIKey}}
+	} else {
+		cfg.Username = indexerConfig.Username
+		cfg.Password = indexerConfig.Password
+	}
+	OpenSearchIndexer.transport = transport
+	OpenSearchIndexer.client, err = opensearch.NewClient(cfg)
 	if err != nil {
 		return fmt.Errorf("error creating the OpenSearch client: %s", err)
 	}
-	r, err := OSClient.Cluster.Health()
+	if indexerConfig.SkipHealthCheck {
+		OpenSearchIndexer.logger.Debugf("OpenSearch health check skipped")
+		OpenSearchIndexer.version = ServerVersion{}
+	} else if err := OpenSearchIndexer.healthCheck(indexerConfig.HealthCheckTimeout); err != nil {
+		return err
+	} else if err := OpenSearchIndexer.detectVersion(indexerConfig.HealthCheckTimeout); err != nil {
+		return err
+	}
+	OpenSearchIndexer.bulkTimeout = indexerConfig.BulkTimeout
+	if OpenSearchIndexer.bulkTimeout == 0 {
+		OpenSearchIndexer.bulkTimeout = defaultBulkTimeout
+	}
+	OpenSearchIndexer.flushBytes = indexerConfig.FlushBytes
+	if OpenSearchIndexer.flushBytes <= 0 {
+		OpenSearchIndexer.flushBytes = defaultFlushBytes
+	}
+	OpenSearchIndexer.flushDocs = indexerConfig.FlushDocs
+	OpenSearchIndexer.maxDocBytes = indexerConfig.MaxDocBytes
+	OpenSearchIndexer.atomicStats = indexerConfig.AtomicStats
+	OpenSearchIndexer.flushes = newFlushSemaphore(indexerConfig.MaxConcurrentFlushes)
+	OpenSearchIndexer.numWorkers = indexerConfig.NumWorkers
+	if OpenSearchIndexer.numWorkers == 0 {
+		OpenSearchIndexer.numWorkers = runtime.NumCPU()
+	}
+	OpenSearchIndexer.skipIndexCreation = indexerConfig.SkipIndexCreation
+	OpenSearchIndexer.autoSanitize = indexerConfig.AutoSanitize
+	if OpenSearchIndexer.indexMappings, err = withLifecyclePolicy(indexerConfig.IndexMappings, "plugins.index_state_management.policy_id", indexerConfig.ILMPolicy); err != nil {
+		return err
+	}
+	OpenSearchIndexer.useDataStream = indexerConfig.UseDataStream
+	if indexerConfig.IndexAlias != "" && indexerConfig.UseDataStream {
+		return fmt.Errorf("index alias isn't supported with data streams")
+	}
+	OpenSearchIndexer.indexAlias = indexerConfig.IndexAlias
+	if OpenSearchIndexer.metrics, err = newIndexingMetrics(indexerConfig, OpenSearchIndex); err != nil {
+		return err
+	}
+	OpenSearchIndexer.index = OpenSearchIndex
+	return OpenSearchIndexer.createIndex(context.Background(), OpenSearchIndex)
+}
+
+// healthCheck checks the cluster health, giving up when the cluster doesn't answer within the timeout
+func (OpenSearchIndexer *OpenSearch) healthCheck(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// The product check run by the client before the first request doesn't honour the request context,
+	// so the health check is abandoned instead of cancelled when the timeout expires
+	type healthCheckResult struct {
+		r   *opensearchapi.Response
+		err error
+	}
+	done := make(chan healthCheckResult, 1)
+	go func() {
+		r, err := OpenSearchIndexer.client.Cluster.Health(OpenSearchIndexer.client.Cluster.Health.WithContext(ctx))
+		done <- healthCheckResult{r: r, err: err}
+	}()
+	var r *opensearchapi.Response
+	var err error
+	select {
+	case result := <-done:
+		r, err = result.r, result.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		OpenSearchIndexer.logger.Errorf("OpenSearch health check failed: %s", err)
 		return fmt.Errorf("OpenSearch health check failed: %s", err)
 	}
+	defer r.Body.Close()
 	if r.StatusCode != 200 {
+		OpenSearchIndexer.logger.Errorf("OpenSearch health check failed with status code %d", r.StatusCode)
 		return fmt.Errorf("unexpected OpenSearch status code: %d", r.StatusCode)
 	}
-	OpenSearchIndexer.index = OpenSearchIndex
-	r, _ = OSClient.Indices.Exists([]string{OpenSearchIndex})
+	OpenSearchIndexer.logger.Debugf("OpenSearch health check succeeded: %s", r.String())
+	return nil
+}
+
+// detectVersion detects the cluster version
+func (OpenSearchIndexer *OpenSearch) detectVersion(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	r, err := OpenSearchIndexer.client.Info(OpenSearchIndexer.client.Info.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error detecting the OpenSearch version: %s", err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		return fmt.Errorf("error detecting the OpenSearch version: %s", r.String())
+	}
+	if OpenSearchIndexer.version, err = decodeServerVersion(r.Body); err != nil {
+		return fmt.Errorf("error detecting the OpenSearch version: %s", err)
+	}
+	if OpenSearchIndexer.version.Distribution != opensearchDistribution {
+		OpenSearchIndexer.logger.Infof("OpenSearch indexer connected to %s %s", OpenSearchIndexer.version.Distribution, OpenSearchIndexer.version.Number)
+	}
+	OpenSearchIndexer.logger.Debugf("OpenSearch version %s detected", OpenSearchIndexer.version.Number)
+	return nil
+}
+
+// Ping checks the cluster is reachable by requesting its health
+func (OpenSearchIndexer *OpenSearch) Ping(ctx context.Context) error {
+	r, err := OpenSearchIndexer.client.Cluster.Health(OpenSearchIndexer.client.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return pingFailed("OpenSearch", ErrUnreachable, err)
+	}
+	defer r.Body.Close()
+	return pingStatus("OpenSearch", r.StatusCode)
+}
+
+// ServerVersion returns the version of the cluster detected when creating the indexer,
+// the zero value when the health check is skipped
+func (OpenSearchIndexer *OpenSearch) ServerVersion() ServerVersion {
+	return OpenSearchIndexer.version
+}
+
+// createIndex creates the given index when it doesn't exist, unless index creation is disabled
+func (OpenSearchIndexer *OpenSearch) createIndex(ctx context.Context, index string) error {
+	logger := loggerOrNop(OpenSearchIndexer.logger)
+	r, err := OpenSearchIndexer.client.Indices.Exists([]string{index}, OpenSearchIndexer.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error checking index %s on OpenSearch: %s", index, err)
+	}
+	r.Body.Close()
 	if r.IsError() {
-		r, _ = OSClient.Indices.Create(OpenSearchIndex)
+		if OpenSearchIndexer.skipIndexCreation {
+			return fmt.Errorf("index %s not found on OpenSearch and index creation is disabled", index)
+		}
+		if OpenSearchIndexer.useDataStream {
+			return OpenSearchIndexer.createDataStream(ctx, index)
+		}
+		createOpts := []func(*opensearchapi.IndicesCreateRequest){OpenSearchIndexer.client.Indices.Create.WithContext(ctx)}
+		if len(OpenSearchIndexer.indexMappings) > 0 {
+			createOpts = append(createOpts, OpenSearchIndexer.client.Indices.Create.WithBody(bytes.NewReader(OpenSearchIndexer.indexMappings)))
+		}
+		r, err = OpenSearchIndexer.client.Indices.Create(index, createOpts...)
+		if err != nil {
+			return fmt.Errorf("error creating index %s on OpenSearch: %s", index, err)
+		}
+		defer r.Body.Close()
 		if r.IsError() {
-			return fmt.Errorf("error creating index %s on OpenSearch: %s", OpenSearchIndex, r.String())
+			logger.Errorf("Error creating index %s on OpenSearch: %s", index, r.String())
+			return fmt.Errorf("error creating index %s on OpenSearch: %s", index, r.String())
+		}
+		logger.Infof("Index %s created on OpenSearch", index)
+		if OpenSearchIndexer.indexAlias != "" && index == OpenSearchIndexer.index {
+			return OpenSearchIndexer.pointAlias(ctx, index)
 		}
 	}
 	return nil
 }
 
+// pointAlias points the index alias at the given index, moving it atomically when it's set on other indices
+func (OpenSearchIndexer *OpenSearch) pointAlias(ctx context.Context, index string) error {
+	logger := loggerOrNop(OpenSearchIndexer.logger)
+	alias := OpenSearchIndexer.indexAlias
+	r, err := OpenSearchIndexer.client.Indices.GetAlias(OpenSearchIndexer.client.Indices.GetAlias.WithName(alias), OpenSearchIndexer.client.Indices.GetAlias.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error getting alias %s on OpenSearch: %s", alias, err)
+	}
+	defer r.Body.Close()
+	var aliases io.Reader
+	switch {
+	case r.StatusCode == http.StatusNotFound:
+	case r.IsError():
+		return fmt.Errorf("error getting alias %s on OpenSearch: %s", alias, r.String())
+	default:
+		aliases = r.Body
+	}
+	actions, err := aliasActions(alias, index, aliases)
+	if err != nil {
+		return err
+	}
+	r, err = OpenSearchIndexer.client.Indices.UpdateAliases(bytes.NewReader(actions), OpenSearchIndexer.client.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error pointing alias %s at index %s on OpenSearch: %s", alias, index, err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		logger.Errorf("Error pointing alias %s at index %s on OpenSearch: %s", alias, index, r.String())
+		return fmt.Errorf("error pointing alias %s at index %s on OpenSearch: %s", alias, index, r.String())
+	}
+	logger.Infof("Alias %s pointed at index %s on OpenSearch", alias, index)
+	return nil
+}
+
+// createDataStream creates the given data stream along with its backing index template
+func (OpenSearchIndexer *OpenSearch) createDataStream(ctx context.Context, name string) error {
+	logger := loggerOrNop(OpenSearchIndexer.logger)
+	template, err := dataStreamTemplate(name, OpenSearchIndexer.indexMappings)
+	if err != nil {
+		return err
+	}
+	r, err := OpenSearchIndexer.client.Indices.PutIndexTemplate(name, bytes.NewReader(template), OpenSearchIndexer.client.Indices.PutIndexTemplate.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error creating index template %s on OpenSearch: %s", name, err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		logger.Errorf("Error creating index template %s on OpenSearch: %s", name, r.String())
+		return fmt.Errorf("error creating index template %s on OpenSearch: %s", name, r.String())
+	}
+	// The OpenSearch client doesn't provide the data stream API
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "/_data_stream/"+name, nil)
+	if err != nil {
+		return fmt.Errorf("error creating data stream %s on OpenSearch: %s", name, err)
+	}
+	res, err := OpenSearchIndexer.client.Perform(req)
+	if err != nil {
+		return fmt.Errorf("error creating data stream %s on OpenSearch: %s", name, err)
+	}
+	r = &opensearchapi.Response{StatusCode: res.StatusCode, Header: res.Header, Body: res.Body}
+	defer r.Body.Close()
+	if r.IsError() {
+		logger.Errorf("Error creating data stream %s on OpenSearch: %s", name, r.String())
+		return fmt.Errorf("error creating data stream %s on OpenSearch: %s", name, r.String())
+	}
+	logger.Infof("Data stream %s created on OpenSearch", name)
+	return nil
+}
+
 // Index uses bulkIndexer to index the documents in the given index
-func (OpenSearchIndexer *OpenSearch) Index(documents []interface{}, opts IndexingOpts) (string, error) {
-	var statString string
-	var indexerStatsLock sync.Mutex
-	indexerStats := make(map[string]int)
+func (OpenSearchIndexer *OpenSearch) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := OpenSearchIndexer.IndexWithResult(ctx, documents, opts)
+	return resultMessage(result, err)
+}
 
+// IndexWithResult uses bulkIndexer to index the documents in the given index and returns the indexing result. When
+// the indexing fails, the result of the documents indexed before the failure is returned along with the error
+func (OpenSearchIndexer *OpenSearch) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
 	if len(documents) <= 0 {
-		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), nil
+		return IndexingResult{Stats: make(map[string]int)}, ErrNoDocuments
 	}
-	hasher := sha256.New()
-	bi, err := opensearchutil.NewBulkIndexer(opensearchutil.BulkIndexerConfig{
-		Client:     OSClient,
-		Index:      OpenSearchIndexer.index,
-		FlushBytes: 5e+6,
-		NumWorkers: runtime.NumCPU(),
-		Timeout:    10 * time.Minute, // TODO: hardcoded
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, OpenSearchIndexer.IndexWithResult)
+	}
+	return OpenSearchIndexer.indexDocuments(ctx, encodeDocuments(ctx, documents, opts), opts)
+}
+
+// IndexStream uses bulkIndexer to index the documents received from the given channel until it's closed
+func (OpenSearchIndexer *OpenSearch) IndexStream(ctx context.Context, documents <-chan interface{}, opts IndexingOpts) (string, error) {
+	if err := validateSampleRate(opts.SampleRate); err != nil {
+		return "", err
+	}
+	transformFailed := 0
+	filter := &documentFilter{opts: opts}
+	next := transformingIterator(channelIterator(ctx, documents), opts.Transform, &transformFailed)
+	result, err := OpenSearchIndexer.indexDocuments(ctx, encodingIterator(filteringIterator(next, filter), opts), opts)
+	addTransformFailed(&result, transformFailed)
+	filter.addStats(&result)
+	return resultMessage(result, err)
+}
+
+// IndexNDJSON uses bulkIndexer to index the JSON lines read from r as they are, without decoding them into documents,
+// their IDs being computed from their raw bytes. The Transform of the indexing options isn't supported
+func (OpenSearchIndexer *OpenSearch) IndexNDJSON(ctx context.Context, r io.Reader, opts IndexingOpts) (string, error) {
+	if opts.Transform != nil {
+		return "", fmt.Errorf("transform isn't supported when indexing NDJSON")
+	}
+	result, err := OpenSearchIndexer.indexDocuments(ctx, ndjsonIterator(ctx, r), opts)
+	return resultMessage(result, err)
+}
+
+// indexDocuments indexes the documents returned by next through the circuit breaker and returns the indexing result
+func (OpenSearchIndexer *OpenSearch) indexDocuments(ctx context.Context, next encodedIterator, opts IndexingOpts) (IndexingResult, error) {
+	if opts.DryRun {
+		return dryRun(next, opts)
+	}
+	if err := validateRefresh(opts.Refresh); err != nil {
+		return IndexingResult{}, err
+	}
+	if opts.MaxFailureReasons < 0 {
+		return IndexingResult{}, fmt.Errorf("invalid maximum number of failure reasons: %d", opts.MaxFailureReasons)
+	}
+	var err error
+	if opts.Action, err = bulkAction(opts.Action, OpenSearchIndexer.useDataStream); err != nil {
+		return IndexingResult{}, err
+	}
+	if opts.VersionType, err = versionType(opts); err != nil {
+		return IndexingResult{}, err
+	}
+	if OpenSearchIndexer.background != nil && (opts.Refresh != "" || opts.Pipeline != "") {
+		return IndexingResult{}, fmt.Errorf("refresh and pipeline aren't supported when flushing on interval")
+	}
+	if OpenSearchIndexer.background != nil && opts.FailFast {
+		return IndexingResult{}, fmt.Errorf("fail fast isn't supported when flushing on interval")
+	}
+	if OpenSearchIndexer.background != nil && opts.MaxFailureReasons > 0 {
+		return IndexingResult{}, fmt.Errorf("failure reasons aren't supported when flushing on interval")
+	}
+	result, err := OpenSearchIndexer.breaker.call(func() (IndexingResult, error) {
+		return OpenSearchIndexer.bulkIndex(ctx, next, opts)
 	})
-	if err != nil {
-		return "", fmt.Errorf("Error creating the indexer: %s", err)
+	// The outcome of the queued documents is observed when flushing them
+	if OpenSearchIndexer.background == nil || err != nil {
+		OpenSearchIndexer.metrics.observe(result, err)
+	}
+	if err == nil && opts.FailFast {
+		err = failedDocumentsError(result)
+	}
+	return result, err
+}
+
+// bulkIndex uses bulkIndexer to index the documents returned by next in the given index and returns the indexing result
+func (OpenSearchIndexer *OpenSearch) bulkIndex(ctx context.Context, next encodedIterator, opts IndexingOpts) (IndexingResult, error) {
+	logger := loggerOrNop(OpenSearchIndexer.logger)
+	indexerStats := newItemStats(OpenSearchIndexer.atomicStats)
+	failures := newFailureReasons(opts.MaxFailureReasons)
+
+	now := time.Now()
+	index := OpenSearchIndexer.index
+	if opts.TimeBasedSuffix != "" {
+		index = timeBasedIndex(index, opts.TimeBasedSuffix, now)
+		if err := OpenSearchIndexer.createIndex(ctx, index); err != nil {
+			return IndexingResult{}, err
+		}
+	}
+	biConfig := OpenSearchIndexer.bulkIndexerConfig()
+	biConfig.Index = index
+	biConfig.Refresh = opts.Refresh
+	biConfig.Pipeline = opts.Pipeline
+	latencies := &flushLatencies{}
+	var session *reusedOpenSearchSession
+	if OpenSearchIndexer.reused != nil {
+		// The reused bulk indexers record the flush durations themselves
+		latencies = &OpenSearchIndexer.reused.latencies
+		session = OpenSearchIndexer.reused.session(biConfig)
+	} else {
+		biConfig.OnFlushStart, biConfig.OnFlushEnd = latencies.hooks(biConfig.OnFlushStart, biConfig.OnFlushEnd)
 	}
 	start := time.Now().UTC()
+	fields := documentFields(opts, start)
 	docHash := make(map[string]bool)
 	redundantSkipped := 0
-	for _, document := range documents {
-		j, err := json.Marshal(document)
+	// Indices known to exist, target indices being created on demand
+	ensuredIndices := map[string]bool{index: true}
+	var bulkStats BulkStats
+	// A new bulk indexer is used every flushDocs documents, forcing a flush
+	var bi opensearchutil.BulkIndexer
+	batchDocs := 0
+	if OpenSearchIndexer.background != nil {
+		// The documents are queued in the bulk indexer held open between calls
+		indexerStats = OpenSearchIndexer.background.stats
+	}
+	queued, oversized := 0, 0
+	// partial closes the pending bulk indexer and returns the result of the documents indexed so far along with err
+	partial := func(err error) (IndexingResult, error) {
+		if bi != nil {
+			_ = bi.Close(ctx)
+		}
+		if session != nil {
+			_ = session.close(ctx)
+			bulkStats = session.stats
+		}
+		if OpenSearchIndexer.background != nil {
+			return IndexingResult{}, err
+		}
+		result := newIndexingResult(indexerStats.snapshot(), redundantSkipped, time.Since(start))
+		addOversized(&result, oversized)
+		result.FailureReasons = failures.list()
+		result.BulkStats = bulkStats
+		return result, err
+	}
+	for {
+		encoded, ok, err := next()
 		if err != nil {
-			return "", fmt.Errorf("Cannot encode document %s: %s", document, err)
+			return partial(err)
+		}
+		if !ok {
+			break
 		}
-		hasher.Write(j)
-		docId := hex.EncodeToString(hasher.Sum(nil))
-		if _, exists := docHash[docId]; exists {
+		j, docHashKey := encoded.j, encoded.hash
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
 			redundantSkipped += 1
 			continue
 		}
-		err = bi.Add(
-			context.Background(),
-			opensearchutil.BulkIndexerItem{
-				Action:     "index",
-				Body:       bytes.NewReader(j),
-				DocumentID: docId,
-				OnSuccess: func(c context.Context, bii opensearchutil.BulkIndexerItem, biri opensearchutil.BulkIndexerResponseItem) {
-					indexerStatsLock.Lock()
-					defer indexerStatsLock.Unlock()
-					indexerStats[biri.Result]++
-				},
+		docId := documentID(j, opts.DocumentIDField, docHashKey)
+		var routing *string
+		if value, exists := documentField(j, opts.RoutingField); exists {
+			routing = &value
+		}
+		version, err := documentVersion(j, opts.VersionField)
+		if err != nil {
+			return partial(fmt.Errorf("Cannot version document %s: %s", docId, err))
+		}
+		var itemVersionType *string
+		if version != nil {
+			itemVersionType = &opts.VersionType
+		}
+		itemIndex, exists, err := documentIndex(j, opts, OpenSearchIndexer.autoSanitize, now)
+		if err != nil {
+			return partial(err)
+		}
+		if exists && !ensuredIndices[itemIndex] {
+			if err := OpenSearchIndexer.createIndex(ctx, itemIndex); err != nil {
+				return partial(err)
+			}
+			ensuredIndices[itemIndex] = true
+		}
+		if j, err = decorateDocument(j, fields); err != nil {
+			return partial(err)
+		}
+		if OpenSearchIndexer.useDataStream {
+			if j, err = withTimestamp(j, dataStreamTimestampField, time.Now()); err != nil {
+				return partial(err)
+			}
+		}
+		if opts.Action == "update" {
+			j = updateBody(j)
+		}
+		if OpenSearchIndexer.maxDocBytes > 0 && len(j) > OpenSearchIndexer.maxDocBytes {
+			logger.Errorf("Document %s skipped, its size of %d bytes exceeds %d bytes", docId, len(j), OpenSearchIndexer.maxDocBytes)
+			oversized++
+			continue
+		}
+		reportDocumentID(opts, encoded.document, docId)
+		item := opensearchutil.BulkIndexerItem{
+			Index:       itemIndex,
+			Action:      opts.Action,
+			Body:        bytes.NewReader(j),
+			DocumentID:  docId,
+			Routing:     routing,
+			Version:     version,
+			VersionType: itemVersionType,
+			OnSuccess: func(c context.Context, bii opensearchutil.BulkIndexerItem, biri opensearchutil.BulkIndexerResponseItem) {
+				indexerStats.add(biri.Result)
+			},
+			OnFailure: func(c context.Context, bii opensearchutil.BulkIndexerItem, biri opensearchutil.BulkIndexerResponseItem, err error) {
+				indexerStats.add("failed")
+				if biri.Error.Type != "" {
+					indexerStats.add(biri.Error.Type)
+				}
+				if err != nil {
+					failures.add(bii.DocumentID, 0, "", err.Error())
+					logger.Errorf("Error indexing document %s: %s", bii.DocumentID, err)
+				} else {
+					failures.add(bii.DocumentID, biri.Status, biri.Error.Type, biri.Error.Reason)
+					logger.Errorf("Error indexing document %s: %s: %s", bii.DocumentID, biri.Error.Type, biri.Error.Reason)
+				}
 			},
-		)
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		if OpenSearchIndexer.background != nil {
+			if err := OpenSearchIndexer.addBackground(ctx, item); err != nil {
+				return partial(err)
+			}
+			queued++
+			continue
+		}
+		if session != nil {
+			if err := session.add(ctx, item); err != nil {
+				return partial(err)
+			}
+			continue
+		}
+		if bi == nil {
+			if bi, err = opensearchutil.NewBulkIndexer(biConfig); err != nil {
+				return partial(fmt.Errorf("Error creating the indexer: %s", err))
+			}
+		}
+		if err := bi.Add(ctx, item); err != nil {
+			return partial(fmt.Errorf("Unexpected OpenSearch indexing error: %s", err))
+		}
+		if batchDocs++; batchDocs == OpenSearchIndexer.flushDocs {
+			if err := bi.Close(ctx); err != nil {
+				bi = nil
+				return partial(fmt.Errorf("Unexpected OpenSearch error: %s", err))
+			}
+			bulkStats.add(BulkStats(bi.Stats()))
+			bi, batchDocs = nil, 0
+		}
+	}
+	if OpenSearchIndexer.background != nil {
+		result := newIndexingResult(map[string]int{queuedStat: queued}, redundantSkipped, time.Since(start))
+		addOversized(&result, oversized)
+		return result, nil
+	}
+	if bi != nil {
+		if err := bi.Close(ctx); err != nil {
+			bi = nil
+			return partial(fmt.Errorf("Unexpected OpenSearch error: %s", err))
+		}
+		bulkStats.add(BulkStats(bi.Stats()))
+	}
+	if session != nil {
+		if err := session.close(ctx); err != nil {
+			return partial(err)
+		}
+		bulkStats = session.stats
+	}
+	result := newIndexingResult(indexerStats.snapshot(), redundantSkipped, time.Since(start))
+	addOversized(&result, oversized)
+	result.FailureReasons = failures.list()
+	result.FlushLatency = latencies.percentiles()
+	result.BulkStats = bulkStats
+	return result, nil
+}
+
+// reusedOpenSearchBulkIndexers bulk indexers reused across the indexing calls, one per index, refresh and pipeline,
+// closed on Close
+type reusedOpenSearchBulkIndexers struct {
+	*bulkReuse
+	indexers map[reusedBulkKey]opensearchutil.BulkIndexer
+}
+
+// reusedOpenSearchSession reused bulk indexer used by an indexing call, holding the lock of the reused bulk indexers
+// until closed
+type reusedOpenSearchSession struct {
+	reused *reusedOpenSearchBulkIndexers
+	config opensearchutil.BulkIndexerConfig
+	bi     opensearchutil.BulkIndexer
+	// start statistics of the bulk indexer
+	start  BulkStats
+	added  uint64
+	stats  BulkStats
+	closed bool
+}
+
+// session locks the reused bulk indexers for an indexing call adding its documents with the given configuration
+func (r *reusedOpenSearchBulkIndexers) session(config opensearchutil.BulkIndexerConfig) *reusedOpenSearchSession {
+	r.Lock()
+	// Discard the flush durations left by a failed call
+	r.latencies.percentiles()
+	return &reusedOpenSearchSession{reused: r, config: config}
+}
+
+// add adds the item to the reused bulk indexer, creating it when needed
+func (s *reusedOpenSearchSession) add(ctx context.Context, item opensearchutil.BulkIndexerItem) error {
+	if s.bi == nil {
+		key := reusedBulkKey{index: s.config.Index, refresh: s.config.Refresh, pipeline: s.config.Pipeline}
+		bi, exists := s.reused.indexers[key]
+		if !exists {
+			config := s.config
+			config.FlushInterval = reusedFlushInterval
+			config.OnFlushStart, config.OnFlushEnd = s.reused.hooks(config.OnFlushStart, config.OnFlushEnd)
+			var err error
+			if bi, err = opensearchutil.NewBulkIndexer(config); err != nil {
+				return fmt.Errorf("Error creating the indexer: %s", err)
+			}
+			s.reused.indexers[key] = bi
+		}
+		s.bi, s.start = bi, BulkStats(bi.Stats())
+	}
+	if err := s.bi.Add(ctx, item); err != nil {
+		return fmt.Errorf("Unexpected OpenSearch indexing error: %s", err)
+	}
+	s.added++
+	return nil
+}
+
+// close waits for the documents added to be flushed, accumulates the statistics of the call and releases the lock.
+// The bulk indexers are discarded when the wait fails, being closed in the background
+func (s *reusedOpenSearchSession) close(ctx context.Context) error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	defer s.reused.Unlock()
+	if s.bi == nil {
+		return nil
+	}
+	err := s.reused.wait(ctx, func() bool {
+		stats := bulkStatsDelta(BulkStats(s.bi.Stats()), s.start)
+		return stats.NumFlushed+stats.NumFailed >= s.added
+	})
+	s.stats = bulkStatsDelta(BulkStats(s.bi.Stats()), s.start)
+	if err != nil {
+		for key, bi := range s.reused.indexers {
+			go bi.Close(context.Background())
+			delete(s.reused.indexers, key)
+		}
+		return fmt.Errorf("Unexpected OpenSearch error: %s", err)
+	}
+	return nil
+}
+
+// close closes the reused bulk indexers, flushing their documents
+func (r *reusedOpenSearchBulkIndexers) close(ctx context.Context) error {
+	r.Lock()
+	defer r.Unlock()
+	var err error
+	for key, bi := range r.indexers {
+		if closeErr := bi.Close(ctx); closeErr != nil && err == nil {
+			err = fmt.Errorf("Unexpected OpenSearch error: %s", closeErr)
+		}
+		delete(r.indexers, key)
+	}
+	return err
+}
+
+// bulkIndexerConfig returns the configuration used to create the bulk indexer
+func (OpenSearchIndexer *OpenSearch) bulkIndexerConfig() opensearchutil.BulkIndexerConfig {
+	logger := loggerOrNop(OpenSearchIndexer.logger)
+	return opensearchutil.BulkIndexerConfig{
+		Client:     OpenSearchIndexer.client,
+		Index:      OpenSearchIndexer.index,
+		FlushBytes: OpenSearchIndexer.flushBytes,
+		NumWorkers: OpenSearchIndexer.numWorkers,
+		Timeout:    OpenSearchIndexer.bulkTimeout,
+		OnError: func(ctx context.Context, err error) {
+			logger.Errorf("Bulk indexer error: %s", err)
+		},
+		OnFlushStart: func(ctx context.Context) context.Context {
+			logger.Debugf("Bulk indexer flush started")
+			return OpenSearchIndexer.flushes.acquire(ctx)
+		},
+		OnFlushEnd: func(ctx context.Context) {
+			OpenSearchIndexer.flushes.release(ctx)
+			logger.Debugf("Bulk indexer flush finished")
+		},
+	}
+}
+
+// addBackground adds the item to the bulk indexer held open between the indexing calls, starting the interval flusher.
+// The bulk indexer is closed, forcing a flush, every flushDocs documents
+func (OpenSearchIndexer *OpenSearch) addBackground(ctx context.Context, item opensearchutil.BulkIndexerItem) error {
+	OpenSearchIndexer.background.Lock()
+	defer OpenSearchIndexer.background.Unlock()
+	if OpenSearchIndexer.bulkIndexer == nil {
+		biConfig := OpenSearchIndexer.bulkIndexerConfig()
+		biConfig.OnFlushStart, biConfig.OnFlushEnd = OpenSearchIndexer.background.latencies.hooks(biConfig.OnFlushStart, biConfig.OnFlushEnd)
+		bi, err := opensearchutil.NewBulkIndexer(biConfig)
 		if err != nil {
-			return "", fmt.Errorf("Unexpected OpenSearch indexing error: %s", err)
+			return fmt.Errorf("Error creating the indexer: %s", err)
 		}
-		docHash[docId] = true
-		hasher.Reset()
+		OpenSearchIndexer.bulkIndexer = bi
+	}
+	OpenSearchIndexer.background.startFlusher(func() { _ = OpenSearchIndexer.flushBackground() })
+	if err := OpenSearchIndexer.bulkIndexer.Add(ctx, item); err != nil {
+		return fmt.Errorf("Unexpected OpenSearch indexing error: %s", err)
+	}
+	if OpenSearchIndexer.bulkDocs++; OpenSearchIndexer.bulkDocs == OpenSearchIndexer.flushDocs {
+		return OpenSearchIndexer.closeBulkIndexer(ctx)
+	}
+	return nil
+}
+
+// closeBulkIndexer closes the bulk indexer held open between the indexing calls and accumulates its statistics.
+// Must be called holding the background lock
+func (OpenSearchIndexer *OpenSearch) closeBulkIndexer(ctx context.Context) error {
+	bi := OpenSearchIndexer.bulkIndexer
+	OpenSearchIndexer.bulkIndexer, OpenSearchIndexer.bulkDocs = nil, 0
+	if err := bi.Close(ctx); err != nil {
+		return fmt.Errorf("Unexpected OpenSearch error: %s", err)
+	}
+	OpenSearchIndexer.bulkStats.add(BulkStats(bi.Stats()))
+	return nil
+}
+
+// flushBackground flushes the bulk indexer held open between the indexing calls, reporting the outcome of its documents
+func (OpenSearchIndexer *OpenSearch) flushBackground() error {
+	logger := loggerOrNop(OpenSearchIndexer.logger)
+	OpenSearchIndexer.background.Lock()
+	defer OpenSearchIndexer.background.Unlock()
+	var err error
+	if OpenSearchIndexer.bulkIndexer != nil {
+		err = OpenSearchIndexer.closeBulkIndexer(context.Background())
+	}
+	if OpenSearchIndexer.bulkStats.NumAdded == 0 && err == nil {
+		return nil
+	}
+	result := OpenSearchIndexer.background.result()
+	result.BulkStats = OpenSearchIndexer.bulkStats
+	OpenSearchIndexer.bulkStats = BulkStats{}
+	OpenSearchIndexer.metrics.observe(result, err)
+	if err != nil {
+		logger.Errorf("OpenSearch background flush failed: %s", err)
+		return err
+	}
+	logger.Debugf("OpenSearch background flush: %s", result)
+	return nil
+}
+
+// Get returns the source of the document with the given ID from the configured index, ErrDocumentNotFound when it
+// doesn't exist. The get API being real-time, the document is returned as soon as it's indexed. Data streams aren't
+// supported, as their documents are only read from their backing indices
+func (OpenSearchIndexer *OpenSearch) Get(ctx context.Context, id string) (json.RawMessage, error) {
+	if OpenSearchIndexer.useDataStream {
+		return nil, fmt.Errorf("reading documents by ID isn't supported on data streams")
+	}
+	r, err := OpenSearchIndexer.client.Get(OpenSearchIndexer.index, id, OpenSearchIndexer.client.Get.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error getting document %s from OpenSearch: %s", id, err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode == http.StatusNotFound {
+		return nil, ErrDocumentNotFound
+	}
+	if r.IsError() {
+		return nil, fmt.Errorf("error getting document %s from OpenSearch: %s", id, r.String())
+	}
+	var document struct {
+		Source json.RawMessage `json:"_source"`
 	}
-	if err := bi.Close(context.Background()); err != nil {
-		return "", fmt.Errorf("Unexpected OpenSearch error: %s", err)
+	if err := json.NewDecoder(r.Body).Decode(&document); err != nil {
+		return nil, fmt.Errorf("error decoding document %s from OpenSearch: %s", id, err)
 	}
-	dur := time.Since(start)
-	for stat, val := range indexerStats {
-		statString += fmt.Sprintf(" %s=%d", stat, val)
+	return document.Source, nil
+}
+
+// Count returns the number of documents of the configured index. The documents indexed are only counted once the
+// index is refreshed, as with the wait_for refresh option
+func (OpenSearchIndexer *OpenSearch) Count(ctx context.Context) (int, error) {
+	r, err := OpenSearchIndexer.client.Count(OpenSearchIndexer.client.Count.WithIndex(OpenSearchIndexer.index), OpenSearchIndexer.client.Count.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("error counting documents on OpenSearch: %s", err)
+	}
+	defer r.Body.Close()
+	if r.IsError() {
+		return 0, fmt.Errorf("error counting documents on OpenSearch: %s", r.String())
+	}
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&count); err != nil {
+		return 0, fmt.Errorf("error decoding document count from OpenSearch: %s", err)
 	}
-	if(redundantSkipped > 0){
-		statString += fmt.Sprintf(" redundantskipped=%d", redundantSkipped)
+	return count.Count, nil
+}
+
+// CompressionStats returns the statistics of the gzip encoded responses received, when compression is enabled
+func (OpenSearchIndexer *OpenSearch) CompressionStats() CompressionStats {
+	return OpenSearchIndexer.responseStats.snapshot()
+}
+
+// Close flushes the queued documents, closes the reused bulk indexers and closes the idle connections of the OpenSearch
+// client transport
+func (OpenSearchIndexer *OpenSearch) Close() error {
+	var err error
+	if OpenSearchIndexer.background != nil {
+		OpenSearchIndexer.background.stop()
+		err = OpenSearchIndexer.flushBackground()
+	}
+	if OpenSearchIndexer.reused != nil {
+		if closeErr := OpenSearchIndexer.reused.close(context.Background()); closeErr != nil && err == nil {
+			err = closeErr
+		}
 	}
-	return fmt.Sprintf("Indexing finished in %v:%v", dur.Truncate(time.Millisecond), statString), nil
+	closeIdleConnections(OpenSearchIndexer.transport)
+	return err
 }
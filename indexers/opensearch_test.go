@@ -1,11 +1,22 @@
 package indexers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
-	"log"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -13,7 +24,7 @@ import (
 
 // tests for opensearch.go
 var _ = Describe("Tests for opensearch.go", func() {
-	Context("Tests for new()", func() {
+	Context("Tests for New()", func() {
 		var testcase newMethodTestcase
 		var indexer OpenSearch
 		BeforeEach(func() {
@@ -39,12 +50,19 @@ var _ = Describe("Tests for opensearch.go", func() {
 			}))
 			defer testcase.mockServer.Close()
 			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
-			err := indexer.new(testcase.indexerConfig)
+			err := indexer.New(testcase.indexerConfig)
 			Expect(err).To(BeEquivalentTo(errors.New("OpenSearch health check failed: cannot retrieve information from OpenSearch")))
 		})
 
+		It("Returns err no servers", func() {
+			defer testcase.mockServer.Close()
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
 		It("when no url is passed", func() {
-			err := indexer.new(testcase.indexerConfig)
+			testcase.indexerConfig.AllowEnvFallback = true
+			err := indexer.New(testcase.indexerConfig)
 			testcase.mockServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusGatewayTimeout)
 			}))
@@ -53,10 +71,11 @@ var _ = Describe("Tests for opensearch.go", func() {
 
 		It("Returns err not a valid URL in env variable", func() {
 			testcase.indexerConfig.Servers = []string{}
+			testcase.indexerConfig.AllowEnvFallback = true
 			os.Setenv("ELASTICSEARCH_URL", "not a valid url:port")
 			defer os.Unsetenv("ELASTICSEARCH_URL")
 			defer testcase.mockServer.Close()
-			err := indexer.new(testcase.indexerConfig)
+			err := indexer.New(testcase.indexerConfig)
 			Expect(err).To(BeEquivalentTo(errors.New("error creating the OpenSearch client: cannot create client: cannot parse url: parse \"not a valid url:port\": first path segment in URL cannot contain colon")))
 		})
 
@@ -64,10 +83,448 @@ var _ = Describe("Tests for opensearch.go", func() {
 			defer testcase.mockServer.Close()
 			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
 			testcase.indexerConfig.Index = ""
-			err := indexer.new(testcase.indexerConfig)
+			err := indexer.New(testcase.indexerConfig)
 			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
 		})
 
+		It("Returns err invalid index name", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.Index = "_Go Commons"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(MatchError(`invalid index name "_go commons": must not contain ' '`))
+		})
+
+		It("Sanitizes the index name when enabled", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.Index = "_Go Commons,Test"
+			testcase.indexerConfig.AutoSanitize = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.index).To(Equal("go_commons_test"))
+		})
+
+		It("Authenticates with basic auth", func() {
+			var authorization string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.Username = "user"
+			testcase.indexerConfig.Password = "secret"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(authorization).To(Equal("Basic dXNlcjpzZWNyZXQ="))
+		})
+
+		It("Authenticates with the API key over basic auth", func() {
+			var authorization string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.Username = "user"
+			testcase.indexerConfig.Password = "secret"
+			testcase.indexerConfig.APIKey = "YXBpLWtleQ=="
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(authorization).To(Equal("ApiKey YXBpLWtleQ=="))
+		})
+
+		It("Verifies the server certificate with the supplied CA", func() {
+			tlsServer := httptest.NewTLSServer(testcase.mockServer.Config.Handler)
+			defer tlsServer.Close()
+			defer testcase.mockServer.Close()
+			caCertPath := writeCACert(tlsServer)
+			defer os.Remove(caCertPath)
+			testcase.indexerConfig.Servers = []string{tlsServer.URL}
+			testcase.indexerConfig.InsecureSkipVerify = false
+			testcase.indexerConfig.MaxRetries = -1
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err.Error()).To(ContainSubstring("certificate"))
+			testcase.indexerConfig.CACertPath = caCertPath
+			err = indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+		})
+
+		It("Presents the client certificate to servers requiring mutual TLS", func() {
+			clientCert, clientKey := newClientCertificate()
+			tlsServer := newMTLSServer(testcase.mockServer.Config.Handler, clientCert)
+			defer tlsServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{tlsServer.URL}
+			testcase.indexerConfig.MaxRetries = -1
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(HaveOccurred())
+			testcase.indexerConfig.ClientCert, testcase.indexerConfig.ClientKey = clientCert, clientKey
+			err = indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+		})
+
+		It("Creates the index when it doesn't exist", func() {
+			var created []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case http.MethodPut:
+					created = append(created, r.URL.Path)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(created).To(ConsistOf("/go-commons-test"))
+		})
+
+		It("Sends the index mappings when creating the index", func() {
+			var body []byte
+			mappings := `{"mappings":{"properties":{"value":{"type":"double"}}}}`
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case http.MethodPut:
+					body, _ = io.ReadAll(r.Body)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.IndexMappings = json.RawMessage(mappings)
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(body).To(MatchJSON(mappings))
+		})
+
+		It("Attaches the lifecycle policy when creating the index", func() {
+			var body []byte
+			mappings := `{"mappings":{"properties":{"value":{"type":"double"}}}}`
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case http.MethodPut:
+					body, _ = io.ReadAll(r.Body)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.IndexMappings = json.RawMessage(mappings)
+			testcase.indexerConfig.ILMPolicy = "go-commons-rollover"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(body).To(MatchJSON(`{"mappings":{"properties":{"value":{"type":"double"}}},"settings":{"plugins.index_state_management.policy_id":"go-commons-rollover"}}`))
+		})
+
+		It("Points the alias at the index when creating it", func() {
+			var actions []byte
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case r.URL.Path == "/_alias/go-commons-alias":
+					fmt.Fprint(w, `{"go-commons-old":{"aliases":{"go-commons-alias":{}}}}`)
+					return
+				case r.URL.Path == "/_aliases":
+					actions, _ = io.ReadAll(r.Body)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.IndexAlias = "go-commons-alias"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(actions).To(MatchJSON(`{"actions":[{"remove":{"index":"go-commons-old","alias":"go-commons-alias"}},{"add":{"index":"go-commons-test","alias":"go-commons-alias"}}]}`))
+		})
+
+		It("Creates the alias when it doesn't exist", func() {
+			var actions []byte
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case r.URL.Path == "/_alias/go-commons-alias":
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"error":"alias [go-commons-alias] missing","status":404}`)
+					return
+				case r.URL.Path == "/_aliases":
+					actions, _ = io.ReadAll(r.Body)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.IndexAlias = "go-commons-alias"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(actions).To(MatchJSON(`{"actions":[{"add":{"index":"go-commons-test","alias":"go-commons-alias"}}]}`))
+		})
+
+		It("Returns err index alias with data streams", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.IndexAlias = "go-commons-alias"
+			testcase.indexerConfig.UseDataStream = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index alias isn't supported with data streams")))
+		})
+
+		It("Creates a data stream and its index template when enabled", func() {
+			var created []string
+			var template []byte
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case http.MethodPut:
+					created = append(created, r.URL.Path)
+					if strings.HasPrefix(r.URL.Path, "/_index_template/") {
+						template, _ = io.ReadAll(r.Body)
+					}
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.UseDataStream = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(created).To(Equal([]string{"/_index_template/go-commons-test", "/_data_stream/go-commons-test"}))
+			Expect(template).To(MatchJSON(`{"index_patterns":["go-commons-test*"],"data_stream":{},"priority":200}`))
+		})
+
+		It("Logs the health check and the index creation", func() {
+			logger := &capturingLogger{}
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.Logger = logger
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(logger.messages).To(ContainElement(HavePrefix("debug: OpenSearch health check succeeded")))
+			Expect(logger.messages).To(ContainElement("info: Index go-commons-test created on OpenSearch"))
+		})
+
+		It("Returns err missing index when index creation is disabled", func() {
+			var created []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodHead:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case http.MethodPut:
+					created = append(created, r.URL.Path)
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.SkipIndexCreation = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index go-commons-test not found on OpenSearch and index creation is disabled")))
+			Expect(created).To(BeEmpty())
+		})
+
+		It("Uses the existing index when index creation is disabled", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.SkipIndexCreation = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+		})
+
+		It("Sends the requests through the supplied transport", func() {
+			defer testcase.mockServer.Close()
+			transport := &recordingTransport{}
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.Transport = transport
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(transport.paths).To(ContainElement("/_cluster/health"))
+		})
+
+		It("Passes the configured bulk timeout to the bulk indexer", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.BulkTimeout = 5 * time.Second
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.bulkIndexerConfig().Timeout).To(Equal(5 * time.Second))
+		})
+
+		It("Defaults the bulk timeout when not configured", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.bulkIndexerConfig().Timeout).To(Equal(10 * time.Minute))
+		})
+
+		It("Passes the configured flush bytes to the bulk indexer", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.FlushBytes = 1024
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.bulkIndexerConfig().FlushBytes).To(Equal(1024))
+		})
+
+		It("Defaults the flush bytes when an invalid value is configured", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.FlushBytes = -1
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.bulkIndexerConfig().FlushBytes).To(Equal(defaultFlushBytes))
+		})
+
+		It("Passes the configured number of workers to the bulk indexer", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.NumWorkers = 2
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.bulkIndexerConfig().NumWorkers).To(Equal(2))
+		})
+
+		It("Defaults the number of workers to the number of CPUs", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.bulkIndexerConfig().NumWorkers).To(Equal(runtime.NumCPU()))
+		})
+
+		It("Signs the requests with AWS SigV4", func() {
+			var authorizations []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorizations = append(authorizations, r.Header.Get("Authorization"))
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			setEnv(map[string]string{"AWS_ACCESS_KEY_ID": "AKIDEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_SESSION_TOKEN": ""})
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.AWSSigV4 = true
+			testcase.indexerConfig.Region = "us-east-1"
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(authorizations).ToNot(BeEmpty())
+			for _, authorization := range authorizations {
+				Expect(authorization).To(HavePrefix("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+				Expect(authorization).To(ContainSubstring("/us-east-1/es/aws4_request"))
+			}
+		})
+
+		It("Keeps a client per indexer", func() {
+			defer testcase.mockServer.Close()
+			var first, second OpenSearch
+			firstTransport := &recordingTransport{}
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.Transport = firstTransport
+			Expect(first.New(testcase.indexerConfig)).To(BeNil())
+			testcase.indexerConfig.Transport = &recordingTransport{}
+			Expect(second.New(testcase.indexerConfig)).To(BeNil())
+			Expect(first.client).ToNot(BeIdenticalTo(second.client))
+			Expect(first.bulkIndexerConfig().Client).To(BeIdenticalTo(first.client))
+			firstTransport.paths = nil
+			Expect(first.createIndex(context.Background(), "go-commons-test")).To(BeNil())
+			Expect(firstTransport.paths).ToNot(BeEmpty())
+		})
+
+		It("Closes the idle connections of the transport", func() {
+			defer testcase.mockServer.Close()
+			transport := &recordingTransport{}
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.Transport = transport
+			Expect(indexer.New(testcase.indexerConfig)).To(BeNil())
+			Expect(indexer.Close()).To(BeNil())
+			Expect(transport.closed).To(BeTrue())
+		})
+
+		It("Returns err negative number of documents per flush", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.FlushDocs = -1
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("invalid number of documents per flush: -1")))
+		})
+
+		It("Returns err negative number of workers", func() {
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{testcase.mockServer.URL}
+			testcase.indexerConfig.NumWorkers = -1
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("invalid number of workers: -1")))
+		})
+
+		It("Returns err when the health check times out", func() {
+			defer testcase.mockServer.Close()
+			release := make(chan struct{})
+			slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			}))
+			defer slowServer.Close()
+			defer close(release)
+			testcase.indexerConfig.Servers = []string{slowServer.URL}
+			testcase.indexerConfig.HealthCheckTimeout = 100 * time.Millisecond
+			start := time.Now()
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("OpenSearch health check failed: timed out after 100ms")))
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		})
+
+		It("Skips the health check when disabled", func() {
+			var paths []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				if r.URL.Path == "/_cluster/health" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				testcase.mockServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			defer testcase.mockServer.Close()
+			testcase.indexerConfig.Servers = []string{mockServer.URL}
+			testcase.indexerConfig.SkipHealthCheck = true
+			err := indexer.New(testcase.indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(paths).NotTo(ContainElement("/_cluster/health"))
+		})
+
 	})
 
 	Context("Tests for Index()", func() {
@@ -99,27 +556,1105 @@ var _ = Describe("Tests for opensearch.go", func() {
 
 		var indexer OpenSearch
 		It("No err returned", func() {
-			_, err := indexer.Index(testcase.documents, testcase.opts)
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
 			Expect(err).To(BeNil())
 		})
 
 		It("Test empty list of docs", func() {
-			_, err := indexer.Index([]interface{}{}, testcase.opts)
-			Expect(err).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(result.Stats).To(BeEmpty())
 		})
 
 		It("Redundant list of docs", func() {
 			lastDoc := testcase.documents[len(testcase.documents)-1]
 			testcase.documents = append(testcase.documents, lastDoc)
-			_, err := indexer.Index(testcase.documents, testcase.opts)
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
 			Expect(err).To(BeNil())
 		})
 
 		It("err returned docs not processed", func() {
 			testcase.documents = append(testcase.documents, make(chan string))
-			_, err := indexer.Index(testcase.documents, testcase.opts)
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
 			Expect(err.Error()).To(ContainSubstring("Cannot encode document"))
 		})
 
+		It("Reports documents that failed to be indexed", func() {
+			mockServer := newBulkMockServer(func(n int) int {
+				if n%2 == 0 {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			})
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			msg, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("failed=3"))
+			Expect(msg).To(ContainSubstring("created=3"))
+		})
+
+		It("Returns err in fail-fast mode when a document fails to be indexed", func() {
+			mockServer := newBulkMockServer(func(n int) int {
+				if n == 0 {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			})
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.FailFast = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(ErrDocumentsFailed))
+			Expect(err.Error()).To(HaveSuffix("1 of 6 documents failed"))
+			Expect(result.Failed).To(Equal(1))
+		})
+
+		It("Returns the result of the documents indexed before the failure along with the error", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushDocs: 2,
+				Transport: &cancelingTransport{cancel: cancel}})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(ctx, testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(result.Created).To(Equal(2))
+			Expect(result.BulkStats.NumRequests).To(BeEquivalentTo(1))
+			ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+			err = indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushDocs: 2,
+				Transport: &cancelingTransport{cancel: cancel}})
+			Expect(err).To(BeNil())
+			msg, err := indexer.Index(ctx, testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(msg).To(ContainSubstring("updated=2"))
+		})
+
+		It("Returns the context error when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := indexer.Index(ctx, testcase.documents, testcase.opts)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("Logs the bulk flushes and the failed documents", func() {
+			logger := &capturingLogger{}
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusBadRequest })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", Logger: logger})
+			Expect(err).To(BeNil())
+			_, err = indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(logger.messages).To(ContainElement("debug: Bulk indexer flush started"))
+			Expect(logger.messages).To(ContainElement("debug: Bulk indexer flush finished"))
+			Expect(logger.messages).To(ContainElement(MatchRegexp("error: Error indexing document .*: mapper_parsing_exception: failed to parse")))
+		})
+
+		It("Returns the structured indexing result", func() {
+			mockServer := newBulkMockServer(func(n int) int {
+				if n == 0 {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			})
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(5))
+			Expect(result.Updated).To(Equal(0))
+			Expect(result.Failed).To(Equal(1))
+			Expect(result.Skipped).To(Equal(1))
+			Expect(result.Duration).To(BeNumerically(">", 0))
+		})
+
+		It("Returns the bulk indexer stats", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.BulkStats.NumAdded).To(BeEquivalentTo(len(testcase.documents)))
+			Expect(result.BulkStats.NumFlushed).To(BeEquivalentTo(len(testcase.documents)))
+			Expect(result.BulkStats.NumIndexed).To(BeEquivalentTo(len(testcase.documents)))
+			Expect(result.BulkStats.NumRequests).To(BeNumerically(">", 0))
+			Expect(result.String()).To(ContainSubstring("flushed=6"))
+		})
+
+		It("Reports the stats of every call when reusing the bulk indexer", func() {
+			mockServer := newBulkMockServer(func(n int) int {
+				if n == 1 {
+					return http.StatusBadRequest
+				}
+				return http.StatusCreated
+			})
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", ReuseBulkIndexer: true})
+			Expect(err).To(BeNil())
+			defer indexer.Close()
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents[:2], testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Failed).To(Equal(1))
+			Expect(result.BulkStats.NumAdded).To(BeEquivalentTo(2))
+			Expect(result.BulkStats.NumFailed).To(BeEquivalentTo(1))
+			Expect(result.FlushLatency.Flushes).To(BeNumerically(">", 0))
+			result, err = indexer.IndexWithResult(context.Background(), testcase.documents[2:], testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(4))
+			Expect(result.Failed).To(Equal(0))
+			Expect(result.BulkStats.NumAdded).To(BeEquivalentTo(4))
+			Expect(result.BulkStats.NumFlushed).To(BeEquivalentTo(4))
+			Expect(result.BulkStats.NumFailed).To(BeEquivalentTo(0))
+			Expect(indexer.reused.indexers).To(HaveLen(1))
+		})
+
+		It("Closes the reused bulk indexer on Close", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", ReuseBulkIndexer: true})
+			Expect(err).To(BeNil())
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(indexer.Close()).To(Succeed())
+			Expect(indexer.reused.indexers).To(BeEmpty())
+		})
+
+		It("Returns the percentiles of the flush durations", func() {
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					time.Sleep(20 * time.Millisecond)
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushDocs: 2})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.FlushLatency.Flushes).To(BeEquivalentTo(result.BulkStats.NumRequests))
+			Expect(result.FlushLatency.P50).To(BeNumerically(">=", 20*time.Millisecond))
+			Expect(result.FlushLatency.P95).To(BeNumerically(">=", result.FlushLatency.P50))
+			Expect(result.FlushLatency.P99).To(BeNumerically(">=", result.FlushLatency.P95))
+			Expect(result.String()).To(ContainSubstring(" p50="))
+		})
+
+		It("Flushes the bulk indexer every FlushDocs documents", func() {
+			bulkRequests := 0
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					bulkRequests++
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushDocs: 4})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			Expect(bulkRequests).To(Equal(2))
+			Expect(result.BulkStats.NumFlushed).To(BeEquivalentTo(len(testcase.documents)))
+			Expect(result.BulkStats.NumRequests).To(BeEquivalentTo(2))
+		})
+
+		It("Indexes against the cluster of each indexer", func() {
+			var bulkRequests [2]int32
+			var indexers [2]Indexer
+			for i := range indexers {
+				i := i
+				bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+				defer bulkServer.Close()
+				mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.HasSuffix(r.URL.Path, "/_bulk") {
+						atomic.AddInt32(&bulkRequests[i], 1)
+					}
+					bulkServer.Config.Handler.ServeHTTP(w, r)
+				}))
+				defer mockServer.Close()
+				var err error
+				indexers[i], err = NewIndexer(IndexerConfig{Type: "opensearch", Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+				Expect(err).To(BeNil())
+			}
+			var wg sync.WaitGroup
+			results := make([]IndexingResult, len(indexers))
+			for i, idx := range indexers {
+				wg.Add(1)
+				go func(i int, idx Indexer) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					var err error
+					results[i], err = idx.IndexWithResult(context.Background(), testcase.documents[:i+2], testcase.opts)
+					Expect(err).To(BeNil())
+				}(i, idx)
+			}
+			wg.Wait()
+			Expect(results[0].Created).To(Equal(2))
+			Expect(results[1].Created).To(Equal(3))
+			Expect(atomic.LoadInt32(&bulkRequests[0])).To(BeEquivalentTo(1))
+			Expect(atomic.LoadInt32(&bulkRequests[1])).To(BeEquivalentTo(1))
+		})
+
+		It("Uses the create action and sets the timestamp when indexing in a data stream", func() {
+			var lines []map[string]interface{}
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkLines(bulkServer.Config.Handler, &lines))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", UseDataStream: true})
+			Expect(err).To(BeNil())
+			testcase.documents = []interface{}{
+				map[string]interface{}{"value": 1},
+				map[string]interface{}{"value": 2, "@timestamp": "2023-06-01T10:00:00Z"},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(HaveKey("create"))
+			Expect(lines[1]).To(HaveKey("@timestamp"))
+			Expect(lines[2]).To(HaveKey("create"))
+			Expect(lines[3]).To(HaveKeyWithValue("@timestamp", "2023-06-01T10:00:00Z"))
+		})
+
+		It("Returns err indexing scalar documents in a data stream", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", UseDataStream: true})
+			Expect(err).To(BeNil())
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{42}, IndexingOpts{})
+			Expect(err).To(MatchError("document 42 is not a JSON object, cannot set @timestamp"))
+		})
+
+		It("Sets the indexing timestamp on every document", func() {
+			var lines []map[string]interface{}
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkLines(bulkServer.Config.Handler, &lines))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			testcase.documents = []interface{}{map[string]interface{}{"value": 1}, 42}
+			testcase.opts.AddTimestamp = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[1]).To(HaveKeyWithValue("value", BeEquivalentTo(1)))
+			Expect(lines[1]).To(HaveKeyWithValue("metadata", HaveKey("timestamp")))
+			Expect(lines[3]).To(HaveKeyWithValue("document", BeEquivalentTo(42)))
+			Expect(lines[3]).To(HaveKeyWithValue("metadata", HaveKey("timestamp")))
+		})
+
+		It("Sets the indexing timestamp on the configured field", func() {
+			var lines []map[string]interface{}
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkLines(bulkServer.Config.Handler, &lines))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.AddTimestamp = true
+			testcase.opts.TimestampField = "indexedAt"
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{"example document"}, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(lines).To(HaveLen(2))
+			Expect(lines[1]).To(HaveKeyWithValue("document", "example document"))
+			Expect(lines[1]).To(HaveKey("indexedAt"))
+		})
+
+		It("Tags the documents with the metric and job names", func() {
+			var lines []map[string]interface{}
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkLines(bulkServer.Config.Handler, &lines))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			testcase.opts = IndexingOpts{MetricName: "podLatency", JobName: "node-density"}
+			documents := []interface{}{map[string]interface{}{"value": 1}, 3.14, map[string]interface{}{"value": 2, "metricName": "nodeStatus"}}
+			_, err = indexer.IndexWithResult(context.Background(), documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(lines).To(HaveLen(6))
+			Expect(lines[1]).To(Equal(map[string]interface{}{"value": 1.0, "metricName": "podLatency", "jobName": "node-density"}))
+			Expect(lines[3]).To(Equal(map[string]interface{}{"document": 3.14, "metricName": "podLatency", "jobName": "node-density"}))
+			Expect(lines[5]).To(Equal(map[string]interface{}{"value": 2.0, "metricName": "nodeStatus", "jobName": "node-density"}))
+		})
+
+		It("Doesn't send any request in dry-run mode", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			transport := &recordingTransport{}
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", Transport: transport})
+			Expect(err).To(BeNil())
+			transport.paths = nil
+			testcase.opts.DryRun = true
+			testcase.opts.TimeBasedSuffix = "2006.01.02"
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(Equal(map[string]int{"validated": len(testcase.documents) - 1}))
+			Expect(result.Skipped).To(Equal(1))
+			testcase.documents = append(testcase.documents, make(chan string))
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err.Error()).To(ContainSubstring("Cannot encode document"))
+			Expect(transport.paths).To(BeEmpty())
+		})
+
+		It("Indexes the documents received from a channel", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushDocs: 10})
+			Expect(err).To(BeNil())
+			documents := make(chan interface{})
+			go func() {
+				defer close(documents)
+				for i := 0; i < 25; i++ {
+					documents <- map[string]interface{}{"value": i}
+				}
+			}()
+			var streamIndexer StreamIndexer = &indexer
+			msg, err := streamIndexer.IndexStream(context.Background(), documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("created=25"))
+			Expect(msg).To(ContainSubstring("requests=3"))
+		})
+
+		It("Indexes the raw JSON lines of an NDJSON reader", func() {
+			var lock sync.Mutex
+			var bulkLines []string
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					body, _ := io.ReadAll(r.Body)
+					lock.Lock()
+					bulkLines = append(bulkLines, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+					lock.Unlock()
+					r.Body = io.NopCloser(strings.NewReader(string(body)))
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			ndjson := "{\"value\":1,\"uuid\":\"1234\"}\n\n{ \"value\": 2 }\r\n{\"value\":1,\"uuid\":\"1234\"}\n[3]"
+			var ndjsonIndexer NDJSONIndexer = &indexer
+			msg, err := ndjsonIndexer.IndexNDJSON(context.Background(), strings.NewReader(ndjson), IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(msg).To(ContainSubstring("created=3"))
+			Expect(msg).To(ContainSubstring("skipped=1"))
+			Expect(bulkLines).To(HaveLen(6))
+			Expect(bulkLines[0]).To(ContainSubstring(hashDocument([]byte(`{"value":1,"uuid":"1234"}`))))
+			Expect(bulkLines[1]).To(Equal(`{"value":1,"uuid":"1234"}`))
+			Expect(bulkLines[3]).To(Equal(`{ "value": 2 }`))
+			Expect(bulkLines[5]).To(Equal(`[3]`))
+		})
+
+		It("Reads the indexed documents back", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			document := map[string]interface{}{"uuid": "1234", "value": 1}
+			_, err = indexer.Index(context.Background(), []interface{}{document}, IndexingOpts{DocumentIDField: "uuid"})
+			Expect(err).To(BeNil())
+			var reader DocumentReader = &indexer
+			source, err := reader.Get(context.Background(), "1234")
+			Expect(err).To(BeNil())
+			Expect(source).To(MatchJSON(`{"uuid":"1234","value":1}`))
+			count, err := reader.Count(context.Background())
+			Expect(err).To(BeNil())
+			Expect(count).To(Equal(1))
+			_, err = reader.Get(context.Background(), "5678")
+			Expect(err).To(MatchError(ErrDocumentNotFound))
+		})
+
+		It("Fails the flushes exceeding the flush timeout without exceeding the overall deadline", func() {
+			var lock sync.Mutex
+			flushes := 0
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					lock.Lock()
+					flushes++
+					slow := flushes == 1
+					lock.Unlock()
+					if slow {
+						time.Sleep(500 * time.Millisecond)
+					}
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushDocs: 1, NumWorkers: 1, MaxRetries: -1, FlushTimeout: 100 * time.Millisecond})
+			Expect(err).To(BeNil())
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			result, err := indexer.IndexWithResult(ctx, []interface{}{1, 2, 3}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(ctx.Err()).To(BeNil())
+			Expect(result.BulkStats.NumFailed).To(Equal(uint64(1)))
+			Expect(result.Created).To(Equal(2))
+		})
+
+		It("Returns err invalid flush timeout", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{"http://127.0.0.1:1"}, Index: "go-commons-test", FlushTimeout: -time.Second})
+			Expect(err).To(MatchError("invalid flush timeout: -1s"))
+		})
+
+		It("Returns err reusing the bulk indexer when flushing on interval", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{"http://127.0.0.1:1"}, Index: "go-commons-test", ReuseBulkIndexer: true, FlushInterval: time.Second})
+			Expect(err).To(MatchError("reusing the bulk indexer isn't supported when flushing on interval"))
+		})
+
+		It("Sends the document versions and counts the version conflicts", func() {
+			var lock sync.Mutex
+			var actions []map[string]map[string]interface{}
+			versions := make(map[string]int64)
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+					w.Write(payload)
+					return
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				var items []string
+				scanner := bufio.NewScanner(r.Body)
+				for line := 0; scanner.Scan(); line++ {
+					if line%2 != 0 {
+						continue
+					}
+					var action map[string]map[string]interface{}
+					Expect(json.Unmarshal(scanner.Bytes(), &action)).To(Succeed())
+					actions = append(actions, action)
+					meta := action["index"]
+					id := meta["_id"].(string)
+					version := int64(meta["version"].(float64))
+					if stored, exists := versions[id]; exists && version <= stored {
+						items = append(items, fmt.Sprintf(`{"index":{"_id":"%s","status":409,"error":{"type":"version_conflict_engine_exception","reason":"version conflict"}}}`, id))
+						continue
+					}
+					versions[id] = version
+					items = append(items, fmt.Sprintf(`{"index":{"_id":"%s","status":201,"result":"created"}}`, id))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			opts := IndexingOpts{DocumentIDField: "uuid", VersionField: "generation"}
+			documents := []interface{}{map[string]interface{}{"uuid": "1234", "generation": 2}}
+			result, err := indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(actions[0]["index"]).To(HaveKeyWithValue("version", BeNumerically("==", 2)))
+			Expect(actions[0]["index"]).To(HaveKeyWithValue("version_type", "external"))
+			documents = []interface{}{map[string]interface{}{"uuid": "1234", "generation": 1}}
+			result, err = indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(1))
+			Expect(result.Stats).To(HaveKeyWithValue("version_conflict_engine_exception", 1))
+		})
+
+		It("Returns err invalid versioning options", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{1}, IndexingOpts{VersionField: "generation", VersionType: "internal"})
+			Expect(err).To(MatchError("invalid version type: internal"))
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{1}, IndexingOpts{VersionType: "external"})
+			Expect(err).To(MatchError("version type external set without a version field"))
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{1}, IndexingOpts{VersionField: "generation", Action: "update"})
+			Expect(err).To(MatchError("versioning isn't supported by the update action"))
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{map[string]interface{}{"generation": "next"}}, IndexingOpts{VersionField: "generation"})
+			Expect(err).To(MatchError(ContainSubstring("invalid version next")))
+		})
+
+		It("Returns err invalid NDJSON line", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			_, err = indexer.IndexNDJSON(context.Background(), strings.NewReader("{\"value\":1}\n{\"value\":"), IndexingOpts{})
+			Expect(err).To(MatchError("invalid JSON document on line 2"))
+		})
+
+		It("Routes the documents with the configured routing field", func() {
+			var lines []map[string]interface{}
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkLines(bulkServer.Config.Handler, &lines))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			testcase.documents = []interface{}{
+				map[string]interface{}{"shard": "a", "value": 1},
+				map[string]interface{}{"value": 2},
+			}
+			testcase.opts.RoutingField = "shard"
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(HaveKeyWithValue("index", HaveKeyWithValue("routing", "a")))
+			Expect(lines[2]).To(HaveKeyWithValue("index", Not(HaveKey("routing"))))
+		})
+
+		It("Reports the redundant documents skipped", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(0))
+			Expect(result.String()).To(ContainSubstring("redundantskipped=0"))
+			testcase.documents = append(testcase.documents, testcase.documents[0], testcase.documents[1], testcase.documents[0])
+			result, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(3))
+			Expect(result.String()).To(ContainSubstring("redundantskipped=3"))
+		})
+
+		It("Forwards the refresh parameter to the bulk requests", func() {
+			var queries []url.Values
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkQueries(bulkServer.Config.Handler, &queries))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.Refresh = "wait_for"
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			Expect(queries).NotTo(BeEmpty())
+			for _, query := range queries {
+				Expect(query.Get("refresh")).To(Equal("wait_for"))
+			}
+		})
+
+		It("Processes the documents with the configured ingest pipeline", func() {
+			var queries []url.Values
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkQueries(bulkServer.Config.Handler, &queries))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.Pipeline = "geoip"
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(queries).NotTo(BeEmpty())
+			for _, query := range queries {
+				Expect(query.Get("pipeline")).To(Equal("geoip"))
+			}
+		})
+
+		It("Short-circuits the indexing calls while the circuit breaker is open", func() {
+			var failing atomic.Bool
+			failing.Store(true)
+			var bulkRequests atomic.Int32
+			bulkServer := newBulkMockServer(func(n int) int {
+				if failing.Load() {
+					return http.StatusServiceUnavailable
+				}
+				return http.StatusCreated
+			})
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					bulkRequests.Add(1)
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", CircuitBreakerFailures: 2, CircuitBreakerCooldown: time.Minute})
+			Expect(err).To(BeNil())
+			for i := 0; i < 2; i++ {
+				result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(BeNil())
+				Expect(result.Failed).To(Equal(len(testcase.documents)))
+			}
+			requests := bulkRequests.Load()
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(ErrCircuitOpen))
+			Expect(bulkRequests.Load()).To(Equal(requests))
+			// The trial call closes the breaker once the cluster recovers
+			failing.Store(false)
+			indexer.breaker.now = func() time.Time { return time.Now().Add(time.Minute) }
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			Expect(indexer.breaker.state).To(Equal(circuitClosed))
+		})
+
+		It("Uses the configured bulk action", func() {
+			for _, action := range []string{"index", "create", "update"} {
+				var lines []map[string]interface{}
+				bulkServer := newBulkMockServer(func(n int) int { return http.StatusOK })
+				mockServer := httptest.NewServer(recordBulkLines(bulkServer.Config.Handler, &lines))
+				err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+				Expect(err).To(BeNil())
+				testcase.opts.Action = action
+				_, err = indexer.IndexWithResult(context.Background(), []interface{}{map[string]interface{}{"key": "value"}}, testcase.opts)
+				mockServer.Close()
+				bulkServer.Close()
+				Expect(err).To(BeNil())
+				Expect(lines).To(HaveLen(2))
+				Expect(lines[0]).To(HaveKey(action))
+				if action == "update" {
+					Expect(lines[1]).To(Equal(map[string]interface{}{"doc": map[string]interface{}{"key": "value", "metricName": "placeholder"}}))
+				} else {
+					Expect(lines[1]).To(Equal(map[string]interface{}{"key": "value", "metricName": "placeholder"}))
+				}
+			}
+		})
+
+		It("Returns err invalid bulk action", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.Action = "delete"
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError("invalid bulk action: delete"))
+		})
+
+		It("Returns err bulk action not supported by data streams", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", UseDataStream: true})
+			Expect(err).To(BeNil())
+			testcase.opts.Action = "update"
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError("bulk action update not supported by data streams"))
+		})
+
+		It("Returns err invalid refresh value", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.Refresh = "always"
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeEquivalentTo(errors.New("invalid refresh value: always")))
+		})
+
+		It("Captures the reasons of the failed documents", func() {
+			reason := "failed to parse field [value] of type [long], preview of field's value: '" + strings.Repeat("x", 300) + "'"
+			mockServer := httptest.NewServer(failingBulkHandler([]FailureReason{
+				{Status: http.StatusBadRequest, Type: "mapper_parsing_exception", Reason: reason},
+				{Status: http.StatusTooManyRequests, Type: "es_rejected_execution_exception", Reason: "rejected execution of coordinating operation"},
+				{Status: http.StatusConflict, Type: "version_conflict_engine_exception", Reason: "version conflict, document already exists"},
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(3))
+			Expect(result.FailureReasons).To(BeEmpty())
+			testcase.opts.MaxFailureReasons = 2
+			result, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(3))
+			Expect(result.FailureReasons).To(HaveLen(2))
+			Expect(result.FailureReasons[0].DocumentID).NotTo(BeEmpty())
+			Expect(result.FailureReasons[0].Status).To(Equal(http.StatusBadRequest))
+			Expect(result.FailureReasons[0].Type).To(Equal("mapper_parsing_exception"))
+			Expect(result.FailureReasons[0].Reason).To(HaveLen(256))
+			Expect(result.FailureReasons[0].Reason).To(HavePrefix("failed to parse field [value] of type [long]"))
+			Expect(result.FailureReasons[0].Reason).To(HaveSuffix("xxx..."))
+			Expect(result.FailureReasons[1]).To(Equal(FailureReason{
+				DocumentID: result.FailureReasons[1].DocumentID,
+				Status:     http.StatusTooManyRequests,
+				Type:       "es_rejected_execution_exception",
+				Reason:     "rejected execution of coordinating operation",
+			}))
+			testcase.opts.MaxFailureReasons = -1
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError("invalid maximum number of failure reasons: -1"))
+		})
+
+		It("Uses the configured document field as document ID", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			testcase.documents = []interface{}{
+				map[string]interface{}{"uuid": "a", "value": 1},
+				map[string]interface{}{"uuid": "b", "value": 2},
+				map[string]interface{}{"uuid": "a", "value": 3},
+			}
+			testcase.opts.DocumentIDField = "uuid"
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.Updated).To(Equal(1))
+			Expect(result.Skipped).To(Equal(0))
+		})
+
+		It("Compresses the bulk requests when compression is enabled", func() {
+			var encodings []string
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					encodings = append(encodings, r.Header.Get("Content-Encoding"))
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", Compression: true})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			Expect(encodings).To(ConsistOf("gzip"))
+		})
+
+		It("Skips and counts the documents exceeding the maximum document size", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", MaxDocBytes: 64})
+			Expect(err).To(BeNil())
+			documents := []interface{}{
+				map[string]interface{}{"value": 1},
+				map[string]interface{}{"value": strings.Repeat("x", 64)},
+				map[string]interface{}{"value": 2},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.Stats).To(HaveKeyWithValue("oversized", 1))
+		})
+
+		It("Returns err invalid maximum document size", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{"http://localhost:9200"}, Index: "go-commons-test", MaxDocBytes: -1})
+			Expect(err).To(MatchError("invalid maximum document size: -1"))
+		})
+
+		It("Decompresses the gzip encoded responses when compression is enabled", func() {
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(gzipResponseHandler(bulkServer.Config.Handler))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", Compression: true})
+			Expect(err).To(BeNil())
+			Expect(indexer.ServerVersion().Number).To(Equal("7.10.2"))
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+			stats := indexer.CompressionStats()
+			Expect(stats.Responses).To(BeNumerically(">=", 2))
+			Expect(stats.BytesSaved()).To(BeNumerically(">", 0))
+		})
+
+		It("Retries the bulk requests failed with a transient error", func() {
+			bulkRequests := 0
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					bulkRequests++
+					if bulkRequests <= 2 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", RetryBackoff: time.Millisecond})
+			Expect(err).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(bulkRequests).To(Equal(3))
+			Expect(result.Created).To(Equal(len(testcase.documents)))
+		})
+
+		It("Indexes the documents in a time based index", func() {
+			var created, bulkPaths []string
+			index := timeBasedIndex("go-commons-test", "2006.01.02", time.Now())
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodHead && r.URL.Path == "/"+index:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case r.Method == http.MethodPut:
+					created = append(created, r.URL.Path)
+				case strings.HasSuffix(r.URL.Path, "/_bulk"):
+					bulkPaths = append(bulkPaths, r.URL.Path)
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.TimeBasedSuffix = "2006.01.02"
+			_, err = indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(created).To(ConsistOf("/" + index))
+			Expect(bulkPaths).To(ConsistOf("/" + index + "/_bulk"))
+		})
+
+		It("Routes the documents to the index held in the index field", func() {
+			var created []string
+			var lines []map[string]interface{}
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(recordBulkLines(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodHead && r.URL.Path == "/tenant-b":
+					w.WriteHeader(http.StatusNotFound)
+					return
+				case r.Method == http.MethodPut:
+					created = append(created, r.URL.Path)
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}), &lines))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 1})
+			Expect(err).To(BeNil())
+			testcase.documents = []interface{}{
+				map[string]interface{}{"tenant": "Tenant-A", "value": 1},
+				map[string]interface{}{"tenant": "tenant-b", "value": 2},
+				map[string]interface{}{"tenant": "tenant-b", "value": 3},
+				map[string]interface{}{"value": 4},
+			}
+			testcase.opts.IndexField = "tenant"
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(4))
+			Expect(created).To(ConsistOf("/tenant-b"))
+			var indices []interface{}
+			for i := 0; i < len(lines); i += 2 {
+				indices = append(indices, lines[i]["index"].(map[string]interface{})["_index"])
+			}
+			Expect(indices).To(Equal([]interface{}{"tenant-a", "tenant-b", "tenant-b", nil}))
+		})
+
+		It("Returns err invalid target index", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.opts.IndexField = "tenant"
+			_, err = indexer.IndexWithResult(context.Background(), []interface{}{map[string]interface{}{"tenant": "a*b"}}, testcase.opts)
+			Expect(err).To(MatchError(`invalid index name "a*b": must not contain '*'`))
+		})
+
+		It("Caps the number of bulk requests in flight", func() {
+			var inFlight, maxInFlight int32
+			bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer bulkServer.Close()
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_bulk") {
+					n := atomic.AddInt32(&inFlight, 1)
+					defer atomic.AddInt32(&inFlight, -1)
+					for {
+						max := atomic.LoadInt32(&maxInFlight)
+						if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+				}
+				bulkServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", NumWorkers: 4, FlushBytes: 100, MaxConcurrentFlushes: 1})
+			Expect(err).To(BeNil())
+			testcase.documents = nil
+			for i := 0; i < 40; i++ {
+				testcase.documents = append(testcase.documents, map[string]interface{}{"value": i})
+			}
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(40))
+			Expect(result.BulkStats.NumRequests).To(BeNumerically(">", 1))
+			Expect(maxInFlight).To(Equal(int32(1)))
+		})
+
+		It("Returns err invalid number of concurrent flushes", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{"http://localhost:9200"}, Index: "go-commons-test", MaxConcurrentFlushes: -1})
+			Expect(err).To(MatchError("invalid number of concurrent flushes: -1"))
+		})
+
+		Context("Flushing on interval", func() {
+			var bulkRequests int32
+			var mockServer *httptest.Server
+			var ticks chan time.Time
+			BeforeEach(func() {
+				atomic.StoreInt32(&bulkRequests, 0)
+				bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+				DeferCleanup(bulkServer.Close)
+				mockServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.HasSuffix(r.URL.Path, "/_bulk") {
+						atomic.AddInt32(&bulkRequests, 1)
+					}
+					bulkServer.Config.Handler.ServeHTTP(w, r)
+				}))
+				DeferCleanup(mockServer.Close)
+				err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", FlushInterval: time.Minute})
+				Expect(err).To(BeNil())
+				ticks = make(chan time.Time)
+				indexer.background.newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+					Expect(d).To(Equal(time.Minute))
+					return ticks, func() {}
+				}
+			})
+
+			It("Queues the documents until the interval elapses", func() {
+				result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(BeNil())
+				Expect(result.Stats[queuedStat] + result.Skipped).To(Equal(len(testcase.documents)))
+				Expect(result.Created).To(Equal(0))
+				_, err = indexer.IndexWithResult(context.Background(), testcase.documents[:1], testcase.opts)
+				Expect(err).To(BeNil())
+				Consistently(func() int32 { return atomic.LoadInt32(&bulkRequests) }, 100*time.Millisecond).Should(BeZero())
+				ticks <- time.Now()
+				Eventually(func() int32 { return atomic.LoadInt32(&bulkRequests) }).Should(BeEquivalentTo(1))
+				Expect(indexer.Close()).To(Succeed())
+				Expect(atomic.LoadInt32(&bulkRequests)).To(BeEquivalentTo(1))
+			})
+
+			It("Flushes the queued documents on Close", func() {
+				_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(BeNil())
+				Expect(indexer.Close()).To(Succeed())
+				Expect(atomic.LoadInt32(&bulkRequests)).To(BeEquivalentTo(1))
+			})
+
+			It("Returns err refresh with a flush interval", func() {
+				testcase.opts.Refresh = "true"
+				_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(MatchError("refresh and pipeline aren't supported when flushing on interval"))
+			})
+
+			It("Returns err fail fast with a flush interval", func() {
+				testcase.opts.FailFast = true
+				_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(MatchError("fail fast isn't supported when flushing on interval"))
+			})
+
+			It("Returns err failure reasons with a flush interval", func() {
+				testcase.opts.MaxFailureReasons = 10
+				_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(MatchError("failure reasons aren't supported when flushing on interval"))
+			})
+		})
+
+		It("Returns err invalid flush interval", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{"http://localhost:9200"}, Index: "go-commons-test", FlushInterval: -time.Second})
+			Expect(err).To(MatchError("invalid flush interval: -1s"))
+		})
+
+		It("Detects the OpenSearch version", func() {
+			var bulkHeaders []http.Header
+			mockServer := newVersionMockServer(`{"distribution":"opensearch","number":"2.11.0"}`, &bulkHeaders)
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			Expect(indexer.ServerVersion()).To(Equal(ServerVersion{Distribution: "opensearch", Number: "2.11.0", Major: 2, Minor: 11}))
+		})
+
+		It("Doesn't detect the version when the health check is skipped", func() {
+			var bulkHeaders []http.Header
+			mockServer := newVersionMockServer(`{"distribution":"opensearch","number":"2.11.0"}`, &bulkHeaders)
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test", SkipHealthCheck: true})
+			Expect(err).To(BeNil())
+			Expect(indexer.ServerVersion()).To(BeZero())
+		})
+
+		It("Skips redundant documents by default", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created + result.Updated).To(Equal(6))
+			Expect(result.Skipped).To(Equal(1))
+		})
+
+		It("Indexes redundant documents when deduplication is disabled", func() {
+			mockServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+			defer mockServer.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			testcase.opts.SkipDedup = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created + result.Updated).To(Equal(7))
+			Expect(result.Skipped).To(Equal(0))
+		})
+
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer OpenSearch
+		var status int
+		var mockServer *httptest.Server
+		BeforeEach(func() {
+			status = http.StatusOK
+			mockServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/_cluster/health" {
+					w.WriteHeader(status)
+				}
+				_, _ = w.Write(payload)
+			}))
+			err := indexer.New(IndexerConfig{Servers: []string{mockServer.URL}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+		})
+		AfterEach(func() {
+			mockServer.Close()
+		})
+
+		It("Succeeds when the cluster is healthy", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Returns err unauthorized when the credentials are rejected", func() {
+			status = http.StatusUnauthorized
+			err := indexer.Ping(context.Background())
+			Expect(err).To(MatchError(ErrUnauthorized))
+			Expect(err).To(MatchError("OpenSearch backend rejected the credentials: status code 401"))
+		})
+
+		It("Returns err unhealthy when the cluster fails", func() {
+			status = http.StatusServiceUnavailable
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnhealthy))
+		})
+
+		It("Returns err unreachable when the cluster can't be reached", func() {
+			mockServer.Close()
+			err := indexer.Ping(context.Background())
+			Expect(err).To(MatchError(ErrUnreachable))
+			Expect(errors.Is(err, ErrUnauthorized)).To(BeFalse())
+		})
 	})
 })
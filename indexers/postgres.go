@@ -0,0 +1,254 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const postgresIndexer = "postgres"
+
+// postgresTx transaction upserting documents
+type postgresTx interface {
+	upsert(ctx context.Context, query, id string, document []byte) (bool, error)
+	Commit() error
+	Rollback() error
+}
+
+// postgresDB PostgreSQL database
+type postgresDB interface {
+	begin(ctx context.Context) (postgresTx, error)
+	exec(ctx context.Context, query string) error
+	Close() error
+}
+
+// sqlPostgresDB postgresDB backed by database/sql
+type sqlPostgresDB struct {
+	*sql.DB
+}
+
+// begin begins a transaction
+func (db sqlPostgresDB) begin(ctx context.Context) (postgresTx, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sqlPostgresTx{tx}, nil
+}
+
+// exec executes the given statement
+func (db sqlPostgresDB) exec(ctx context.Context, query string) error {
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
+
+// sqlPostgresTx postgresTx backed by database/sql
+type sqlPostgresTx struct {
+	*sql.Tx
+}
+
+// upsert runs the given upsert query, reporting whether the row was inserted rather than updated
+func (tx sqlPostgresTx) upsert(ctx context.Context, query, id string, document []byte) (bool, error) {
+	var inserted bool
+	err := tx.QueryRowContext(ctx, query, id, string(document)).Scan(&inserted)
+	return inserted, err
+}
+
+// Postgres PostgreSQL instance
+type Postgres struct {
+	table     string
+	db        postgresDB
+	flushDocs int
+}
+
+// Init function
+func init() {
+	Register(postgresIndexer, func() Indexer { return &Postgres{} })
+}
+
+// Returns new indexer for PostgreSQL, Servers[0] being the DSN. The table, holding the documents in a JSONB column
+// keyed by their ID, is created when it doesn't exist unless index creation is disabled
+func (p *Postgres) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	connConfig, err := pgx.ParseConfig(indexerConfig.Servers[0])
+	if err != nil {
+		return fmt.Errorf("invalid PostgreSQL DSN: %s", err)
+	}
+	if indexerConfig.InsecureSkipVerify || indexerConfig.CACertPath != "" {
+		if connConfig.TLSConfig, err = tlsConfig(indexerConfig); err != nil {
+			return err
+		}
+	}
+	p.db = sqlPostgresDB{stdlib.OpenDB(*connConfig)}
+	p.table = quotePostgresTable(indexerConfig.Index)
+	p.flushDocs = indexerConfig.FlushDocs
+	if indexerConfig.SkipIndexCreation {
+		return nil
+	}
+	return p.createTable(context.Background())
+}
+
+// createTable creates the table when it doesn't exist
+func (p *Postgres) createTable(ctx context.Context) error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, document JSONB NOT NULL)", p.table)
+	if err := p.db.exec(ctx, query); err != nil {
+		return fmt.Errorf("error creating table %s on PostgreSQL: %s", p.table, err)
+	}
+	return nil
+}
+
+// Index upserts the documents into the configured table
+func (p *Postgres) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := p.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult upserts the documents into the configured table and returns the indexing result.
+// Documents are keyed by their ID and upserted in transactions of up to flushDocs documents, all of them by default
+func (p *Postgres) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, p.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	// xmax is only set on the updated rows
+	query := fmt.Sprintf("INSERT INTO %s (id, document) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET document = EXCLUDED.document RETURNING (xmax = 0)", p.table)
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var tx postgresTx
+	txDocs := 0
+	batchStats := make(map[string]int)
+	commit := func() error {
+		if tx == nil {
+			return nil
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("Unexpected PostgreSQL error: %s", err)
+		}
+		for result, count := range batchStats {
+			indexerStats[result] += count
+		}
+		tx, txDocs, batchStats = nil, 0, make(map[string]int)
+		return nil
+	}
+	rollback := func(err error) (IndexingResult, error) {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+		return IndexingResult{}, err
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return rollback(fmt.Errorf("Cannot encode document %s: %s", document, err))
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		docId := documentID(j, opts.DocumentIDField, docHashKey)
+		reportDocumentID(opts, document, docId)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return rollback(err)
+		}
+		if tx == nil {
+			if tx, err = p.db.begin(ctx); err != nil {
+				return IndexingResult{}, fmt.Errorf("Unexpected PostgreSQL error: %s", err)
+			}
+		}
+		inserted, err := tx.upsert(ctx, query, docId, j)
+		if err != nil {
+			return rollback(fmt.Errorf("Unexpected PostgreSQL error: %s", err))
+		}
+		if inserted {
+			batchStats["created"]++
+		} else {
+			batchStats["updated"]++
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		if txDocs++; txDocs == p.flushDocs {
+			if err := commit(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+	}
+	if err := commit(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// quotePostgresTable quotes the parts of the given, optionally schema qualified, table name
+func quotePostgresTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = pgx.Identifier{part}.Sanitize()
+	}
+	return strings.Join(parts, ".")
+}
+
+// Ping checks the PostgreSQL server can be queried, telling the rejected credentials apart
+func (p *Postgres) Ping(ctx context.Context) error {
+	err := p.db.exec(ctx, "SELECT 1")
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	// Class 28 SQLSTATE codes report invalid authorization specifications
+	if errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "28") {
+		return pingFailed("PostgreSQL", ErrUnauthorized, err)
+	}
+	return pingFailed("PostgreSQL", ErrUnreachable, err)
+}
+
+// Close closes the PostgreSQL connections
+func (p *Postgres) Close() error {
+	if p.db != nil {
+		return p.db.Close()
+	}
+	return nil
+}
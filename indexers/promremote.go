@@ -0,0 +1,243 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const prometheusIndexer = "prometheus"
+
+// Prometheus remote-write indexer instance
+type Prometheus struct {
+	url      string
+	client   *http.Client
+	username string
+	password string
+	apiKey   string
+}
+
+// promSample document format expected by the prometheus indexer
+type promSample struct {
+	MetricName string            `json:"metricName"`
+	Labels     map[string]string `json:"labels"`
+	Value      *float64          `json:"value"`
+	Timestamp  json.RawMessage   `json:"timestamp"`
+}
+
+// Init function
+func init() {
+	Register(prometheusIndexer, func() Indexer { return &Prometheus{} })
+}
+
+// Returns new indexer for Prometheus remote-write
+func (p *Prometheus) New(indexerConfig IndexerConfig) error {
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("remote-write endpoint not specified")
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	p.url = indexerConfig.Servers[0]
+	p.client = &http.Client{Transport: transport}
+	p.username = indexerConfig.Username
+	p.password = indexerConfig.Password
+	p.apiKey = indexerConfig.APIKey
+	return nil
+}
+
+// Index writes the documents as samples to the remote-write endpoint
+func (p *Prometheus) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := p.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult writes the documents as samples to the remote-write endpoint and returns the indexing result
+func (p *Prometheus) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, p.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var writeRequest []byte
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		timeSeries, err := encodeTimeSeries(j, opts.MetricName, start)
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		writeRequest = protowire.AppendTag(writeRequest, 1, protowire.BytesType)
+		writeRequest = protowire.AppendBytes(writeRequest, timeSeries)
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		indexerStats["created"]++
+	}
+	if err := p.write(ctx, snappy.Encode(nil, writeRequest)); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// write posts the compressed write request to the remote-write endpoint
+func (p *Prometheus) write(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	p.authorize(req)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unexpected Prometheus error: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected Prometheus response %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// encodeTimeSeries encodes the given JSON document as a remote-write TimeSeries message,
+// metricName is used when the document doesn't provide one and now when it has no timestamp
+func encodeTimeSeries(j []byte, metricName string, now time.Time) ([]byte, error) {
+	var sample promSample
+	if err := json.Unmarshal(j, &sample); err != nil {
+		return nil, fmt.Errorf("Cannot decode sample %s: %s", j, err)
+	}
+	if sample.Value == nil {
+		return nil, fmt.Errorf("value not found in sample %s", j)
+	}
+	if sample.MetricName == "" {
+		sample.MetricName = metricName
+	}
+	if sample.MetricName == "" {
+		return nil, fmt.Errorf("metric name not found in sample %s", j)
+	}
+	timestamp, err := sampleTimestamp(sample.Timestamp, now)
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{"__name__": sample.MetricName}
+	for name, value := range sample.Labels {
+		if name != "__name__" {
+			labels[name] = value
+		}
+	}
+	// Remote-write receivers expect the labels sorted by name
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var timeSeries []byte
+	for _, name := range names {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, labels[name])
+		timeSeries = protowire.AppendTag(timeSeries, 1, protowire.BytesType)
+		timeSeries = protowire.AppendBytes(timeSeries, label)
+	}
+	var s []byte
+	s = protowire.AppendTag(s, 1, protowire.Fixed64Type)
+	s = protowire.AppendFixed64(s, math.Float64bits(*sample.Value))
+	s = protowire.AppendTag(s, 2, protowire.VarintType)
+	s = protowire.AppendVarint(s, uint64(timestamp.UnixMilli()))
+	timeSeries = protowire.AppendTag(timeSeries, 2, protowire.BytesType)
+	timeSeries = protowire.AppendBytes(timeSeries, s)
+	return timeSeries, nil
+}
+
+// sampleTimestamp parses a RFC3339 or unix milliseconds timestamp, defaulting to now
+func sampleTimestamp(raw json.RawMessage, now time.Time) (time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return now, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid sample timestamp %s: %s", s, err)
+		}
+		return t, nil
+	}
+	ms, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid sample timestamp %s: %s", raw, err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// Ping checks the remote-write endpoint is reachable. Remote-write endpoints only accept writes, answering the GET
+// request with 405 or 400, which are considered healthy along with the 2xx status codes
+func (p *Prometheus) Ping(ctx context.Context) error {
+	return pingHTTP(ctx, "Prometheus", p.client, p.url, p.authorize, http.StatusMethodNotAllowed, http.StatusBadRequest)
+}
+
+// authorize sets the credentials of the request, the API key taking precedence over basic auth
+func (p *Prometheus) authorize(req *http.Request) {
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	} else if p.username != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+}
+
+// Close closes the idle connections of the remote-write client
+func (p *Prometheus) Close() error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
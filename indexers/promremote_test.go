@@ -0,0 +1,242 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriteSample sample decoded from a remote-write request
+type remoteWriteSample struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest decodes the snappy compressed remote-write request into its samples
+func decodeWriteRequest(body []byte) []remoteWriteSample {
+	data, err := snappy.Decode(nil, body)
+	Expect(err).To(BeNil())
+	var samples []remoteWriteSample
+	for len(data) > 0 {
+		_, _, n := protowire.ConsumeTag(data)
+		data = data[n:]
+		timeSeries, n := protowire.ConsumeBytes(data)
+		data = data[n:]
+		sample := remoteWriteSample{labels: map[string]string{}}
+		for len(timeSeries) > 0 {
+			num, _, n := protowire.ConsumeTag(timeSeries)
+			timeSeries = timeSeries[n:]
+			msg, n := protowire.ConsumeBytes(timeSeries)
+			timeSeries = timeSeries[n:]
+			fields := map[protowire.Number][]byte{}
+			for len(msg) > 0 {
+				field, typ, n := protowire.ConsumeTag(msg)
+				msg = msg[n:]
+				n = protowire.ConsumeFieldValue(field, typ, msg)
+				fields[field] = msg[:n]
+				msg = msg[n:]
+			}
+			if num == 1 {
+				name, _ := protowire.ConsumeBytes(fields[1])
+				value, _ := protowire.ConsumeBytes(fields[2])
+				sample.labels[string(name)] = string(value)
+			} else {
+				value, _ := protowire.ConsumeFixed64(fields[1])
+				timestamp, _ := protowire.ConsumeVarint(fields[2])
+				sample.value = math.Float64frombits(value)
+				sample.timestamp = int64(timestamp)
+			}
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+var _ = Describe("Tests for promremote.go", func() {
+	var indexerConfig IndexerConfig
+	var indexer Prometheus
+	var server *httptest.Server
+	var requests []*http.Request
+	var samples []remoteWriteSample
+	BeforeEach(func() {
+		requests = nil
+		samples = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			requests = append(requests, r)
+			samples = append(samples, decodeWriteRequest(body)...)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		indexerConfig = IndexerConfig{Type: "prometheus",
+			Servers: []string{server.URL + "/api/v1/write"},
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Tests for New()", func() {
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.url).To(Equal(server.URL + "/api/v1/write"))
+		})
+
+		It("Returns err no endpoint", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var timestamp time.Time
+		BeforeEach(func() {
+			timestamp = time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+		})
+
+		It("Writes the samples to the remote-write endpoint", func() {
+			documents := []interface{}{
+				map[string]interface{}{
+					"metricName": "podLatency",
+					"labels":     map[string]string{"uuid": "1234", "quantile": "P99"},
+					"value":      2.5,
+					"timestamp":  timestamp.Format(time.RFC3339),
+				},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Method).To(Equal(http.MethodPost))
+			Expect(requests[0].Header.Get("Content-Encoding")).To(Equal("snappy"))
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/x-protobuf"))
+			Expect(samples).To(HaveLen(1))
+			Expect(samples[0].labels).To(Equal(map[string]string{"__name__": "podLatency", "uuid": "1234", "quantile": "P99"}))
+			Expect(samples[0].value).To(Equal(2.5))
+			Expect(samples[0].timestamp).To(Equal(timestamp.UnixMilli()))
+		})
+
+		It("Uses the metric name from the options and unix milliseconds timestamps", func() {
+			documents := []interface{}{
+				map[string]interface{}{"value": 1, "timestamp": timestamp.UnixMilli()},
+			}
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{MetricName: "jobSummary"})
+			Expect(err).To(BeNil())
+			Expect(samples).To(HaveLen(1))
+			Expect(samples[0].labels).To(HaveKeyWithValue("__name__", "jobSummary"))
+			Expect(samples[0].timestamp).To(Equal(timestamp.UnixMilli()))
+		})
+
+		It("Skips the redundant samples", func() {
+			document := map[string]interface{}{"metricName": "up", "value": 1, "timestamp": timestamp.UnixMilli()}
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{document, document}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Skipped).To(Equal(1))
+			Expect(samples).To(HaveLen(1))
+		})
+
+		It("Sends basic auth credentials", func() {
+			indexerConfig.Username = "user"
+			indexerConfig.Password = "secret"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"metricName": "up", "value": 1}}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			username, password, ok := requests[0].BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("user"))
+			Expect(password).To(Equal("secret"))
+		})
+
+		It("Doesn't write any sample in dry-run mode", func() {
+			documents := []interface{}{
+				map[string]interface{}{"metricName": "up", "value": 1},
+				map[string]interface{}{"metricName": "up", "value": 2},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{DryRun: true})
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 2))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err documents without value", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"metricName": "up", "labels": map[string]string{"uuid": "1234"}}}, IndexingOpts{})
+			Expect(err).To(MatchError(ContainSubstring("value not found in sample")))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err no metric name", func() {
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{})
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Returns err when the endpoint rejects the request", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "out of order sample", http.StatusBadRequest)
+			})
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"metricName": "up", "value": 1}}, IndexingOpts{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("out of order sample"))
+		})
+
+		It("Returns the skip message with no documents", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, IndexingOpts{})
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Accepts the remote-write endpoints only allowing writes", func() {
+			for _, status := range []int{http.StatusMethodNotAllowed, http.StatusBadRequest} {
+				status := status
+				server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(status)
+				})
+				Expect(indexer.New(indexerConfig)).To(BeNil())
+				Expect(indexer.Ping(context.Background())).To(Succeed())
+			}
+		})
+
+		It("Returns ErrUnauthorized when the credentials are rejected", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnauthorized))
+		})
+
+		It("Returns ErrUnhealthy when the endpoint isn't found", func() {
+			server.Config.Handler = http.NotFoundHandler()
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnhealthy))
+		})
+	})
+})
@@ -0,0 +1,131 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQueueClosed returned by Submit once the queue is closed
+var ErrQueueClosed = errors.New("queue closed")
+
+// Queue bounded work queue giving back-pressure to the producers of documents: Submit blocks while the queue is
+// full, a background goroutine draining it to the indexer in batches of up to the queue size
+type Queue struct {
+	ctx       context.Context
+	indexer   Indexer
+	opts      IndexingOpts
+	documents chan interface{}
+	done      chan struct{}
+	start     time.Time
+	// closeLock guards closed, held for reading by the pending Submit calls
+	closeLock sync.RWMutex
+	closed    bool
+	lock      sync.Mutex
+	result    IndexingResult
+	errs      multiError
+}
+
+// NewQueue returns a queue of the given size indexing the submitted documents with the given indexer and options.
+// The queue doesn't close the indexer, which remains owned by the caller
+func NewQueue(ctx context.Context, indexer Indexer, size int, opts IndexingOpts) (*Queue, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid queue size: %d", size)
+	}
+	q := &Queue{
+		ctx:       ctx,
+		indexer:   indexer,
+		opts:      opts,
+		documents: make(chan interface{}, size),
+		done:      make(chan struct{}),
+		start:     time.Now(),
+		result:    IndexingResult{Stats: make(map[string]int)},
+	}
+	go q.drain()
+	return q, nil
+}
+
+// Submit queues the document, blocking while the queue is full until the document is queued or ctx is done
+func (q *Queue) Submit(ctx context.Context, document interface{}) error {
+	q.closeLock.RLock()
+	defer q.closeLock.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.documents <- document:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drain indexes the queued documents until the queue is closed, taking along with every document the ones already
+// queued behind it
+func (q *Queue) drain() {
+	defer close(q.done)
+	for document := range q.documents {
+		batch := []interface{}{document}
+	collect:
+		for len(batch) < cap(q.documents) {
+			select {
+			case document, ok := <-q.documents:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, document)
+			default:
+				break collect
+			}
+		}
+		q.flush(batch)
+	}
+}
+
+// flush indexes the given batch, adding its result to the total
+func (q *Queue) flush(batch []interface{}) {
+	result, err := q.indexer.IndexWithResult(q.ctx, batch, q.opts)
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	addResult(&q.result, result)
+	if err != nil {
+		q.errs = append(q.errs, err)
+	}
+}
+
+// Close stops accepting documents, waits for the queued ones to be indexed and returns the sum of the indexing
+// results along with the errors of the failed batches
+func (q *Queue) Close() (IndexingResult, error) {
+	q.closeLock.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.documents)
+	}
+	q.closeLock.Unlock()
+	<-q.done
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	result := q.result
+	result.Stats = copyStats(q.result.Stats)
+	result.Duration = time.Since(q.start)
+	if len(q.errs) > 0 {
+		return result, append(multiError(nil), q.errs...)
+	}
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// blockingIndexer mock indexer blocking every indexing call until released
+type blockingIndexer struct {
+	mockChildIndexer
+	indexing chan []interface{}
+	release  chan struct{}
+}
+
+func (b *blockingIndexer) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	b.indexing <- documents
+	<-b.release
+	return b.mockChildIndexer.IndexWithResult(ctx, documents, opts)
+}
+
+var _ = Describe("Tests for queue.go", func() {
+	var indexer *blockingIndexer
+	var queue *Queue
+	BeforeEach(func() {
+		indexer = &blockingIndexer{indexing: make(chan []interface{}, 10), release: make(chan struct{})}
+		var err error
+		queue, err = NewQueue(context.Background(), indexer, 2, IndexingOpts{})
+		Expect(err).To(BeNil())
+	})
+
+	It("Returns err invalid queue size", func() {
+		_, err := NewQueue(context.Background(), indexer, 0, IndexingOpts{})
+		Expect(err).To(MatchError("invalid queue size: 0"))
+	})
+
+	It("Blocks Submit while the queue is full until the queued documents are flushed", func() {
+		Expect(queue.Submit(context.Background(), 1)).To(Succeed())
+		Eventually(indexer.indexing).Should(Receive(Equal([]interface{}{1})))
+		Expect(queue.Submit(context.Background(), 2)).To(Succeed())
+		Expect(queue.Submit(context.Background(), 3)).To(Succeed())
+		submitted := make(chan error)
+		go func() {
+			submitted <- queue.Submit(context.Background(), 4)
+		}()
+		Consistently(submitted, 100*time.Millisecond).ShouldNot(Receive())
+		indexer.release <- struct{}{}
+		Eventually(submitted).Should(Receive(BeNil()))
+		Eventually(indexer.indexing).Should(Receive(Equal([]interface{}{2, 3})))
+		close(indexer.release)
+		result, err := queue.Close()
+		Expect(err).To(BeNil())
+		Expect(result.Created).To(Equal(4))
+		Expect(indexer.documents).To(Equal([]interface{}{1, 2, 3, 4}))
+	})
+
+	It("Gives up submitting when the context is done", func() {
+		Expect(queue.Submit(context.Background(), 1)).To(Succeed())
+		Eventually(indexer.indexing).Should(Receive())
+		Expect(queue.Submit(context.Background(), 2)).To(Succeed())
+		Expect(queue.Submit(context.Background(), 3)).To(Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(queue.Submit(ctx, 4)).To(MatchError(context.DeadlineExceeded))
+		close(indexer.release)
+		result, err := queue.Close()
+		Expect(err).To(BeNil())
+		Expect(result.Created).To(Equal(3))
+	})
+
+	It("Returns the indexing errors when closing", func() {
+		close(indexer.release)
+		indexer.err = fmt.Errorf("cluster unavailable: %w", ErrDocumentsFailed)
+		Expect(queue.Submit(context.Background(), 1)).To(Succeed())
+		_, err := queue.Close()
+		Expect(err).To(MatchError("cluster unavailable: " + ErrDocumentsFailed.Error()))
+		Expect(errors.Is(err, ErrDocumentsFailed)).To(BeTrue())
+		Expect(queue.Submit(context.Background(), 2)).To(MatchError(ErrQueueClosed))
+	})
+})
@@ -0,0 +1,213 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisIndexer = "redis"
+
+// redisDocumentField field of the stream entries holding the JSON encoded document
+const redisDocumentField = "document"
+
+// redisIDField field of the stream entries holding the document ID
+const redisIDField = "id"
+
+// Redis Redis Streams instance
+type Redis struct {
+	stream    string
+	client    *redis.Client
+	maxLen    int64
+	flushDocs int
+	logger    Logger
+}
+
+// Init function
+func init() {
+	Register(redisIndexer, func() Indexer { return &Redis{} })
+}
+
+// Returns new indexer for Redis Streams, adding the documents to the Index stream of the Servers[0] server, given
+// either as a redis:// or rediss:// URL or as a host:port address
+func (r *Redis) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if indexerConfig.StreamMaxLen < 0 {
+		return fmt.Errorf("invalid stream maximum length: %d", indexerConfig.StreamMaxLen)
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	options := &redis.Options{Addr: indexerConfig.Servers[0]}
+	if strings.Contains(indexerConfig.Servers[0], "://") {
+		var err error
+		if options, err = redis.ParseURL(indexerConfig.Servers[0]); err != nil {
+			return fmt.Errorf("invalid Redis URL: %s", err)
+		}
+	}
+	if indexerConfig.Username != "" {
+		options.Username = indexerConfig.Username
+	}
+	if indexerConfig.Password != "" {
+		options.Password = indexerConfig.Password
+	}
+	if indexerConfig.InsecureSkipVerify || indexerConfig.CACertPath != "" {
+		tlsClientConfig, err := tlsConfig(indexerConfig)
+		if err != nil {
+			return err
+		}
+		options.TLSConfig = tlsClientConfig
+	}
+	r.client = redis.NewClient(options)
+	r.stream = indexerConfig.Index
+	r.maxLen = indexerConfig.StreamMaxLen
+	r.flushDocs = indexerConfig.FlushDocs
+	r.logger = loggerOrNop(indexerConfig.Logger)
+	if indexerConfig.SkipHealthCheck {
+		return nil
+	}
+	timeout := indexerConfig.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := r.Ping(ctx); err != nil {
+		r.client.Close()
+		return err
+	}
+	return nil
+}
+
+// Index adds the documents to the configured stream
+func (r *Redis) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := r.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult adds every document to the configured stream with XADD, as an entry holding its ID and its JSON
+// encoding, and returns the indexing result. The entries are pipelined up to FlushDocs at a time, the stream being
+// trimmed to its maximum length, if any, as they're added. The entries failing to be added are counted as failed
+func (r *Redis) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, r.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	pipe := r.client.Pipeline()
+	var added []*redis.StringCmd
+	var ids []string
+	flush := func() error {
+		if len(added) == 0 {
+			return nil
+		}
+		// The failed entries are reported by their commands
+		if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+			return fmt.Errorf("Unexpected Redis error: %s", err)
+		}
+		for i, cmd := range added {
+			if err := cmd.Err(); err != nil {
+				r.logger.Errorf("Error adding document %s: %s", ids[i], err)
+				indexerStats["failed"]++
+			} else {
+				indexerStats["created"]++
+			}
+		}
+		added, ids = nil, nil
+		return nil
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		docId := documentID(j, opts.DocumentIDField, docHashKey)
+		reportDocumentID(opts, document, docId)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		added = append(added, pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: r.stream,
+			MaxLen: r.maxLen,
+			Values: []interface{}{redisIDField, docId, redisDocumentField, string(j)},
+		}))
+		ids = append(ids, docId)
+		if r.flushDocs > 0 && len(added) == r.flushDocs {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if err := flush(); err != nil {
+		return IndexingResult{}, err
+	}
+	result := newIndexingResult(indexerStats, redundantSkipped, time.Since(start))
+	if opts.FailFast {
+		return result, failedDocumentsError(result)
+	}
+	return result, nil
+}
+
+// Ping checks the Redis server answers PING, telling the rejected credentials apart
+func (r *Redis) Ping(ctx context.Context) error {
+	err := r.client.Ping(ctx).Err()
+	if err == nil {
+		return nil
+	}
+	if strings.HasPrefix(err.Error(), "NOAUTH") || strings.HasPrefix(err.Error(), "WRONGPASS") {
+		return pingFailed("Redis", ErrUnauthorized, err)
+	}
+	return pingFailed("Redis", ErrUnreachable, err)
+}
+
+// Close closes the Redis client
+func (r *Redis) Close() error {
+	if r.client != nil {
+		return r.client.Close()
+	}
+	return nil
+}
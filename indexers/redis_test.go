@@ -0,0 +1,162 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for redis.go", func() {
+	var server *miniredis.Miniredis
+	BeforeEach(func() {
+		server = miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+	})
+
+	Context("Tests for New()", func() {
+		var indexerConfig IndexerConfig
+		var indexer Redis
+		BeforeEach(func() {
+			indexerConfig = IndexerConfig{Type: "redis",
+				Servers: []string{server.Addr()},
+				Index:   "go-commons-test",
+			}
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
+		It("Returns err invalid stream maximum length", func() {
+			indexerConfig.StreamMaxLen = -1
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("invalid stream maximum length: -1")))
+		})
+
+		It("Connects to the server given as URL", func() {
+			indexerConfig.Servers = []string{"redis://" + server.Addr() + "/0"}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.Close()).To(Succeed())
+		})
+
+		It("Returns err when the credentials are rejected", func() {
+			server.RequireAuth("secret")
+			indexerConfig.Password = "wrong"
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError(ErrUnauthorized))
+		})
+
+		It("Returns err when the server can't be reached", func() {
+			indexerConfig.Servers = []string{"127.0.0.1:1"}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError(ErrUnreachable))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer Redis
+		BeforeEach(func() {
+			err := indexer.New(IndexerConfig{Servers: []string{server.Addr()}, Index: "go-commons-test"})
+			Expect(err).To(BeNil())
+			DeferCleanup(indexer.Close)
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Adds every document to the stream along with its ID", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			entries, err := server.Stream("go-commons-test")
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(3))
+			Expect(entries[1].Values).To(HaveLen(4))
+			Expect(entries[1].Values[0:3]).To(Equal([]string{"id", hashDocument([]byte("42")), "document"}))
+			Expect(entries[1].Values[3]).To(MatchJSON(`{"document":42,"metricName":"placeholder"}`))
+		})
+
+		It("Trims the stream to its maximum length", func() {
+			indexer.maxLen = 2
+			indexer.flushDocs = 1
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			entries, err := server.Stream("go-commons-test")
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Values[1]).To(Equal(hashDocument([]byte("42"))))
+		})
+
+		It("Counts the entries failing to be added", func() {
+			server.Set("go-commons-test", "not a stream")
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Failed).To(Equal(3))
+			testcase.opts.FailFast = true
+			_, err = indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(ErrDocumentsFailed))
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			entries, err := server.Stream("go-commons-test")
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer Redis
+		BeforeEach(func() {
+			Expect(indexer.New(IndexerConfig{Type: "redis", Servers: []string{server.Addr()}, Index: "go-commons-test"})).To(BeNil())
+			DeferCleanup(indexer.Close)
+		})
+
+		It("Answers PING", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Returns ErrUnauthorized when authentication is required", func() {
+			server.RequireAuth("secret")
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnauthorized))
+		})
+
+		It("Returns ErrUnreachable when the server is down", func() {
+			server.Close()
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package indexers_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloud-bulldozer/go-commons/indexers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeIndexer third-party indexer implemented outside of the package
+type fakeIndexer struct {
+	index     string
+	documents []interface{}
+}
+
+func (f *fakeIndexer) New(indexerConfig indexers.IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return errors.New("index name not specified")
+	}
+	f.index = indexerConfig.Index
+	return nil
+}
+
+func (f *fakeIndexer) Index(ctx context.Context, documents []interface{}, opts indexers.IndexingOpts) (string, error) {
+	result, err := f.IndexWithResult(ctx, documents, opts)
+	return result.String(), err
+}
+
+func (f *fakeIndexer) IndexWithResult(ctx context.Context, documents []interface{}, opts indexers.IndexingOpts) (indexers.IndexingResult, error) {
+	f.documents = append(f.documents, documents...)
+	return indexers.IndexingResult{Created: len(documents)}, nil
+}
+
+func (f *fakeIndexer) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeIndexer) Close() error {
+	return nil
+}
+
+func init() {
+	indexers.Register("fake", func() indexers.Indexer { return &fakeIndexer{} })
+}
+
+var _ = Describe("Tests for Register()", func() {
+	It("Resolves the registered indexer through NewIndexer", func() {
+		Expect(indexers.RegisteredIndexers()).To(ContainElement("fake"))
+		indexer, err := indexers.NewIndexer(indexers.IndexerConfig{Type: "fake", Index: "go-commons-test"})
+		Expect(err).To(BeNil())
+		Expect(indexer.(*fakeIndexer).index).To(Equal("go-commons-test"))
+		result, err := indexer.IndexWithResult(context.Background(), []interface{}{1, 2}, indexers.IndexingOpts{})
+		Expect(err).To(BeNil())
+		Expect(result.Created).To(Equal(2))
+	})
+
+	It("Returns the error of the registered indexer", func() {
+		_, err := indexers.NewIndexer(indexers.IndexerConfig{Type: "fake"})
+		Expect(err).To(MatchError("index name not specified"))
+	})
+
+	It("Panics on duplicate registration", func() {
+		Expect(func() {
+			indexers.Register("fake", func() indexers.Indexer { return &fakeIndexer{} })
+		}).To(PanicWith("indexers: Register called twice for indexer fake"))
+		Expect(func() {
+			indexers.Register(string(indexers.ElasticIndexer), func() indexers.Indexer { return &fakeIndexer{} })
+		}).To(Panic())
+	})
+})
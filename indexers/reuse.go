@@ -0,0 +1,111 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// reusedFlushInterval flush interval of the bulk indexers reused across the indexing calls. As the bulk indexers
+// can only be flushed on demand by closing them, the indexing calls wait for their documents to be flushed on interval
+const reusedFlushInterval = time.Millisecond
+
+// reusedBulkKey identifies a reused bulk indexer by the settings its bulk requests are sent with
+type reusedBulkKey struct {
+	index    string
+	refresh  string
+	pipeline string
+}
+
+// bulkReuse state of the bulk indexers reused across the indexing calls. Its lock is held by the indexing call
+// using them, so the bulk indexer statistics changing meanwhile are the ones of its documents
+type bulkReuse struct {
+	sync.Mutex
+	flushLock sync.Mutex
+	inflight  int
+	// flushed closed and replaced whenever a flush ends
+	flushed   chan struct{}
+	latencies flushLatencies
+}
+
+// newBulkReuse returns the bulk indexer reuse state of the given configuration, nil when reuse is disabled
+func newBulkReuse(indexerConfig IndexerConfig) (*bulkReuse, error) {
+	if !indexerConfig.ReuseBulkIndexer {
+		return nil, nil
+	}
+	if indexerConfig.FlushInterval > 0 {
+		return nil, fmt.Errorf("reusing the bulk indexer isn't supported when flushing on interval")
+	}
+	if indexerConfig.FlushDocs > 0 {
+		return nil, fmt.Errorf("reusing the bulk indexer isn't supported along with a number of documents per flush")
+	}
+	return &bulkReuse{flushed: make(chan struct{})}, nil
+}
+
+// hooks returns flush hooks calling the given ones, recording the flush durations and tracking the flushes in progress
+func (r *bulkReuse) hooks(onStart func(context.Context) context.Context, onEnd func(context.Context)) (func(context.Context) context.Context, func(context.Context)) {
+	onStart, onEnd = r.latencies.hooks(onStart, onEnd)
+	start := func(ctx context.Context) context.Context {
+		r.flushLock.Lock()
+		r.inflight++
+		r.flushLock.Unlock()
+		return onStart(ctx)
+	}
+	end := func(ctx context.Context) {
+		onEnd(ctx)
+		r.flushLock.Lock()
+		defer r.flushLock.Unlock()
+		r.inflight--
+		close(r.flushed)
+		r.flushed = make(chan struct{})
+	}
+	return start, end
+}
+
+// wait waits until no flush is in progress and done reports true, or until ctx is done. The item callbacks being
+// called during the flushes, they're all done once done reports the documents flushed
+func (r *bulkReuse) wait(ctx context.Context, done func() bool) error {
+	for {
+		r.flushLock.Lock()
+		if r.inflight == 0 && done() {
+			r.flushLock.Unlock()
+			return nil
+		}
+		flushed := r.flushed
+		r.flushLock.Unlock()
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// bulkStatsDelta returns the statistics of a bulk indexer accumulated since the start ones
+func bulkStatsDelta(stats, start BulkStats) BulkStats {
+	return BulkStats{
+		NumAdded:    stats.NumAdded - start.NumAdded,
+		NumFlushed:  stats.NumFlushed - start.NumFlushed,
+		NumFailed:   stats.NumFailed - start.NumFailed,
+		NumIndexed:  stats.NumIndexed - start.NumIndexed,
+		NumCreated:  stats.NumCreated - start.NumCreated,
+		NumUpdated:  stats.NumUpdated - start.NumUpdated,
+		NumDeleted:  stats.NumDeleted - start.NumDeleted,
+		NumRequests: stats.NumRequests - start.NumRequests,
+	}
+}
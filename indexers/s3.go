@@ -0,0 +1,212 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const s3Indexer = "s3"
+
+// s3Uploader uploads objects to a bucket
+type s3Uploader interface {
+	PutObjectWithContext(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error)
+	HeadBucketWithContext(aws.Context, *s3.HeadBucketInput, ...request.Option) (*s3.HeadBucketOutput, error)
+}
+
+// S3 object storage indexer instance
+type S3 struct {
+	bucket     string
+	prefix     string
+	flushBytes int
+	uploader   s3Uploader
+	client     *http.Client
+}
+
+// Init function
+func init() {
+	Register(s3Indexer, func() Indexer { return &S3{} })
+}
+
+// Returns new indexer for S3, Servers[0] being the endpoint of S3-compatible stores, the AWS one being used when
+// no server is given
+func (o *S3) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if indexerConfig.Bucket == "" {
+		return fmt.Errorf("bucket not specified")
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	o.client = &http.Client{Transport: transport}
+	awsConfig := aws.Config{HTTPClient: o.client}
+	if indexerConfig.Region != "" {
+		awsConfig.Region = aws.String(indexerConfig.Region)
+	}
+	if len(indexerConfig.Servers) > 0 {
+		// S3-compatible stores seldom support virtual-hosted-style bucket addressing
+		awsConfig.Endpoint = aws.String(indexerConfig.Servers[0])
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	if indexerConfig.Username != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(indexerConfig.Username, indexerConfig.Password, "")
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{Config: awsConfig, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return fmt.Errorf("error creating the AWS session: %s", err)
+	}
+	o.uploader = s3.New(sess)
+	o.bucket = indexerConfig.Bucket
+	o.prefix = indexerConfig.Index
+	o.flushBytes = indexerConfig.FlushBytes
+	if o.flushBytes <= 0 {
+		o.flushBytes = defaultFlushBytes
+	}
+	return nil
+}
+
+// Index uploads the documents as newline-delimited JSON objects
+func (o *S3) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := o.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult uploads the documents as newline-delimited JSON objects of up to flushBytes, named after
+// the prefix, the metric name and the indexing time, and returns the indexing result
+func (o *S3) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, o.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var object bytes.Buffer
+	objectDocs := 0
+	part := 0
+	upload := func() error {
+		if objectDocs == 0 {
+			return nil
+		}
+		if err := o.upload(ctx, o.objectKey(opts.MetricName, start, part), object.Bytes()); err != nil {
+			return err
+		}
+		indexerStats["created"] += objectDocs
+		object.Reset()
+		objectDocs = 0
+		part++
+		return nil
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		if object.Len()+len(j)+1 > o.flushBytes {
+			if err := upload(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		object.Write(j)
+		object.WriteByte('\n')
+		objectDocs++
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if err := upload(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// objectKey returns the key of the given part of the documents indexed at t
+func (o *S3) objectKey(metricName string, t time.Time, part int) string {
+	return path.Join(o.prefix, metricName, fmt.Sprintf("%s-%d.ndjson", t.Format("20060102T150405.000000000Z"), part))
+}
+
+// upload uploads the object to the bucket
+func (o *S3) upload(ctx context.Context, key string, object []byte) error {
+	_, err := o.uploader.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(o.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(object),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	if err != nil {
+		return fmt.Errorf("Unexpected S3 error: %s", err)
+	}
+	return nil
+}
+
+// Ping checks the bucket is reachable, the rejected credentials and missing bucket being told apart
+func (o *S3) Ping(ctx context.Context) error {
+	_, err := o.uploader.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(o.bucket)})
+	if err == nil {
+		return nil
+	}
+	var failure awserr.RequestFailure
+	if errors.As(err, &failure) && failure.StatusCode() > 0 {
+		if statusErr := pingStatus("S3", failure.StatusCode()); errors.Is(statusErr, ErrUnauthorized) {
+			return statusErr
+		}
+		return pingFailed("S3", ErrUnhealthy, err)
+	}
+	return pingFailed("S3", ErrUnreachable, err)
+}
+
+// Close closes the idle connections of the S3 client
+func (o *S3) Close() error {
+	if o.client != nil {
+		o.client.CloseIdleConnections()
+	}
+	return nil
+}
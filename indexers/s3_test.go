@@ -0,0 +1,174 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// mockS3Uploader records the uploaded objects
+type mockS3Uploader struct {
+	keys    []string
+	objects []string
+	err     error
+	headErr error
+}
+
+func (m *mockS3Uploader) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.keys = append(m.keys, aws.StringValue(input.Key))
+	m.objects = append(m.objects, string(body))
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3Uploader) HeadBucketWithContext(ctx aws.Context, input *s3.HeadBucketInput, opts ...request.Option) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, m.headErr
+}
+
+var _ = Describe("Tests for s3.go", func() {
+	Context("Tests for New()", func() {
+		var indexerConfig IndexerConfig
+		var indexer S3
+		BeforeEach(func() {
+			indexerConfig = IndexerConfig{Type: "s3",
+				Servers:  []string{"http://localhost:9000"},
+				Index:    "go-commons-test",
+				Bucket:   "archive",
+				Region:   "us-east-1",
+				Username: "access",
+				Password: "secret",
+			}
+		})
+
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.bucket).To(Equal("archive"))
+			Expect(indexer.flushBytes).To(Equal(defaultFlushBytes))
+			Expect(indexer.Close()).To(BeNil())
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no bucket", func() {
+			indexerConfig.Bucket = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("bucket not specified")))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer S3
+		var uploader *mockS3Uploader
+		BeforeEach(func() {
+			uploader = &mockS3Uploader{}
+			indexer = S3{bucket: "archive", prefix: "go-commons-test", flushBytes: defaultFlushBytes, uploader: uploader}
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Uploads the documents in a single NDJSON object", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(uploader.keys).To(HaveLen(1))
+			Expect(uploader.keys[0]).To(HavePrefix("go-commons-test/placeholder/"))
+			Expect(uploader.keys[0]).To(HaveSuffix("-0.ndjson"))
+			lines := strings.Split(strings.TrimSpace(uploader.objects[0]), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[2]).To(MatchJSON(`{"key1":"value1","key2":123,"metricName":"placeholder"}`))
+		})
+
+		It("Splits the documents in objects of up to flushBytes", func() {
+			indexer.flushBytes = 60
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(len(uploader.keys)).To(BeNumerically(">", 1))
+			Expect(uploader.keys[1]).To(HaveSuffix("-1.ndjson"))
+		})
+
+		It("Doesn't upload any object in dry-run mode", func() {
+			testcase.opts.DryRun = true
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 3))
+			Expect(uploader.keys).To(BeEmpty())
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(result.Created).To(Equal(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Returns err when the upload fails", func() {
+			uploader.err = errors.New("NoSuchBucket")
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeEquivalentTo(errors.New("Unexpected S3 error: NoSuchBucket")))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer S3
+		var uploader *mockS3Uploader
+		BeforeEach(func() {
+			uploader = &mockS3Uploader{}
+			indexer = S3{bucket: "archive", uploader: uploader}
+		})
+
+		It("Succeeds when the bucket is reachable", func() {
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Tells the rejected credentials apart from the missing bucket", func() {
+			uploader.headErr = awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), http.StatusForbidden, "")
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnauthorized))
+			uploader.headErr = awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), http.StatusNotFound, "")
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnhealthy))
+		})
+
+		It("Returns err unreachable when the request fails", func() {
+			uploader.headErr = awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("connection refused"))
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
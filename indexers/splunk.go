@@ -0,0 +1,188 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const splunkIndexer = "splunk"
+
+// splunkEvent HTTP Event Collector event envelope
+type splunkEvent struct {
+	Time       float64         `json:"time"`
+	Index      string          `json:"index"`
+	SourceType string          `json:"sourcetype"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// Splunk HTTP Event Collector indexer instance
+type Splunk struct {
+	url         string
+	client      *http.Client
+	token       string
+	index       string
+	flushBytes  int
+	contentType string
+}
+
+// Init function
+func init() {
+	Register(splunkIndexer, func() Indexer { return &Splunk{} })
+}
+
+// Returns new indexer for Splunk
+func (s *Splunk) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if indexerConfig.Token == "" {
+		return fmt.Errorf("HEC token not specified")
+	}
+	transport, err := newTransport(indexerConfig)
+	if err != nil {
+		return err
+	}
+	s.url = strings.TrimSuffix(indexerConfig.Servers[0], "/") + "/services/collector"
+	s.client = &http.Client{Transport: transport}
+	s.token = indexerConfig.Token
+	s.contentType = contentType(indexerConfig)
+	s.index = indexerConfig.Index
+	s.flushBytes = indexerConfig.FlushBytes
+	if s.flushBytes <= 0 {
+		s.flushBytes = defaultFlushBytes
+	}
+	return nil
+}
+
+// Index sends the documents as events to the HTTP Event Collector
+func (s *Splunk) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := s.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult sends the documents as events to the HTTP Event Collector and returns the indexing result.
+// Events are batched in requests of up to flushBytes
+func (s *Splunk) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, s.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var body bytes.Buffer
+	batchEvents := 0
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		event, err := json.Marshal(splunkEvent{
+			Time:       float64(time.Now().UnixNano()) / float64(time.Second),
+			Index:      s.index,
+			SourceType: "_json",
+			Event:      j,
+		})
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if body.Len() > 0 && body.Len()+len(event) > s.flushBytes {
+			if err := s.send(ctx, body.Bytes()); err != nil {
+				return IndexingResult{}, err
+			}
+			indexerStats["created"] += batchEvents
+			body.Reset()
+			batchEvents = 0
+		}
+		body.Write(event)
+		batchEvents++
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	if batchEvents > 0 {
+		if err := s.send(ctx, body.Bytes()); err != nil {
+			return IndexingResult{}, err
+		}
+		indexerStats["created"] += batchEvents
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// send posts the batched events to the HTTP Event Collector
+func (s *Splunk) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unexpected Splunk error: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected Splunk response %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Ping checks the HTTP Event Collector is reachable and healthy through its health endpoint
+func (s *Splunk) Ping(ctx context.Context) error {
+	return pingHTTP(ctx, "Splunk", s.client, s.url+"/health", func(req *http.Request) {
+		req.Header.Set("Authorization", "Splunk "+s.token)
+	})
+}
+
+// Close closes the idle connections of the Splunk client
+func (s *Splunk) Close() error {
+	if s.client != nil {
+		s.client.CloseIdleConnections()
+	}
+	return nil
+}
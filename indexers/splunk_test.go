@@ -0,0 +1,189 @@
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// decodeSplunkEvents decodes the concatenated events of a HEC request body
+func decodeSplunkEvents(body []byte) []map[string]interface{} {
+	var events []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for decoder.More() {
+		var event map[string]interface{}
+		Expect(decoder.Decode(&event)).To(Succeed())
+		events = append(events, event)
+	}
+	return events
+}
+
+var _ = Describe("Tests for splunk.go", func() {
+	var indexerConfig IndexerConfig
+	var indexer Splunk
+	var server *httptest.Server
+	var requests []*http.Request
+	var batches [][]map[string]interface{}
+	BeforeEach(func() {
+		requests = nil
+		batches = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			requests = append(requests, r)
+			batches = append(batches, decodeSplunkEvents(body))
+			_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+		}))
+		indexerConfig = IndexerConfig{Type: "splunk",
+			Servers: []string{server.URL},
+			Index:   "go-commons-test",
+			Token:   "hec-token",
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Tests for New()", func() {
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.url).To(Equal(server.URL + "/services/collector"))
+			Expect(indexer.flushBytes).To(Equal(defaultFlushBytes))
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("index name not specified"))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("servers not specified"))
+		})
+
+		It("Returns err no token", func() {
+			indexerConfig.Token = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError("HEC token not specified"))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		BeforeEach(func() {
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+		})
+
+		It("Sends the documents as HEC events", func() {
+			documents := []interface{}{
+				map[string]interface{}{"uuid": "1234", "value": 2.5},
+				42,
+			}
+			before := time.Now()
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{MetricName: "podLatency"})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(2))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/services/collector"))
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("Splunk hec-token"))
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(batches[0]).To(HaveLen(2))
+			event := batches[0][0]
+			Expect(event).To(HaveKeyWithValue("index", "go-commons-test"))
+			Expect(event).To(HaveKeyWithValue("sourcetype", "_json"))
+			Expect(event).To(HaveKeyWithValue("time", BeNumerically("~", float64(before.Unix()), 5)))
+			Expect(event).To(HaveKeyWithValue("event", map[string]interface{}{"uuid": "1234", "value": 2.5, "metricName": "podLatency"}))
+			Expect(batches[0][1]).To(HaveKeyWithValue("event", map[string]interface{}{"document": 42.0, "metricName": "podLatency"}))
+		})
+
+		It("Sends the configured content type", func() {
+			indexerConfig.ContentType = "application/x-ndjson"
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			_, err := indexer.Index(context.Background(), []interface{}{map[string]interface{}{"value": 1}}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/x-ndjson"))
+		})
+
+		It("Batches the events up to the flush bytes", func() {
+			indexerConfig.FlushBytes = 200
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			var documents []interface{}
+			for i := 0; i < 5; i++ {
+				documents = append(documents, map[string]interface{}{"value": i})
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(5))
+			Expect(len(requests)).To(BeNumerically(">", 1))
+			events := 0
+			for _, batch := range batches {
+				events += len(batch)
+			}
+			Expect(events).To(Equal(5))
+		})
+
+		It("Skips the redundant documents", func() {
+			document := map[string]interface{}{"value": 1}
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{document, document}, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Skipped).To(Equal(1))
+		})
+
+		It("Doesn't send any event in dry-run mode", func() {
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{1, 2}, IndexingOpts{DryRun: true})
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 2))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, IndexingOpts{})
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Returns err when the collector rejects the events", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"text":"Invalid token","code":4}`, http.StatusForbidden)
+			})
+			_, err := indexer.Index(context.Background(), []interface{}{1}, IndexingOpts{})
+			Expect(err).To(MatchError(`Unexpected Splunk response 403: {"text":"Invalid token","code":4}`))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Checks the health endpoint with the token", func() {
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/services/collector/health"))
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("Splunk hec-token"))
+		})
+
+		It("Returns ErrUnauthorized when the token is rejected", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnauthorized))
+		})
+
+		It("Returns ErrUnhealthy when the collector is unhealthy", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+			Expect(indexer.New(indexerConfig)).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnhealthy))
+		})
+	})
+})
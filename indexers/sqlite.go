@@ -0,0 +1,207 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteIndexer = "sqlite"
+
+// sqliteDriver database/sql driver name of modernc.org/sqlite, the pure-Go SQLite driver not requiring cgo
+const sqliteDriver = "sqlite"
+
+// SQLite database file instance
+type SQLite struct {
+	table     string
+	db        *sql.DB
+	flushDocs int
+}
+
+// Init function
+func init() {
+	Register(sqliteIndexer, func() Indexer { return &SQLite{} })
+}
+
+// Returns new indexer for SQLite, Servers[0] being the path of the database file. The table, holding the documents
+// as JSON text keyed by their ID, is created when it doesn't exist unless index creation is disabled
+func (s *SQLite) New(indexerConfig IndexerConfig) error {
+	if indexerConfig.Index == "" {
+		return fmt.Errorf("index name not specified")
+	}
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	if indexerConfig.FlushDocs < 0 {
+		return fmt.Errorf("invalid number of documents per flush: %d", indexerConfig.FlushDocs)
+	}
+	db, err := sql.Open(sqliteDriver, indexerConfig.Servers[0])
+	if err != nil {
+		return fmt.Errorf("error opening SQLite database %s: %s", indexerConfig.Servers[0], err)
+	}
+	// SQLite allows a single writer at a time
+	db.SetMaxOpenConns(1)
+	s.table = quoteSQLiteTable(indexerConfig.Index)
+	s.db = db
+	s.flushDocs = indexerConfig.FlushDocs
+	if indexerConfig.SkipIndexCreation {
+		return nil
+	}
+	return s.createTable(context.Background())
+}
+
+// createTable creates the table when it doesn't exist
+func (s *SQLite) createTable(ctx context.Context) error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, document TEXT NOT NULL)", s.table)
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("error creating table %s on SQLite: %s", s.table, err)
+	}
+	return nil
+}
+
+// Index upserts the documents into the configured table
+func (s *SQLite) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := s.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult upserts the documents into the configured table and returns the indexing result.
+// Documents are keyed by their ID and upserted in transactions of up to flushDocs documents, all of them by default
+func (s *SQLite) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, s.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var tx *sql.Tx
+	txDocs := 0
+	batchStats := make(map[string]int)
+	commit := func() error {
+		if tx == nil {
+			return nil
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("Unexpected SQLite error: %s", err)
+		}
+		for result, count := range batchStats {
+			indexerStats[result] += count
+		}
+		tx, txDocs, batchStats = nil, 0, make(map[string]int)
+		return nil
+	}
+	rollback := func(err error) (IndexingResult, error) {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+		return IndexingResult{}, err
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return rollback(fmt.Errorf("Cannot encode document %s: %s", document, err))
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		docId := documentID(j, opts.DocumentIDField, docHashKey)
+		reportDocumentID(opts, document, docId)
+		if j, err = decorateDocument(j, fields); err != nil {
+			return rollback(err)
+		}
+		if tx == nil {
+			if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+				return IndexingResult{}, fmt.Errorf("Unexpected SQLite error: %s", err)
+			}
+		}
+		inserted, err := s.upsert(ctx, tx, docId, j)
+		if err != nil {
+			return rollback(fmt.Errorf("Unexpected SQLite error: %s", err))
+		}
+		if inserted {
+			batchStats["created"]++
+		} else {
+			batchStats["updated"]++
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		if txDocs++; txDocs == s.flushDocs {
+			if err := commit(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+	}
+	if err := commit(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// upsert inserts the document in the transaction, updating it when its ID already exists, reporting whether the
+// row was inserted
+func (s *SQLite) upsert(ctx context.Context, tx *sql.Tx, id string, document []byte) (bool, error) {
+	r, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT OR IGNORE INTO %s (id, document) VALUES (?, ?)", s.table), id, string(document))
+	if err != nil {
+		return false, err
+	}
+	if inserted, err := r.RowsAffected(); err != nil || inserted > 0 {
+		return inserted > 0, err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET document = ? WHERE id = ?", s.table), string(document), id)
+	return false, err
+}
+
+// quoteSQLiteTable quotes the given table name
+func quoteSQLiteTable(table string) string {
+	return `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+}
+
+// Ping checks the SQLite database can be queried
+func (s *SQLite) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return pingFailed("SQLite", ErrUnreachable, err)
+	}
+	return nil
+}
+
+// Close closes the SQLite database
+func (s *SQLite) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
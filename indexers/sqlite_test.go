@@ -0,0 +1,182 @@
+package indexers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// countSQLiteRows returns the number of rows of the go-commons-test table
+func countSQLiteRows(db *sql.DB) int {
+	var rows int
+	Expect(db.QueryRow(`SELECT COUNT(*) FROM "go-commons-test"`).Scan(&rows)).To(Succeed())
+	return rows
+}
+
+var _ = Describe("Tests for sqlite.go", func() {
+	var indexerConfig IndexerConfig
+	var indexer SQLite
+	BeforeEach(func() {
+		indexer = SQLite{}
+		indexerConfig = IndexerConfig{Type: "sqlite",
+			Servers: []string{filepath.Join(GinkgoT().TempDir(), "go-commons-test.db")},
+			Index:   "go-commons-test",
+		}
+	})
+
+	AfterEach(func() {
+		Expect(indexer.Close()).To(Succeed())
+	})
+
+	Context("Tests for New()", func() {
+		It("Returns nil as error", func() {
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeNil())
+			Expect(indexer.table).To(Equal(`"go-commons-test"`))
+			Expect(countSQLiteRows(indexer.db)).To(BeZero())
+		})
+
+		It("Returns err no index name", func() {
+			indexerConfig.Index = ""
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("index name not specified")))
+		})
+
+		It("Returns err no servers", func() {
+			indexerConfig.Servers = []string{}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
+		It("Returns err when the database can't be opened", func() {
+			indexerConfig.Servers = []string{filepath.Join(GinkgoT().TempDir(), "missing", "go-commons-test.db")}
+			err := indexer.New(indexerConfig)
+			Expect(err).To(MatchError(HavePrefix(`error creating table "go-commons-test" on SQLite: `)))
+		})
+
+		It("Creates the table with a quoted name", func() {
+			indexerConfig.Index = `go-commons"test`
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+			var name string
+			Expect(indexer.db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table'").Scan(&name)).To(Succeed())
+			Expect(name).To(Equal(`go-commons"test`))
+		})
+
+		It("Skips the table creation", func() {
+			indexerConfig.SkipIndexCreation = true
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+			var tables int
+			Expect(indexer.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables)).To(Succeed())
+			Expect(tables).To(BeZero())
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		BeforeEach(func() {
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+		})
+
+		It("Writes the documents keyed by their ID", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(countSQLiteRows(indexer.db)).To(Equal(3))
+			var document string
+			Expect(indexer.db.QueryRow(`SELECT document FROM "go-commons-test" WHERE id = ?`, hashDocument([]byte("42"))).Scan(&document)).To(Succeed())
+			Expect(document).To(MatchJSON(`{"document":42,"metricName":"placeholder"}`))
+		})
+
+		It("Upserts the documents indexed twice", func() {
+			_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			testcase.opts.MetricName = "updated"
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(BeZero())
+			Expect(result.Updated).To(Equal(3))
+			Expect(countSQLiteRows(indexer.db)).To(Equal(3))
+			var document string
+			Expect(indexer.db.QueryRow(`SELECT document FROM "go-commons-test" WHERE id = ?`, hashDocument([]byte("42"))).Scan(&document)).To(Succeed())
+			Expect(document).To(MatchJSON(`{"document":42,"metricName":"updated"}`))
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(result.Created).To(Equal(3))
+			Expect(countSQLiteRows(indexer.db)).To(Equal(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+
+		It("Rolls the transaction back when a document fails", func() {
+			testcase.documents = append(testcase.documents, make(chan int))
+			_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(MatchError(HavePrefix("Cannot encode document")))
+			Expect(countSQLiteRows(indexer.db)).To(BeZero())
+		})
+
+		Context("Committing every flushDocs documents", func() {
+			BeforeEach(func() {
+				indexerConfig.FlushDocs = 2
+			})
+
+			It("Keeps the committed transactions when a document fails", func() {
+				testcase.documents = append(testcase.documents, make(chan int))
+				_, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(MatchError(HavePrefix("Cannot encode document")))
+				Expect(countSQLiteRows(indexer.db)).To(Equal(2))
+			})
+		})
+
+		Context("Without table", func() {
+			BeforeEach(func() {
+				indexerConfig.SkipIndexCreation = true
+			})
+
+			It("Returns the SQLite error", func() {
+				_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+				Expect(err).To(MatchError(And(HavePrefix("Unexpected SQLite error: "), ContainSubstring("no such table: go-commons-test"))))
+			})
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Pings the database", func() {
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+
+		It("Fails once the database is closed", func() {
+			Expect(indexer.New(indexerConfig)).To(Succeed())
+			Expect(indexer.Close()).To(Succeed())
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})
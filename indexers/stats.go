@@ -0,0 +1,163 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// maxFailureReasonLength length the captured failure reasons are truncated to
+const maxFailureReasonLength = 256
+
+// atomicResults results of the bulk items counted with atomic counters, the other stats, like the error types,
+// being rare enough to be counted in a mutex guarded map
+var atomicResults = []string{"created", "updated", "deleted", "noop", "not_found", "failed"}
+
+// itemStats counts the results of the bulk items reported by their OnSuccess and OnFailure callbacks
+type itemStats interface {
+	// add increments the given stat
+	add(stat string)
+	// snapshot returns a copy of the stats
+	snapshot() map[string]int
+	// take returns a copy of the stats and resets them
+	take() map[string]int
+}
+
+// newItemStats returns the atomic counters when atomicCounters is set, a mutex guarded map otherwise
+func newItemStats(atomicCounters bool) itemStats {
+	if atomicCounters {
+		return &atomicItemStats{
+			counters: make([]atomic.Int64, len(atomicResults)),
+			others:   mutexItemStats{stats: make(map[string]int)},
+		}
+	}
+	return &mutexItemStats{stats: make(map[string]int)}
+}
+
+// mutexItemStats stats held in a mutex guarded map
+type mutexItemStats struct {
+	sync.Mutex
+	stats map[string]int
+}
+
+func (s *mutexItemStats) add(stat string) {
+	s.Lock()
+	defer s.Unlock()
+	s.stats[stat]++
+}
+
+func (s *mutexItemStats) snapshot() map[string]int {
+	s.Lock()
+	defer s.Unlock()
+	return copyStats(s.stats)
+}
+
+func (s *mutexItemStats) take() map[string]int {
+	s.Lock()
+	defer s.Unlock()
+	stats := s.stats
+	s.stats = make(map[string]int, len(stats))
+	return stats
+}
+
+// atomicItemStats stats counted with an atomic counter per result of atomicResults, avoiding the lock contention
+// of the mutex guarded map when many workers report their items
+type atomicItemStats struct {
+	counters []atomic.Int64
+	others   mutexItemStats
+}
+
+func (s *atomicItemStats) add(stat string) {
+	for i, result := range atomicResults {
+		if result == stat {
+			s.counters[i].Add(1)
+			return
+		}
+	}
+	s.others.add(stat)
+}
+
+func (s *atomicItemStats) snapshot() map[string]int {
+	stats := s.others.snapshot()
+	for i, result := range atomicResults {
+		if val := s.counters[i].Load(); val > 0 {
+			stats[result] += int(val)
+		}
+	}
+	return stats
+}
+
+func (s *atomicItemStats) take() map[string]int {
+	stats := s.others.take()
+	for i, result := range atomicResults {
+		if val := s.counters[i].Swap(0); val > 0 {
+			stats[result] += int(val)
+		}
+	}
+	return stats
+}
+
+// failureReasons captures the errors of the first failed bulk items reported by their OnFailure callbacks, up to max
+type failureReasons struct {
+	sync.Mutex
+	max     int
+	reasons []FailureReason
+}
+
+// newFailureReasons returns the failure reasons capturing up to max errors, nil when max is 0
+func newFailureReasons(max int) *failureReasons {
+	if max <= 0 {
+		return nil
+	}
+	return &failureReasons{max: max}
+}
+
+// add captures the error of the failed bulk item, unless max errors are captured already. Nothing is captured by
+// nil failure reasons
+func (f *failureReasons) add(documentID string, status int, errType, reason string) {
+	if f == nil {
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	if len(f.reasons) >= f.max {
+		return
+	}
+	f.reasons = append(f.reasons, FailureReason{DocumentID: documentID, Status: status, Type: errType, Reason: truncateReason(reason)})
+}
+
+// list returns a copy of the captured errors
+func (f *failureReasons) list() []FailureReason {
+	if f == nil {
+		return nil
+	}
+	f.Lock()
+	defer f.Unlock()
+	return append([]FailureReason(nil), f.reasons...)
+}
+
+// truncateReason truncates the reason to maxFailureReasonLength bytes, without splitting its last character
+func truncateReason(reason string) string {
+	if len(reason) <= maxFailureReasonLength {
+		return reason
+	}
+	end := maxFailureReasonLength - len("...")
+	for end > 0 && !utf8.RuneStart(reason[end]) {
+		end--
+	}
+	return reason[:end] + "..."
+}
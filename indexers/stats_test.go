@@ -0,0 +1,106 @@
+package indexers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for stats.go", func() {
+	for _, atomicCounters := range []bool{false, true} {
+		atomicCounters := atomicCounters
+		Context(fmt.Sprintf("Tests for itemStats with atomic counters %t", atomicCounters), func() {
+			It("Counts the stats reported concurrently", func() {
+				stats := newItemStats(atomicCounters)
+				var wg sync.WaitGroup
+				for w := 0; w < 8; w++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for i := 0; i < 100; i++ {
+							stats.add("created")
+							stats.add("failed")
+							stats.add("mapper_parsing_exception")
+						}
+					}()
+				}
+				wg.Wait()
+				Expect(stats.snapshot()).To(Equal(map[string]int{"created": 800, "failed": 800, "mapper_parsing_exception": 800}))
+			})
+
+			It("Resets the stats when taking them", func() {
+				stats := newItemStats(atomicCounters)
+				stats.add("updated")
+				stats.add("version_conflict_engine_exception")
+				Expect(stats.take()).To(Equal(map[string]int{"updated": 1, "version_conflict_engine_exception": 1}))
+				Expect(stats.snapshot()).To(BeEmpty())
+				stats.add("created")
+				Expect(stats.take()).To(Equal(map[string]int{"created": 1}))
+			})
+		})
+	}
+
+	Context("Tests for failureReasons", func() {
+		It("Captures up to the maximum number of failure reasons reported concurrently", func() {
+			failures := newFailureReasons(5)
+			var wg sync.WaitGroup
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					failures.add(fmt.Sprint(w), 400, "mapper_parsing_exception", "failed to parse")
+				}(w)
+			}
+			wg.Wait()
+			Expect(failures.list()).To(HaveLen(5))
+			Expect(failures.list()[0].Type).To(Equal("mapper_parsing_exception"))
+		})
+
+		It("Captures nothing when the maximum is 0", func() {
+			failures := newFailureReasons(0)
+			failures.add("1234", 0, "", "connection refused")
+			Expect(failures.list()).To(BeNil())
+		})
+
+		It("Truncates the long reasons without splitting their characters", func() {
+			Expect(truncateReason("mapping conflict")).To(Equal("mapping conflict"))
+			truncated := truncateReason(strings.Repeat("é", 200))
+			Expect(len(truncated)).To(BeNumerically("<=", maxFailureReasonLength))
+			Expect(truncated).To(Equal(strings.Repeat("é", 126) + "..."))
+			Expect(utf8.ValidString(truncated)).To(BeTrue())
+		})
+	})
+
+	It("Counts the bulk items with atomic counters when configured", func() {
+		mockServer := newBulkMockServer(func(n int) int { return 201 })
+		defer mockServer.Close()
+		var indexer Elastic
+		err := indexer.New(IndexerConfig{Type: ElasticIndexer, Servers: []string{mockServer.URL}, Index: "go-commons-test", AtomicStats: true})
+		Expect(err).To(BeNil())
+		result, err := indexer.IndexWithResult(context.Background(), []interface{}{"first", "second"}, IndexingOpts{})
+		Expect(err).To(BeNil())
+		Expect(result.Created).To(Equal(2))
+	})
+})
+
+// BenchmarkItemStats compares counting the bulk item results in a mutex guarded map and with atomic counters
+// from concurrent workers
+func BenchmarkItemStats(b *testing.B) {
+	for _, atomicCounters := range []bool{false, true} {
+		b.Run(fmt.Sprintf("atomic=%t", atomicCounters), func(b *testing.B) {
+			stats := newItemStats(atomicCounters)
+			b.SetParallelism(4)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					stats.add("created")
+				}
+			})
+		})
+	}
+}
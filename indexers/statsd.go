@@ -0,0 +1,190 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const statsdIndexer = "statsd"
+
+// statsdMaxPacketSize keeps the packets below the usual MTU to avoid IP fragmentation
+const statsdMaxPacketSize = 1432
+
+// statsdSample object document sent by the statsd indexer
+type statsdSample struct {
+	MetricName string   `json:"metricName"`
+	Value      *float64 `json:"value"`
+}
+
+// StatsD indexer instance
+type StatsD struct {
+	conn net.Conn
+}
+
+// Init function
+func init() {
+	Register(statsdIndexer, func() Indexer { return &StatsD{} })
+}
+
+// Returns new indexer for StatsD, Servers[0] being the host:port address of the StatsD UDP listener
+func (s *StatsD) New(indexerConfig IndexerConfig) error {
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	conn, err := net.Dial("udp", indexerConfig.Servers[0])
+	if err != nil {
+		return fmt.Errorf("error creating the StatsD client: %s", err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// Index sends the numeric documents as StatsD gauges
+func (s *StatsD) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := s.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult sends the numeric documents as StatsD gauges named after the metric name and returns the
+// indexing result. Documents are either numbers or objects holding a numeric value field, their metric name
+// field being appended to the metric path. Any other document is skipped and counted in the nonNumeric stat
+func (s *StatsD) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, s.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var packet bytes.Buffer
+	packetDocs := 0
+	flush := func() error {
+		if packetDocs == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.conn.Write(bytes.TrimSuffix(packet.Bytes(), []byte("\n"))); err != nil {
+			return fmt.Errorf("Unexpected StatsD error: %s", err)
+		}
+		indexerStats["created"] += packetDocs
+		packet.Reset()
+		packetDocs = 0
+		return nil
+	}
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		lines, ok := statsdGauge(j, opts.MetricName)
+		if !ok {
+			indexerStats["nonNumeric"]++
+			continue
+		}
+		if packet.Len()+len(lines) > statsdMaxPacketSize {
+			if err := flush(); err != nil {
+				return IndexingResult{}, err
+			}
+		}
+		packet.Write(lines)
+		packetDocs++
+	}
+	if err := flush(); err != nil {
+		return IndexingResult{}, err
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// statsdGauge returns the gauge lines of the given encoded document, reporting false when it isn't numeric
+func statsdGauge(j []byte, metricName string) ([]byte, bool) {
+	var value float64
+	if err := json.Unmarshal(j, &value); err != nil {
+		var sample statsdSample
+		if err := json.Unmarshal(j, &sample); err != nil || sample.Value == nil {
+			return nil, false
+		}
+		value = *sample.Value
+		if sample.MetricName != "" {
+			if metricName != "" {
+				metricName += "."
+			}
+			metricName += sample.MetricName
+		}
+	}
+	if metricName == "" {
+		return nil, false
+	}
+	metricName = statsdMetricPath(metricName)
+	var lines bytes.Buffer
+	// A signed gauge value changes the gauge by that amount, so the gauge is reset before setting a negative value
+	if value < 0 {
+		fmt.Fprintf(&lines, "%s:0|g\n", metricName)
+	}
+	fmt.Fprintf(&lines, "%s:%s|g\n", metricName, strconv.FormatFloat(value, 'f', -1, 64))
+	return lines.Bytes(), true
+}
+
+// statsdMetricPath replaces the characters reserved by the StatsD protocol in the given metric path
+func statsdMetricPath(path string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, path)
+}
+
+// Ping always succeeds as StatsD metrics are sent over UDP, which can't tell whether the server is listening
+func (s *StatsD) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the StatsD connection
+func (s *StatsD) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
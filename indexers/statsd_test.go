@@ -0,0 +1,138 @@
+package indexers
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// receivePackets returns the packets received by the given listener until it stays idle
+func receivePackets(listener net.PacketConn) []string {
+	var packets []string
+	buf := make([]byte, 65535)
+	for {
+		listener.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			return packets
+		}
+		packets = append(packets, string(buf[:n]))
+	}
+}
+
+var _ = Describe("Tests for statsd.go", func() {
+	Context("Tests for New()", func() {
+		var indexer StatsD
+
+		It("Returns nil as error", func() {
+			err := indexer.New(IndexerConfig{Type: "statsd", Servers: []string{"localhost:8125"}})
+			Expect(err).To(BeNil())
+			Expect(indexer.Close()).To(BeNil())
+		})
+
+		It("Returns err no servers", func() {
+			err := indexer.New(IndexerConfig{Type: "statsd"})
+			Expect(err).To(MatchError("servers not specified"))
+		})
+
+		It("Returns err invalid address", func() {
+			err := indexer.New(IndexerConfig{Type: "statsd", Servers: []string{"localhost"}})
+			Expect(err.Error()).To(HavePrefix("error creating the StatsD client"))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var indexer StatsD
+		var listener net.PacketConn
+		var opts IndexingOpts
+		BeforeEach(func() {
+			var err error
+			listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			Expect(indexer.New(IndexerConfig{Type: "statsd", Servers: []string{listener.LocalAddr().String()}})).To(Succeed())
+			opts = IndexingOpts{MetricName: "perf.podLatency"}
+		})
+		AfterEach(func() {
+			indexer.Close()
+			listener.Close()
+		})
+
+		It("Sends the numeric documents as gauges", func() {
+			documents := []interface{}{
+				42,
+				1.5,
+				map[string]interface{}{"metricName": "p99", "value": 120},
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Expect(receivePackets(listener)).To(Equal([]string{"perf.podLatency:42|g\nperf.podLatency:1.5|g\nperf.podLatency.p99:120|g"}))
+		})
+
+		It("Resets the gauge before sending negative values", func() {
+			_, err := indexer.IndexWithResult(context.Background(), []interface{}{-3}, opts)
+			Expect(err).To(BeNil())
+			Expect(receivePackets(listener)).To(Equal([]string{"perf.podLatency:0|g\nperf.podLatency:-3|g"}))
+		})
+
+		It("Skips and counts the non-numeric documents", func() {
+			documents := []interface{}{"example document", map[string]interface{}{"key1": "value1"}, 42}
+			result, err := indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(1))
+			Expect(result.Stats).To(HaveKeyWithValue("nonNumeric", 2))
+			Expect(receivePackets(listener)).To(Equal([]string{"perf.podLatency:42|g"}))
+		})
+
+		It("Splits the gauges in packets below the MTU", func() {
+			documents := make([]interface{}, 200)
+			for i := range documents {
+				documents[i] = i
+			}
+			result, err := indexer.IndexWithResult(context.Background(), documents, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(200))
+			packets := receivePackets(listener)
+			Expect(len(packets)).To(BeNumerically(">", 1))
+			lines := 0
+			for _, packet := range packets {
+				Expect(len(packet)).To(BeNumerically("<=", statsdMaxPacketSize))
+				lines += len(strings.Split(packet, "\n"))
+			}
+			Expect(lines).To(Equal(200))
+		})
+
+		It("Replaces the reserved characters of the metric path", func() {
+			opts.MetricName = "pod latency:p99"
+			_, err := indexer.IndexWithResult(context.Background(), []interface{}{1}, opts)
+			Expect(err).To(BeNil())
+			Expect(receivePackets(listener)).To(Equal([]string{"pod_latency_p99:1|g"}))
+		})
+
+		It("Doesn't send any gauge in dry-run mode", func() {
+			opts.DryRun = true
+			result, err := indexer.IndexWithResult(context.Background(), []interface{}{42, 1.5}, opts)
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 2))
+			Expect(receivePackets(listener)).To(BeEmpty())
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		It("Succeeds without any server listening", func() {
+			var indexer StatsD
+			Expect(indexer.New(IndexerConfig{Type: "statsd", Servers: []string{"localhost:8125"}})).To(Succeed())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,94 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const stdout = "stdout"
+
+// Stdout indexer instance
+type Stdout struct {
+	writer io.Writer
+}
+
+// Init function
+func init() {
+	Register(stdout, func() Indexer { return &Stdout{} })
+}
+
+// Prepares the stdout indexer
+func (s *Stdout) New(indexerConfig IndexerConfig) error {
+	s.writer = indexerConfig.Writer
+	if s.writer == nil {
+		s.writer = os.Stdout
+	}
+	return nil
+}
+
+// Index pretty-prints the documents to the configured writer
+func (s *Stdout) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	result, err := s.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.DryRun {
+		return result.String(), nil
+	}
+	return fmt.Sprintf("%d documents printed", result.Created), nil
+}
+
+// IndexWithResult pretty-prints the documents to the configured writer and returns the indexing result
+func (s *Stdout) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, s.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	start := time.Now().UTC()
+	batch, redundantSkipped, err := encodeBatch(ctx, documents, opts)
+	if err != nil {
+		return IndexingResult{}, err
+	}
+	for _, j := range batch {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, j, "", "  "); err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot indent document %s: %s", j, err)
+		}
+		buf.WriteByte('\n')
+		if _, err := s.writer.Write(buf.Bytes()); err != nil {
+			return IndexingResult{}, fmt.Errorf("Error writing document: %s", err)
+		}
+	}
+	return newIndexingResult(map[string]int{"created": len(batch)}, redundantSkipped, time.Since(start)), nil
+}
+
+// Ping always succeeds, the writer being owned by the caller
+func (s *Stdout) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op, the writer is owned by the caller
+func (s *Stdout) Close() error {
+	return nil
+}
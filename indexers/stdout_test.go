@@ -0,0 +1,107 @@
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for stdout.go", func() {
+	Context("Tests for New()", func() {
+		It("Defaults the writer to stdout", func() {
+			var indexer Stdout
+			err := indexer.New(IndexerConfig{Type: "stdout"})
+			Expect(err).To(BeNil())
+			Expect(indexer.writer).To(Equal(os.Stdout))
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var indexer Stdout
+		var buf *bytes.Buffer
+		var documents []interface{}
+		BeforeEach(func() {
+			buf = &bytes.Buffer{}
+			Expect(indexer.New(IndexerConfig{Type: "stdout", Writer: buf})).To(Succeed())
+			documents = []interface{}{
+				map[string]interface{}{"key1": "value1", "key2": 123},
+				map[string]interface{}{"key1": "value2", "key2": 456},
+			}
+		})
+
+		It("Prints the documents to the writer", func() {
+			msg, err := indexer.Index(context.Background(), documents, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(msg).To(Equal("2 documents printed"))
+			decoder := json.NewDecoder(buf)
+			for _, document := range documents {
+				var printed map[string]interface{}
+				Expect(decoder.Decode(&printed)).To(Succeed())
+				expected, _ := json.Marshal(document)
+				Expect(json.Marshal(printed)).To(MatchJSON(expected))
+			}
+			Expect(decoder.More()).To(BeFalse())
+		})
+
+		It("Doesn't escape HTML characters when disabled", func() {
+			documents = []interface{}{map[string]interface{}{"url": "http://x?a=1&b=<c>"}}
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{DisableHTMLEscape: true})
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(ContainSubstring(`"url": "http://x?a=1&b=<c>"`))
+		})
+
+		It("Sets the indexing timestamp on every document", func() {
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{AddTimestamp: true, TimestampField: "timestamp"})
+			Expect(err).To(BeNil())
+			decoder := json.NewDecoder(buf)
+			for range documents {
+				var printed map[string]interface{}
+				Expect(decoder.Decode(&printed)).To(Succeed())
+				Expect(printed).To(HaveKey("timestamp"))
+			}
+		})
+
+		It("Tags the documents with the metric and job names", func() {
+			_, err := indexer.Index(context.Background(), documents[:1], IndexingOpts{MetricName: "placeholder", JobName: "cluster-density"})
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(MatchJSON(`{"key1":"value1","key2":123,"metricName":"placeholder","jobName":"cluster-density"}`))
+		})
+
+		It("Projects the documents down to the included fields", func() {
+			_, err := indexer.Index(context.Background(), documents[:1], IndexingOpts{IncludeFields: []string{"key2"}})
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(MatchJSON(`{"key2":123}`))
+		})
+
+		It("Flattens the nested documents", func() {
+			documents = []interface{}{map[string]interface{}{"labels": map[string]interface{}{"node": "worker"}, "pods": []string{"nginx"}}}
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{Flatten: true})
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(MatchJSON(`{"labels.node":"worker","pods.0":"nginx"}`))
+		})
+
+		It("Doesn't print anything in dry-run mode", func() {
+			result, err := indexer.IndexWithResult(context.Background(), documents, IndexingOpts{DryRun: true})
+			Expect(err).To(BeNil())
+			Expect(result.Stats).To(HaveKeyWithValue("validated", 2))
+			Expect(buf.Len()).To(BeZero())
+		})
+
+		It("err returned docs not processed", func() {
+			documents = append(documents, make(chan string))
+			_, err := indexer.Index(context.Background(), documents, IndexingOpts{})
+			Expect(err.Error()).To(ContainSubstring("Cannot encode document"))
+		})
+
+		It("Skips the unencodable documents", func() {
+			documents = append(documents, make(chan string))
+			msg, err := indexer.Index(context.Background(), documents, IndexingOpts{SkipUnencodable: true})
+			Expect(err).To(BeNil())
+			Expect(msg).To(Equal("2 documents printed"))
+		})
+	})
+})
@@ -1,9 +1,32 @@
 package indexers
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 )
 
 var payload []byte
@@ -36,3 +59,342 @@ type indexMethodTestcase struct {
 	documents []interface{}
 	opts      IndexingOpts
 }
+
+// newBulkMockServer returns a mock server answering the bulk API with the status returned by itemStatus for the n-th
+// document. The documents indexed are served back by the get and count APIs
+func newBulkMockServer(itemStatus func(n int) int) *httptest.Server {
+	return httptest.NewServer(bulkMockHandler(itemStatus))
+}
+
+// newES8MockServer returns a bulk mock server behaving as an ES 8 cluster, reporting an ES 8 version and sending the
+// product header checked by the v8 client
+func newES8MockServer(itemStatus func(n int) int) *httptest.Server {
+	return httptest.NewServer(es8Handler(bulkMockHandler(itemStatus)))
+}
+
+// es8Handler wraps handler, answering the info API with an ES 8 version and sending the product header of ES 8
+func es8Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{
+				"name" : "es-8-0",
+				"cluster_name" : "perfscale-dev",
+				"version" : {
+				  "number" : "8.11.1",
+				  "build_flavor" : "default",
+				  "build_type" : "docker",
+				  "lucene_version" : "9.8.0",
+				  "minimum_wire_compatibility_version" : "7.17.0",
+				  "minimum_index_compatibility_version" : "7.0.0"
+				},
+				"tagline" : "You Know, for Search"
+			  }`))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// bulkMockHandler returns the handler of the mock server returned by newBulkMockServer
+func bulkMockHandler(itemStatus func(n int) int) http.Handler {
+	var lock sync.Mutex
+	docs := 0
+	sources := make(map[string]json.RawMessage)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/_doc/"):
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			source, found := sources[id]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, `{"_id":"%s","found":false}`, id)
+				return
+			}
+			fmt.Fprintf(w, `{"_id":"%s","found":true,"_source":%s}`, id, source)
+			return
+		case strings.HasSuffix(r.URL.Path, "/_count"):
+			fmt.Fprintf(w, `{"count":%d}`, len(sources))
+			return
+		case !strings.HasSuffix(r.URL.Path, "/_bulk"):
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(payload)
+			if err != nil {
+				log.Printf("Error while sending payload to http mock server: %v", err)
+			}
+			return
+		}
+		var items []string
+		hasErrors := false
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		scanner := bufio.NewScanner(body)
+		indexedID := ""
+		for line := 0; scanner.Scan(); line++ {
+			// Odd lines hold the document bodies
+			if line%2 != 0 {
+				if indexedID != "" {
+					sources[indexedID] = append(json.RawMessage(nil), scanner.Bytes()...)
+				}
+				continue
+			}
+			var action map[string]map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			indexedID = ""
+			for name, meta := range action {
+				id := fmt.Sprint(meta["_id"])
+				status := itemStatus(docs)
+				result := "created"
+				if _, exists := sources[id]; exists {
+					result = "updated"
+				}
+				item := fmt.Sprintf(`{"_index":"%v","_id":"%v","status":%d,"result":"%s"}`, meta["_index"], meta["_id"], status, result)
+				if status >= 300 {
+					hasErrors = true
+					item = fmt.Sprintf(`{"_index":"%v","_id":"%v","status":%d,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}`, meta["_index"], meta["_id"], status)
+				}
+				if status < 300 {
+					indexedID = id
+				}
+				items = append(items, fmt.Sprintf(`{"%s":%s}`, name, item))
+			}
+			docs++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, hasErrors, strings.Join(items, ","))
+	})
+}
+
+// failingBulkHandler returns a mock handler failing the n-th item of every bulk request with the status, error type
+// and reason of the n-th failure, the items beyond the failures being created. Other requests are answered as by
+// newBulkMockServer
+func failingBulkHandler(failures []FailureReason) http.Handler {
+	handler := bulkMockHandler(func(n int) int { return http.StatusCreated })
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		var items []string
+		scanner := bufio.NewScanner(r.Body)
+		for line := 0; scanner.Scan(); line++ {
+			if line%2 != 0 {
+				continue
+			}
+			var action map[string]map[string]interface{}
+			Expect(json.Unmarshal(scanner.Bytes(), &action)).To(Succeed())
+			for name, meta := range action {
+				n := len(items)
+				if n >= len(failures) {
+					items = append(items, fmt.Sprintf(`{"%s":{"_id":"%v","status":201,"result":"created"}}`, name, meta["_id"]))
+					continue
+				}
+				reason, _ := json.Marshal(failures[n].Reason)
+				items = append(items, fmt.Sprintf(`{"%s":{"_id":"%v","status":%d,"error":{"type":"%s","reason":%s}}}`, name, meta["_id"], failures[n].Status, failures[n].Type, reason))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	})
+}
+
+// gzipResponseHandler wraps handler, gzip encoding its responses when the client accepts them
+func gzipResponseHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		for name, values := range recorder.Header() {
+			w.Header()[name] = values
+		}
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(recorder.Code)
+		zw := gzip.NewWriter(w)
+		_, _ = zw.Write(recorder.Body.Bytes())
+		_ = zw.Close()
+	})
+}
+
+// newVersionMockServer returns a bulk mock server reporting the given version, recording the headers of the bulk requests
+func newVersionMockServer(version string, bulkHeaders *[]http.Header) *httptest.Server {
+	bulkServer := newBulkMockServer(func(n int) int { return http.StatusCreated })
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprintf(w, `{"version":%s}`, version)
+			return
+		case strings.HasSuffix(r.URL.Path, "/_bulk"):
+			*bulkHeaders = append(*bulkHeaders, r.Header.Clone())
+		}
+		bulkServer.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+// recordBulkLines wraps the given handler, recording the decoded lines of the bulk requests in lines
+func recordBulkLines(handler http.Handler, lines *[]map[string]interface{}) http.Handler {
+	var lock sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_bulk") {
+			body, _ := io.ReadAll(r.Body)
+			lock.Lock()
+			for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+				var l map[string]interface{}
+				Expect(json.Unmarshal([]byte(line), &l)).To(Succeed())
+				*lines = append(*lines, l)
+			}
+			lock.Unlock()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// recordBulkQueries wraps the given handler, recording the query parameters of the bulk requests in queries
+func recordBulkQueries(handler http.Handler, queries *[]url.Values) http.Handler {
+	var lock sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_bulk") {
+			lock.Lock()
+			*queries = append(*queries, r.URL.Query())
+			lock.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// writeCACert writes the certificate of the given TLS mock server to a temporary PEM file and returns its path
+func writeCACert(server *httptest.Server) string {
+	f, err := os.CreateTemp("", "go-commons-ca-*.pem")
+	Expect(err).To(BeNil())
+	defer f.Close()
+	Expect(pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})).To(Succeed())
+	return f.Name()
+}
+
+// newClientCertificate returns a self-signed PEM encoded client certificate along with its private key
+func newClientCertificate() ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).To(BeNil())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-commons-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).To(BeNil())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).To(BeNil())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newMTLSServer returns a TLS server serving handler only to the clients presenting the given certificate
+func newMTLSServer(handler http.Handler, clientCert []byte) *httptest.Server {
+	clientCAs := x509.NewCertPool()
+	Expect(clientCAs.AppendCertsFromPEM(clientCert)).To(BeTrue())
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	return server
+}
+
+// cancelingTransport calls cancel once the response of the first bulk request is received, its body being read
+// beforehand so that the bulk indexer can still process it
+type cancelingTransport struct {
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (ct *cancelingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || !strings.HasSuffix(req.URL.Path, "/_bulk") {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	ct.once.Do(ct.cancel)
+	return resp, nil
+}
+
+// recordingTransport records the paths of the requests sent through it
+type recordingTransport struct {
+	sync.Mutex
+	paths  []string
+	closed bool
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.Lock()
+	rt.paths = append(rt.paths, req.URL.Path)
+	rt.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (rt *recordingTransport) CloseIdleConnections() {
+	rt.Lock()
+	rt.closed = true
+	rt.Unlock()
+}
+
+// capturingLogger records the logged messages
+type capturingLogger struct {
+	sync.Mutex
+	messages []string
+}
+
+func (l *capturingLogger) log(level, format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.messages = append(l.messages, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Debugf(format string, args ...interface{}) {
+	l.log("debug", format, args...)
+}
+
+func (l *capturingLogger) Infof(format string, args ...interface{}) {
+	l.log("info", format, args...)
+}
+
+func (l *capturingLogger) Errorf(format string, args ...interface{}) {
+	l.log("error", format, args...)
+}
+
+// setEnv sets the given environment variables, restoring them once the spec finishes
+func setEnv(env map[string]string) {
+	for k, v := range env {
+		prev, set := os.LookupEnv(k)
+		Expect(os.Setenv(k, v)).To(Succeed())
+		DeferCleanup(func(k, prev string, set bool) {
+			if set {
+				os.Setenv(k, prev)
+			} else {
+				os.Unsetenv(k)
+			}
+		}, k, prev, set)
+	}
+}
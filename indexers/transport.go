@@ -0,0 +1,347 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// retryOnStatus status codes of the requests retried by the indexers
+var retryOnStatus = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// retryPolicy returns the maximum number of retries and the backoff function to use for the given configuration,
+// retries are disabled when MaxRetries is negative. The backoff is a random duration, up to a ceiling doubled at
+// every attempt and capped by RetryMaxBackoff, so clients failing together don't retry together
+func retryPolicy(indexerConfig IndexerConfig) (int, func(int) time.Duration) {
+	maxRetries := indexerConfig.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+	backoff := indexerConfig.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	maxBackoff := indexerConfig.RetryMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	var lock sync.Mutex
+	jitter := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return maxRetries, func(attempt int) time.Duration {
+		ceiling := backoff
+		for i := 1; i < attempt && ceiling < maxBackoff; i++ {
+			ceiling *= 2
+		}
+		if ceiling > maxBackoff {
+			ceiling = maxBackoff
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		return time.Duration(jitter.Int63n(int64(ceiling) + 1))
+	}
+}
+
+// newTransport returns the HTTP transport for the given indexer configuration, pooling the idle connections as
+// configured
+func newTransport(indexerConfig IndexerConfig) (http.RoundTripper, error) {
+	if indexerConfig.Transport != nil {
+		return indexerConfig.Transport, nil
+	}
+	if indexerConfig.MaxIdleConns < 0 {
+		return nil, fmt.Errorf("invalid maximum number of idle connections: %d", indexerConfig.MaxIdleConns)
+	}
+	if indexerConfig.MaxIdleConnsPerHost < 0 {
+		return nil, fmt.Errorf("invalid maximum number of idle connections per host: %d", indexerConfig.MaxIdleConnsPerHost)
+	}
+	if indexerConfig.IdleConnTimeout < 0 {
+		return nil, fmt.Errorf("invalid idle connection timeout: %s", indexerConfig.IdleConnTimeout)
+	}
+	tlsClientConfig, err := tlsConfig(indexerConfig)
+	if err != nil {
+		return nil, err
+	}
+	maxIdleConnsPerHost := indexerConfig.MaxIdleConnsPerHost
+	if maxIdleConnsPerHost == 0 {
+		// The net/http default of 2 idle connections per host throttles the parallel bulk workers
+		maxIdleConnsPerHost = indexerConfig.NumWorkers
+		if maxIdleConnsPerHost <= 0 {
+			maxIdleConnsPerHost = runtime.NumCPU()
+		}
+		if maxIdleConnsPerHost < http.DefaultMaxIdleConnsPerHost {
+			maxIdleConnsPerHost = http.DefaultMaxIdleConnsPerHost
+		}
+	}
+	return &http.Transport{
+		TLSClientConfig:     tlsClientConfig,
+		MaxIdleConns:        indexerConfig.MaxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     indexerConfig.IdleConnTimeout,
+	}, nil
+}
+
+// tlsConfig returns the TLS configuration for the given indexer configuration
+func tlsConfig(indexerConfig IndexerConfig) (*tls.Config, error) {
+	certificates, err := clientCertificates(indexerConfig)
+	if err != nil {
+		return nil, err
+	}
+	if indexerConfig.CACertPath == "" {
+		return &tls.Config{InsecureSkipVerify: indexerConfig.InsecureSkipVerify, Certificates: certificates}, nil
+	}
+	caCert, err := os.ReadFile(indexerConfig.CACertPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA certificate: %s", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no valid CA certificate found in %s", indexerConfig.CACertPath)
+	}
+	// Server certificates are always verified when a CA is supplied
+	return &tls.Config{RootCAs: rootCAs, Certificates: certificates}, nil
+}
+
+// clientCertificates returns the client certificate presented for mutual TLS, from the PEM encoded ClientCert and
+// ClientKey or else from the files they are read from, none when no certificate is configured
+func clientCertificates(indexerConfig IndexerConfig) ([]tls.Certificate, error) {
+	certPEM, keyPEM := indexerConfig.ClientCert, indexerConfig.ClientKey
+	var err error
+	if len(certPEM) == 0 && indexerConfig.ClientCertPath != "" {
+		if certPEM, err = os.ReadFile(indexerConfig.ClientCertPath); err != nil {
+			return nil, fmt.Errorf("error reading client certificate: %s", err)
+		}
+	}
+	if len(keyPEM) == 0 && indexerConfig.ClientKeyPath != "" {
+		if keyPEM, err = os.ReadFile(indexerConfig.ClientKeyPath); err != nil {
+			return nil, fmt.Errorf("error reading client key: %s", err)
+		}
+	}
+	if len(certPEM) == 0 && len(keyPEM) == 0 {
+		return nil, nil
+	}
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, fmt.Errorf("client certificate and key must be specified together")
+	}
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error loading client certificate: %s", err)
+	}
+	return []tls.Certificate{certificate}, nil
+}
+
+// closeIdleConnections closes the idle connections of the given transport, when supported
+func closeIdleConnections(transport http.RoundTripper) {
+	if t, ok := transport.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+// compressionStats counters of the gzip encoded responses decompressed by gzipResponseTransport
+type compressionStats struct {
+	responses         atomic.Uint64
+	compressedBytes   atomic.Uint64
+	decompressedBytes atomic.Uint64
+}
+
+// snapshot returns the current value of the counters, zero when stats is nil
+func (stats *compressionStats) snapshot() CompressionStats {
+	if stats == nil {
+		return CompressionStats{}
+	}
+	return CompressionStats{
+		Responses:         stats.responses.Load(),
+		CompressedBytes:   stats.compressedBytes.Load(),
+		DecompressedBytes: stats.decompressedBytes.Load(),
+	}
+}
+
+// gzipResponseTransport asks for gzip encoded responses and decompresses them, recording the bytes read in stats.
+// The wrapped transport doesn't negotiate the compression itself, as the Accept-Encoding header is set
+type gzipResponseTransport struct {
+	Transport http.RoundTripper
+	stats     *compressionStats
+}
+
+// RoundTrip asks for a gzip encoded response, unless the request already negotiates the encoding, and returns the
+// response with its body decompressed
+func (gt gzipResponseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" {
+		return gt.Transport.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := gt.Transport.RoundTrip(req)
+	if err != nil || resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, err
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	resp.Body = &gzipResponseBody{body: &countingReader{Reader: resp.Body}, closer: resp.Body, stats: gt.stats}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (gt gzipResponseTransport) CloseIdleConnections() {
+	closeIdleConnections(gt.Transport)
+}
+
+// countingReader counts the bytes read from the wrapped reader
+type countingReader struct {
+	io.Reader
+	n uint64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.Reader.Read(p)
+	cr.n += uint64(n)
+	return n, err
+}
+
+// gzipResponseBody decompresses a gzip encoded response body, the gzip reader being created on the first read so
+// that the empty bodies, i.e. of the HEAD requests, can be closed. The bytes read are recorded on close
+type gzipResponseBody struct {
+	body         *countingReader
+	closer       io.Closer
+	zr           *gzip.Reader
+	decompressed uint64
+	stats        *compressionStats
+}
+
+func (gb *gzipResponseBody) Read(p []byte) (int, error) {
+	if gb.zr == nil {
+		zr, err := gzip.NewReader(gb.body)
+		if err != nil {
+			return 0, err
+		}
+		gb.zr = zr
+	}
+	n, err := gb.zr.Read(p)
+	gb.decompressed += uint64(n)
+	return n, err
+}
+
+func (gb *gzipResponseBody) Close() error {
+	if gb.stats != nil && gb.zr != nil {
+		gb.stats.responses.Add(1)
+		gb.stats.compressedBytes.Add(gb.body.n)
+		gb.stats.decompressedBytes.Add(gb.decompressed)
+		gb.zr = nil
+	}
+	return gb.closer.Close()
+}
+
+// flushTimeoutTransport bounds the duration of every bulk request by timeout, on top of the deadline of its context
+type flushTimeoutTransport struct {
+	Transport http.RoundTripper
+	timeout   time.Duration
+}
+
+// RoundTrip sends the request through the wrapped transport, with a context timing out after timeout for the bulk
+// requests, released once the response body is closed
+func (ft flushTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/_bulk") {
+		return ft.Transport.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), ft.timeout)
+	resp, err := ft.Transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelingBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (ft flushTimeoutTransport) CloseIdleConnections() {
+	closeIdleConnections(ft.Transport)
+}
+
+// cancelingBody response body canceling the context of its request once closed
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (cb *cancelingBody) Close() error {
+	defer cb.cancel()
+	return cb.ReadCloser.Close()
+}
+
+// productCheckTransport flags the responses lacking the X-Elastic-Product header as Elasticsearch ones, letting the
+// v7 client through its product check with OpenSearch and the OSS clusters older than 7.14, whose distribution
+// the ES indexer detects itself
+type productCheckTransport struct {
+	Transport http.RoundTripper
+}
+
+// RoundTrip sets the product header on the response of the wrapped transport when missing
+func (pt productCheckTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := pt.Transport.RoundTrip(req)
+	if err == nil && resp.Header.Get("X-Elastic-Product") == "" {
+		resp.Header.Set("X-Elastic-Product", "Elasticsearch")
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (pt productCheckTransport) CloseIdleConnections() {
+	closeIdleConnections(pt.Transport)
+}
+
+// compatibilityTransport asks ES 8 and later to handle the requests of the v7 client as v7 requests once enabled,
+// through the REST API compatibility headers
+type compatibilityTransport struct {
+	Transport http.RoundTripper
+	enabled   atomic.Bool
+}
+
+// RoundTrip sets the compatibility headers on the request when enabled before sending it through the wrapped transport
+func (ct *compatibilityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !ct.enabled.Load() {
+		return ct.Transport.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept", "application/vnd.elasticsearch+json;compatible-with=7")
+	switch req.Header.Get("Content-Type") {
+	case "":
+	case "application/x-ndjson":
+		req.Header.Set("Content-Type", "application/vnd.elasticsearch+x-ndjson;compatible-with=7")
+	default:
+		req.Header.Set("Content-Type", "application/vnd.elasticsearch+json;compatible-with=7")
+	}
+	return ct.Transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (ct *compatibilityTransport) CloseIdleConnections() {
+	closeIdleConnections(ct.Transport)
+}
@@ -0,0 +1,181 @@
+package indexers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for transport.go", func() {
+	Context("Tests for retryPolicy()", func() {
+		// backoffs returns samples of the backoff of the given attempt
+		backoffs := func(backoff func(int) time.Duration, attempt int) []time.Duration {
+			var samples []time.Duration
+			for i := 0; i < 200; i++ {
+				samples = append(samples, backoff(attempt))
+			}
+			return samples
+		}
+
+		It("Returns the default retry policy", func() {
+			maxRetries, backoff := retryPolicy(IndexerConfig{})
+			Expect(maxRetries).To(Equal(3))
+			Expect(backoffs(backoff, 1)).To(HaveEach(BeNumerically("<=", 100*time.Millisecond)))
+			Expect(backoffs(backoff, 3)).To(HaveEach(BeNumerically("<=", 400*time.Millisecond)))
+			Expect(backoffs(backoff, 20)).To(HaveEach(BeNumerically("<=", 10*time.Second)))
+		})
+
+		It("Returns the configured retry policy", func() {
+			maxRetries, backoff := retryPolicy(IndexerConfig{MaxRetries: 5, RetryBackoff: 10 * time.Millisecond, RetryMaxBackoff: 50 * time.Millisecond})
+			Expect(maxRetries).To(Equal(5))
+			Expect(backoffs(backoff, 2)).To(HaveEach(BeNumerically("<=", 20*time.Millisecond)))
+			Expect(backoffs(backoff, 5)).To(HaveEach(BeNumerically("<=", 50*time.Millisecond)))
+		})
+
+		It("Returns random backoffs within the exponential ceiling", func() {
+			_, backoff := retryPolicy(IndexerConfig{RetryBackoff: time.Second, RetryMaxBackoff: time.Minute})
+			for attempt := 1; attempt <= 10; attempt++ {
+				ceiling := time.Second << (attempt - 1)
+				if ceiling > time.Minute {
+					ceiling = time.Minute
+				}
+				samples := backoffs(backoff, attempt)
+				Expect(samples).To(HaveEach(And(BeNumerically(">=", 0), BeNumerically("<=", ceiling))))
+				distinct := make(map[time.Duration]bool)
+				for _, sample := range samples {
+					distinct[sample] = true
+				}
+				Expect(len(distinct)).To(BeNumerically(">", 1))
+			}
+		})
+
+		It("Returns no retries when retries are disabled", func() {
+			maxRetries, _ := retryPolicy(IndexerConfig{MaxRetries: -1})
+			Expect(maxRetries).To(Equal(0))
+		})
+	})
+
+	Context("Tests for gzipResponseTransport", func() {
+		It("Decompresses the gzip encoded responses and records the bytes read", func() {
+			body := strings.Repeat(`{"value":1}`, 100)
+			server := httptest.NewServer(gzipResponseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, body)
+			})))
+			defer server.Close()
+			stats := &compressionStats{}
+			client := &http.Client{Transport: gzipResponseTransport{Transport: &http.Transport{}, stats: stats}}
+			resp, err := client.Get(server.URL)
+			Expect(err).To(BeNil())
+			received, err := io.ReadAll(resp.Body)
+			Expect(err).To(BeNil())
+			Expect(resp.Body.Close()).To(Succeed())
+			Expect(string(received)).To(Equal(body))
+			Expect(stats.snapshot().Responses).To(BeEquivalentTo(1))
+			Expect(stats.snapshot().DecompressedBytes).To(BeEquivalentTo(len(body)))
+			Expect(stats.snapshot().BytesSaved()).To(BeNumerically(">", 0))
+		})
+
+		It("Returns the responses that aren't gzip encoded as they are", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "plain")
+			}))
+			defer server.Close()
+			stats := &compressionStats{}
+			client := &http.Client{Transport: gzipResponseTransport{Transport: &http.Transport{}, stats: stats}}
+			resp, err := client.Get(server.URL)
+			Expect(err).To(BeNil())
+			received, err := io.ReadAll(resp.Body)
+			Expect(err).To(BeNil())
+			Expect(resp.Body.Close()).To(Succeed())
+			Expect(string(received)).To(Equal("plain"))
+			Expect(stats.snapshot().Responses).To(BeZero())
+		})
+	})
+
+	Context("Tests for newTransport()", func() {
+		It("Configures the connection pool with the supplied values", func() {
+			transport, err := newTransport(IndexerConfig{MaxIdleConns: 50, MaxIdleConnsPerHost: 20, IdleConnTimeout: time.Minute})
+			Expect(err).To(BeNil())
+			Expect(transport).To(BeAssignableToTypeOf(&http.Transport{}))
+			Expect(transport.(*http.Transport).MaxIdleConns).To(Equal(50))
+			Expect(transport.(*http.Transport).MaxIdleConnsPerHost).To(Equal(20))
+			Expect(transport.(*http.Transport).IdleConnTimeout).To(Equal(time.Minute))
+		})
+
+		It("Keeps as many idle connections per host as workers by default", func() {
+			transport, err := newTransport(IndexerConfig{NumWorkers: 16})
+			Expect(err).To(BeNil())
+			Expect(transport.(*http.Transport).MaxIdleConnsPerHost).To(Equal(16))
+			transport, err = newTransport(IndexerConfig{NumWorkers: 1})
+			Expect(err).To(BeNil())
+			Expect(transport.(*http.Transport).MaxIdleConnsPerHost).To(Equal(http.DefaultMaxIdleConnsPerHost))
+		})
+
+		It("Returns err invalid connection pool settings", func() {
+			_, err := newTransport(IndexerConfig{MaxIdleConnsPerHost: -1})
+			Expect(err).To(MatchError("invalid maximum number of idle connections per host: -1"))
+			_, err = newTransport(IndexerConfig{IdleConnTimeout: -time.Second})
+			Expect(err).To(MatchError("invalid idle connection timeout: -1s"))
+		})
+	})
+
+	Context("Tests for tlsConfig()", func() {
+		It("Skips verification when requested and no CA is given", func() {
+			cfg, err := tlsConfig(IndexerConfig{InsecureSkipVerify: true})
+			Expect(err).To(BeNil())
+			Expect(cfg.InsecureSkipVerify).To(BeTrue())
+			Expect(cfg.RootCAs).To(BeNil())
+		})
+
+		It("Verifies the server certificate when a CA is given", func() {
+			server := httptest.NewTLSServer(http.NotFoundHandler())
+			defer server.Close()
+			caCertPath := writeCACert(server)
+			defer os.Remove(caCertPath)
+			cfg, err := tlsConfig(IndexerConfig{InsecureSkipVerify: true, CACertPath: caCertPath})
+			Expect(err).To(BeNil())
+			Expect(cfg.InsecureSkipVerify).To(BeFalse())
+			Expect(cfg.RootCAs).NotTo(BeNil())
+		})
+
+		It("Returns err invalid CA certificate", func() {
+			f, err := os.CreateTemp("", "go-commons-ca-*.pem")
+			Expect(err).To(BeNil())
+			f.Close()
+			defer os.Remove(f.Name())
+			_, err = tlsConfig(IndexerConfig{CACertPath: f.Name()})
+			Expect(err).To(BeEquivalentTo(fmt.Errorf("no valid CA certificate found in %s", f.Name())))
+		})
+
+		It("Loads the client certificate from its files", func() {
+			clientCert, clientKey := newClientCertificate()
+			dir := GinkgoT().TempDir()
+			certPath, keyPath := path.Join(dir, "client.crt"), path.Join(dir, "client.key")
+			Expect(os.WriteFile(certPath, clientCert, 0600)).To(Succeed())
+			Expect(os.WriteFile(keyPath, clientKey, 0600)).To(Succeed())
+			cfg, err := tlsConfig(IndexerConfig{ClientCertPath: certPath, ClientKeyPath: keyPath})
+			Expect(err).To(BeNil())
+			Expect(cfg.Certificates).To(HaveLen(1))
+		})
+
+		It("Returns err client certificate without key", func() {
+			clientCert, _ := newClientCertificate()
+			_, err := tlsConfig(IndexerConfig{ClientCert: clientCert})
+			Expect(err).To(MatchError("client certificate and key must be specified together"))
+		})
+
+		It("Returns err invalid client certificate", func() {
+			_, clientKey := newClientCertificate()
+			_, err := tlsConfig(IndexerConfig{ClientCert: []byte("invalid"), ClientKey: clientKey})
+			Expect(err.Error()).To(HavePrefix("error loading client certificate"))
+		})
+	})
+})
@@ -14,25 +14,320 @@
 
 package indexers
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
 // Types of indexers
 const (
 	// Elastic indexer that sends metrics to the configured ES instance
 	ElasticIndexer IndexerType = "elastic"
+	// Elastic8 indexer that sends metrics to the configured ES 8 instance through the v8 client
+	Elastic8Indexer IndexerType = "elastic8"
 	// OpenSearch indexer that sends metrics to the configured Search Instance
 	OpenSearchIndexer IndexerType = "opensearch"
 	// Local indexer that writes metrics to local directory
 	LocalIndexer IndexerType = "local"
+	// Kafka indexer that produces metrics to the configured Kafka topic
+	KafkaIndexer IndexerType = "kafka"
+	// Stdout indexer that prints metrics to the standard output
+	StdoutIndexer IndexerType = "stdout"
+	// Prometheus indexer that writes metrics to the configured remote-write endpoint
+	PrometheusIndexer IndexerType = "prometheus"
+	// InfluxDB indexer that writes metrics to the configured InfluxDB database
+	InfluxDBIndexer IndexerType = "influxdb"
+	// MongoDB indexer that upserts metrics into the configured MongoDB collection
+	MongoDBIndexer IndexerType = "mongodb"
+	// Splunk indexer that sends metrics to the configured Splunk HTTP Event Collector
+	SplunkIndexer IndexerType = "splunk"
+	// Loki indexer that pushes metrics as log lines to the configured Loki instance
+	LokiIndexer IndexerType = "loki"
+	// ClickHouse indexer that inserts metrics into the configured ClickHouse table
+	ClickHouseIndexer IndexerType = "clickhouse"
+	// Datadog indexer that submits metrics to the configured Datadog site
+	DatadogIndexer IndexerType = "datadog"
+	// S3 indexer that archives metrics to the configured S3 bucket
+	S3Indexer IndexerType = "s3"
+	// StatsD indexer that sends metrics to the configured StatsD server
+	StatsDIndexer IndexerType = "statsd"
+	// BigQuery indexer that streams metrics to the configured BigQuery table
+	BigQueryIndexer IndexerType = "bigquery"
+	// PostgreSQL indexer that upserts metrics into the configured PostgreSQL table
+	PostgresIndexer IndexerType = "postgres"
+	// Kusto indexer that queues metrics for ingestion into the configured Azure Data Explorer table
+	KustoIndexer IndexerType = "kusto"
+	// WebSocket indexer that streams metrics as JSON frames to the configured WebSocket endpoint
+	WebSocketIndexer IndexerType = "websocket"
+	// SQLite indexer that upserts metrics into the configured table of a SQLite database file
+	SQLiteIndexer IndexerType = "sqlite"
+	// NATS indexer that publishes metrics to the configured NATS JetStream subject
+	NATSIndexer IndexerType = "nats"
+	// Redis indexer that adds metrics to the configured Redis stream
+	RedisIndexer IndexerType = "redis"
+	// GELF indexer that sends metrics as GELF messages to the configured Graylog input
+	GELFIndexer IndexerType = "gelf"
+	// Webhook indexer that posts metrics to the configured HTTP endpoint
+	WebhookIndexer IndexerType = "webhook"
+	// Multi indexer that sends metrics to every one of its child indexers
+	MultiIndexer IndexerType = "multi"
+)
+
+// Bulk indexer defaults
+const (
+	// defaultBulkTimeout timeout used by the bulk indexers when none is configured
+	defaultBulkTimeout = 10 * time.Minute
+	// defaultFlushBytes flush threshold in bytes used by the bulk indexers when none is configured
+	defaultFlushBytes int = 5e+6
+	// defaultMaxRetries number of retries of the failed requests when none is configured
+	defaultMaxRetries = 3
+	// defaultRetryBackoff initial backoff between retries when none is configured
+	defaultRetryBackoff = 100 * time.Millisecond
+	// defaultRetryMaxBackoff maximum backoff between retries when none is configured
+	defaultRetryMaxBackoff = 10 * time.Second
+	// defaultHealthCheckTimeout timeout of the cluster health check when none is configured
+	defaultHealthCheckTimeout = 10 * time.Second
 )
 
+// defaultContentType Content-Type of the requests sent by the JSON based HTTP indexers when none is configured
+const defaultContentType = "application/json"
+
+// ErrNoDocuments returned, along with a message suitable for logging, when indexing an empty list of documents
+var ErrNoDocuments = errors.New("no documents to index")
+
+// ErrDocumentsFailed returned, wrapped along with the indexing result, when any document fails to be indexed in
+// fail-fast mode
+var ErrDocumentsFailed = errors.New("documents failed to be indexed")
+
+// ErrDocumentNotFound returned by Get when the document doesn't exist
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrUnreachable returned, wrapped, by Ping when the backend can't be reached
+var ErrUnreachable = errors.New("backend unreachable")
+
+// ErrUnauthorized returned, wrapped, by Ping when the backend rejects the credentials
+var ErrUnauthorized = errors.New("backend rejected the credentials")
+
+// ErrUnhealthy returned, wrapped, by Ping when the backend is reachable but reports a failure
+var ErrUnhealthy = errors.New("backend unhealthy")
+
 // Indexer interface
 type Indexer interface {
-	Index([]interface{}, IndexingOpts) (string, error)
-	new(IndexerConfig) error
+	Index(context.Context, []interface{}, IndexingOpts) (string, error)
+	IndexWithResult(context.Context, []interface{}, IndexingOpts) (IndexingResult, error)
+	// Ping checks the backend is reachable, i.e. for readiness probes, returning an error wrapping ErrUnreachable,
+	// ErrUnauthorized or ErrUnhealthy otherwise
+	Ping(context.Context) error
+	Close() error
+	New(IndexerConfig) error
+}
+
+// StreamIndexer is implemented by the indexers able to index the documents as they are received
+type StreamIndexer interface {
+	IndexStream(context.Context, <-chan interface{}, IndexingOpts) (string, error)
+}
+
+// NDJSONIndexer is implemented by the indexers able to index the raw JSON lines of a newline-delimited JSON stream
+type NDJSONIndexer interface {
+	IndexNDJSON(context.Context, io.Reader, IndexingOpts) (string, error)
+}
+
+// DocumentReader is implemented by the indexers able to read the indexed documents back, to verify them
+type DocumentReader interface {
+	Get(ctx context.Context, id string) (json.RawMessage, error)
+	Count(ctx context.Context) (int, error)
 }
 
 // Indexing options
 type IndexingOpts struct {
-	MetricName string // MetricName, required for local indexer
+	MetricName      string   // MetricName, required for local indexer, set as the metricName field of the indexed documents lacking one
+	JobName         string   // JobName set as the jobName field of the indexed documents lacking one
+	DocumentIDField string   // DocumentIDField document field used as document ID, defaults to the document content hash
+	SkipDedup       bool     // SkipDedup index redundant documents instead of skipping them
+	TimeBasedSuffix string   // TimeBasedSuffix time layout of the suffix appended to the index name, i.e. 2006.01.02 for daily indices
+	AddTimestamp    bool     // AddTimestamp set the indexing time on every document, documents that aren't objects are wrapped in an envelope
+	TimestampField  string   // TimestampField field set by AddTimestamp, defaults to metadata.timestamp
+	DryRun          bool     // DryRun encode and deduplicate the documents without sending them
+	RoutingField    string   // RoutingField document field used as routing value, documents without it aren't routed
+	Refresh         string   // Refresh refresh parameter of the bulk requests: true, false or wait_for, defaults to the server setting
+	Pipeline        string   // Pipeline ingest pipeline processing every indexed document
+	LabelFields     []string // LabelFields document fields used as labels of the Loki streams
+	Action          string   // Action bulk action: index, create or update, defaults to index, or create for data streams
+	IndexField      string   // IndexField document field holding the target index, documents without it go to the default index
+	// DisableHTMLEscape encode the documents without escaping the <, > and & characters of their strings, the
+	// document IDs are unchanged
+	DisableHTMLEscape bool
+	// StreamingHash hash the documents while encoding them instead of hashing their canonical form, allocating
+	// less. The IDs are unchanged for the documents already encoded in canonical form, such as maps of strings
+	// and integers, while differently typed documents with the same content are no longer deduplicated
+	StreamingHash bool
+	// Indent indent the documents written by the local indexer, unless line delimited
+	Indent bool
+	// FailFast return ErrDocumentsFailed when any document is rejected by the bulk indexer, instead of only
+	// counting it as failed
+	FailFast bool
+	// Transform applied to every document before encoding it, the documents it fails on are dropped and counted
+	// in the transformFailed stat
+	Transform func(interface{}) (interface{}, error)
+	// SkipUnencodable skip the documents failing to be encoded, such as the ones holding channels or functions, and
+	// count them in the unencodable stat, instead of failing the whole indexing call. The documents are encoded once
+	// more to find them
+	SkipUnencodable bool
+	// SampleRate fraction of the documents indexed, between 0 and 1, for the noisy high-frequency metrics. The
+	// documents are kept based on their content hash, so the same documents are kept every time, and the dropped ones
+	// are counted in the sampledout stat. Every document is indexed when 0, the default
+	SampleRate float64
+	// IncludeFields top-level fields the map documents are projected down to before encoding them, reducing the
+	// stored size. Other documents are indexed unchanged
+	IncludeFields []string
+	// Flatten flatten the nested maps and arrays of the map documents into dotted keys before encoding them, a.b.c,
+	// the array elements being suffixed with their index, a.0, for the tools not handling nested objects. Other
+	// documents are indexed unchanged
+	Flatten bool
+	// OnDocumentID called with every document sent to the backend along with its ID, either the content hash or
+	// the DocumentIDField value, letting the callers correlate the indexed documents with their source. Only called
+	// by the backends identifying the documents, on the calling goroutine
+	OnDocumentID func(document interface{}, id string)
+	// VersionField document field holding the external version of the document, sent along with it so the bulk
+	// items older than the indexed documents are rejected as version conflicts
+	VersionField string
+	// VersionType version type of the VersionField versions: external or external_gte, defaults to external
+	VersionType string
+	// MaxFailureReasons number of failed bulk items whose error is captured in the FailureReasons of the indexing
+	// result, the reasons being truncated, so the failures are diagnosed without enabling verbose logging. None are
+	// captured when 0, the default. Only supported by the ES and OpenSearch indexers
+	MaxFailureReasons int
+}
+
+// IndexingResult holds the outcome of an indexing operation
+type IndexingResult struct {
+	// Created number of documents created
+	Created int
+	// Updated number of documents updated
+	Updated int
+	// Skipped number of redundant documents skipped, always reported by String
+	Skipped int
+	// Failed number of documents that failed to be indexed
+	Failed int
+	// Duration time taken to index the documents
+	Duration time.Duration
+	// Stats per result counters as reported by the indexer backend
+	Stats map[string]int
+	// BulkStats statistics of the bulk indexer, when used by the indexer backend
+	BulkStats BulkStats
+	// FlushLatency percentiles of the bulk indexer flush durations, when used by the indexer backend
+	FlushLatency FlushLatency
+	// FailureReasons errors of the first failed bulk items, up to the MaxFailureReasons of the indexing options
+	FailureReasons []FailureReason
+}
+
+// FailureReason error of a bulk item that failed to be indexed
+type FailureReason struct {
+	// DocumentID ID of the failed document
+	DocumentID string
+	// Status status code of the bulk item, 0 when the whole bulk request failed
+	Status int
+	// Type error type, i.e. mapper_parsing_exception, empty when the whole bulk request failed
+	Type string
+	// Reason error reason, truncated to 256 bytes
+	Reason string
+}
+
+// FlushLatency percentiles of the bulk indexer flush durations
+type FlushLatency struct {
+	Flushes int
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+}
+
+// BulkStats holds the statistics reported by the bulk indexer
+type BulkStats struct {
+	NumAdded    uint64
+	NumFlushed  uint64
+	NumFailed   uint64
+	NumIndexed  uint64
+	NumCreated  uint64
+	NumUpdated  uint64
+	NumDeleted  uint64
+	NumRequests uint64
+}
+
+// CompressionStats statistics of the gzip encoded responses decompressed by the indexer transport
+type CompressionStats struct {
+	Responses         uint64
+	CompressedBytes   uint64
+	DecompressedBytes uint64
+}
+
+// BytesSaved number of bytes the compression of the responses saved from being transferred
+func (s CompressionStats) BytesSaved() int64 {
+	return int64(s.DecompressedBytes) - int64(s.CompressedBytes)
+}
+
+// add accumulates the given statistics
+func (s *BulkStats) add(other BulkStats) {
+	s.NumAdded += other.NumAdded
+	s.NumFlushed += other.NumFlushed
+	s.NumFailed += other.NumFailed
+	s.NumIndexed += other.NumIndexed
+	s.NumCreated += other.NumCreated
+	s.NumUpdated += other.NumUpdated
+	s.NumDeleted += other.NumDeleted
+	s.NumRequests += other.NumRequests
+}
+
+// String returns the human readable form of the indexing result
+func (r IndexingResult) String() string {
+	var statString string
+	for stat, val := range r.Stats {
+		statString += fmt.Sprintf(" %s=%d", stat, val)
+	}
+	// Always reported, so consumers parsing the result can rely on it
+	statString += fmt.Sprintf(" redundantskipped=%d", r.Skipped)
+	if r.BulkStats.NumRequests > 0 {
+		statString += fmt.Sprintf(" flushed=%d requests=%d", r.BulkStats.NumFlushed, r.BulkStats.NumRequests)
+	}
+	if r.FlushLatency.Flushes > 0 {
+		statString += fmt.Sprintf(" p50=%v p95=%v p99=%v", r.FlushLatency.P50.Truncate(time.Millisecond), r.FlushLatency.P95.Truncate(time.Millisecond), r.FlushLatency.P99.Truncate(time.Millisecond))
+	}
+	return fmt.Sprintf("Indexing finished in %v:%v", r.Duration.Truncate(time.Millisecond), statString)
+}
+
+// newIndexingResult builds an indexing result from the per result counters
+func newIndexingResult(stats map[string]int, skipped int, duration time.Duration) IndexingResult {
+	return IndexingResult{
+		Created:  stats["created"],
+		Updated:  stats["updated"],
+		Skipped:  skipped,
+		Failed:   stats["failed"],
+		Duration: duration,
+		Stats:    stats,
+	}
+}
+
+// ServerVersion version reported by the cluster an indexer is connected to
+type ServerVersion struct {
+	// Distribution elasticsearch or opensearch
+	Distribution string
+	// Number version number, i.e. 8.11.1
+	Number string
+	// Major major version
+	Major int
+	// Minor minor version
+	Minor int
+}
+
+// atLeast reports whether the version is major.minor or later
+func (v ServerVersion) atLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
 }
 
 // IndexerType type of indexer
@@ -44,14 +339,150 @@ type IndexerConfig struct {
 	Type IndexerType `yaml:"type"`
 	// Servers List of ElasticSearch instances
 	Servers []string `yaml:"esServers"`
+	// AllowEnvFallback allow an empty Servers list, letting the client read the server URL from the environment
+	AllowEnvFallback bool `yaml:"allowEnvFallback"`
 	// Index index to send documents to server
 	Index string `yaml:"defaultIndex"`
+	// AutoSanitize replace the invalid characters of the index name instead of rejecting it
+	AutoSanitize bool `yaml:"autoSanitize"`
+	// Bucket bucket of the s3 indexer, Index being the prefix of the object keys
+	Bucket string `yaml:"bucket"`
+	// Dataset dataset of the bigquery indexer, optionally prefixed with its project as project.dataset
+	Dataset string `yaml:"dataset"`
+	// Database database of the kusto indexer
+	Database string `yaml:"database"`
+	// StreamMaxLen maximum number of entries of the stream of the redis indexer, the oldest ones being trimmed as
+	// the documents are added. Unlimited by default
+	StreamMaxLen int64 `yaml:"streamMaxLen"`
+	// Username username used for basic authentication
+	Username string `yaml:"username"`
+	// Password password used for basic authentication
+	Password string `yaml:"password"`
+	// APIKey base64 encoded API key, takes precedence over basic authentication
+	APIKey string `yaml:"apiKey"`
+	// UsernameFile file holding the username, read by NewIndexer and overriding Username
+	UsernameFile string `yaml:"usernameFile"`
+	// PasswordFile file holding the password, read by NewIndexer and overriding Password
+	PasswordFile string `yaml:"passwordFile"`
+	// APIKeyFile file holding the API key, read by NewIndexer and overriding APIKey
+	APIKeyFile string `yaml:"apiKeyFile"`
+	// TenantID Azure AD tenant of the service principal used by the kusto indexer, given by Username and Password
+	TenantID string `yaml:"tenantID"`
+	// Token authentication token of the Splunk HTTP Event Collector or Datadog API key
+	Token string `yaml:"token"`
+	// ContentType Content-Type header of the requests sent by the Datadog, Loki, Splunk and webhook indexers, for the
+	// endpoints expecting application/x-ndjson or a vendor media type. Defaults to application/json
+	ContentType string `yaml:"contentType"`
+	// Headers extra headers of the requests sent by the webhook indexer, overriding the ones set by the indexer
+	Headers map[string]string `yaml:"headers"`
+	// WebhookFormat format of the batches of documents posted by the webhook indexer, json sending them as a JSON
+	// array and ndjson as newline delimited JSON, with the application/x-ndjson content type unless ContentType is
+	// set. Defaults to json
+	WebhookFormat string `yaml:"webhookFormat"`
+	// AWSSigV4 sign the OpenSearch requests with AWS SigV4 using the default AWS credential chain
+	AWSSigV4 bool `yaml:"awsSigV4"`
+	// Region AWS region of the OpenSearch service or S3 bucket, taken from the AWS configuration when not set
+	Region string `yaml:"region"`
 	// InsecureSkipVerify disable TLS ceriticate verification
 	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+	// CACertPath path of the PEM encoded CA certificates used to verify the server certificate
+	CACertPath string `yaml:"caCertPath"`
+	// ClientCert PEM encoded client certificate presented to the servers requiring mutual TLS, along with ClientKey
+	ClientCert []byte `yaml:"clientCert"`
+	// ClientKey PEM encoded private key of the client certificate
+	ClientKey []byte `yaml:"clientKey"`
+	// ClientCertPath path of the PEM encoded client certificate, used when ClientCert isn't set
+	ClientCertPath string `yaml:"clientCertPath"`
+	// ClientKeyPath path of the PEM encoded private key of the client certificate, used when ClientKey isn't set
+	ClientKeyPath string `yaml:"clientKeyPath"`
+	// Transport HTTP transport used instead of the default one built from the TLS settings
+	Transport http.RoundTripper `yaml:"-"`
+	// MaxIdleConns maximum number of idle connections kept open across all hosts by the default transport,
+	// unlimited by default
+	MaxIdleConns int `yaml:"maxIdleConns"`
+	// MaxIdleConnsPerHost maximum number of idle connections kept open per host by the default transport, defaults
+	// to the number of workers so the parallel bulk requests reuse their connections
+	MaxIdleConnsPerHost int `yaml:"maxIdleConnsPerHost"`
+	// IdleConnTimeout time an idle connection of the default transport is kept open, unlimited by default
+	IdleConnTimeout time.Duration `yaml:"idleConnTimeout"`
+	// Compression compress the request bodies with gzip and ask the ES and OpenSearch clusters for gzip encoded
+	// responses, reported by CompressionStats
+	Compression bool `yaml:"compression"`
+	// HealthCheckTimeout timeout of the cluster health check performed when creating the indexer, defaults to 10 seconds
+	HealthCheckTimeout time.Duration `yaml:"healthCheckTimeout"`
+	// SkipHealthCheck don't check the cluster health when creating the indexer
+	SkipHealthCheck bool `yaml:"skipHealthCheck"`
+	// SkipIndexCreation don't create the index when it doesn't exist
+	SkipIndexCreation bool `yaml:"skipIndexCreation"`
+	// IndexMappings mappings and settings sent when creating the index
+	IndexMappings json.RawMessage `yaml:"indexMappings"`
+	// ILMPolicy lifecycle policy attached to the index when creating it, an ILM policy for Elasticsearch and an ISM
+	// policy for OpenSearch
+	ILMPolicy string `yaml:"ilmPolicy"`
+	// IndexAlias alias pointed at the index when creating it, being moved from the indices it was set on
+	IndexAlias string `yaml:"indexAlias"`
+	// UseDataStream index the documents in a data stream, created along with its index template when it doesn't exist
+	UseDataStream bool `yaml:"useDataStream"`
 	// Directory to save metrics files in
 	MetricsDirectory string `yaml:"metricsDirectory"`
 	// Create tarball
 	CreateTarball bool `yaml:"createTarball"`
 	// TarBall name
 	TarballName string `yaml:"tarballName"`
+	// MetricsRegisterer registerer of the Prometheus indexing metrics, no metrics are collected when not set
+	MetricsRegisterer prometheus.Registerer `yaml:"-"`
+	// Logger logger used to report the indexer activity, messages are discarded when not set
+	Logger Logger `yaml:"-"`
+	// Writer destination of the stdout indexer, defaults to os.Stdout
+	Writer io.Writer `yaml:"-"`
+	// TimestampField document field holding the timestamp of the influxdb points, loki log lines and GELF messages,
+	// defaults to timestamp
+	TimestampField string `yaml:"timestampField"`
+	// LineDelimited local indexer writes documents as JSON lines to <index>.json
+	LineDelimited bool `yaml:"lineDelimited"`
+	// BulkTimeout timeout of the bulk requests, given to the cluster, defaults to 10 minutes
+	BulkTimeout time.Duration `yaml:"bulkTimeout"`
+	// FlushTimeout client side timeout of every bulk request sent by the ES and OpenSearch bulk indexers, a flush
+	// timing out failing alone, reported in the NumFailed bulk stat, while the indexing call goes on until the
+	// deadline of its context. Unlimited by default
+	FlushTimeout time.Duration `yaml:"flushTimeout"`
+	// FlushBytes flush threshold in bytes of the bulk indexer, defaults to 5MB
+	FlushBytes int `yaml:"flushBytes"`
+	// FlushDocs maximum number of documents sent per bulk indexer flush, unlimited by default
+	FlushDocs int `yaml:"flushDocs"`
+	// MaxDocBytes maximum size in bytes of the documents sent to ES and OpenSearch, the larger documents being skipped
+	// and counted in the oversized stat instead of failing their bulk request. Unlimited by default
+	MaxDocBytes int `yaml:"maxDocBytes"`
+	// AtomicStats count the results of the ES and OpenSearch bulk items with atomic counters instead of a mutex
+	// guarded map, reducing the lock contention between the bulk indexer workers at high throughput
+	AtomicStats bool `yaml:"atomicStats"`
+	// FlushInterval hold the ES and OpenSearch bulk indexers open between the indexing calls and flush them every
+	// interval, the calls returning once the documents are queued, reporting them in the queued stat. The queued
+	// documents are also flushed when reaching FlushBytes or FlushDocs and on Close. Disabled by default
+	FlushInterval time.Duration `yaml:"flushInterval"`
+	// ReuseBulkIndexer keep the ES and OpenSearch bulk indexers open across the indexing calls instead of creating
+	// them per call, closing them on Close. Every call still waits for its documents to be flushed, the calls using
+	// the bulk indexers one at a time. Not supported along with FlushInterval or FlushDocs. Disabled by default
+	ReuseBulkIndexer bool `yaml:"reuseBulkIndexer"`
+	// NumWorkers number of bulk indexer workers, defaults to the number of CPUs
+	NumWorkers int `yaml:"numWorkers"`
+	// MaxConcurrentFlushes maximum number of bulk requests in flight across the indexing calls, unlimited by default
+	MaxConcurrentFlushes int `yaml:"maxConcurrentFlushes"`
+	// MaxRetries number of retries of the requests failed with 429, 502, 503 or 504, defaults to 3. A negative value disables retries
+	MaxRetries int `yaml:"maxRetries"`
+	// RetryBackoff initial backoff ceiling between retries, doubled at every attempt, defaults to 100 milliseconds. Retries wait a random duration up to the ceiling
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	// RetryMaxBackoff maximum backoff between retries, defaults to 10 seconds
+	RetryMaxBackoff time.Duration `yaml:"retryMaxBackoff"`
+	// CircuitBreakerFailures number of consecutive failed indexing calls opening the circuit breaker, disabled when 0
+	CircuitBreakerFailures int `yaml:"circuitBreakerFailures"`
+	// CircuitBreakerCooldown time the circuit breaker stays open before letting a trial call through, defaults to 30 seconds
+	CircuitBreakerCooldown time.Duration `yaml:"circuitBreakerCooldown"`
+	// DefaultOpts indexing options of the indexers created by NewIndexer, used for the options left unset by the
+	// indexing calls. The indexer returned implements the same optional interfaces as the configured one
+	DefaultOpts IndexingOpts `yaml:"defaultOpts"`
+	// Indexers configurations of the child indexers of the multi indexer, every document being sent to all of them
+	Indexers []IndexerConfig `yaml:"indexers"`
+	// ContinueOnError keep sending the documents to the other child indexers of the multi indexer when one fails
+	ContinueOnError bool `yaml:"continueOnError"`
 }
@@ -0,0 +1,1186 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dataStreamTimestampField timestamp field required by data streams
+const dataStreamTimestampField = "@timestamp"
+
+// defaultTimestampField field set with the indexing time when no TimestampField is given
+const defaultTimestampField = "metadata.timestamp"
+
+// documentEnvelopeField field of the envelope holding the documents that aren't JSON objects
+const documentEnvelopeField = "document"
+
+// Distributions reported by the clusters
+const (
+	elasticsearchDistribution = "elasticsearch"
+	opensearchDistribution    = "opensearch"
+)
+
+// indexNameInvalidChars characters not allowed in index names
+const indexNameInvalidChars = `\/*?"<>| ,#:`
+
+// maxIndexNameBytes maximum length in bytes of index names
+const maxIndexNameBytes = 255
+
+// transformFailedStat stat counting the documents the Transform of the indexing options failed on
+const transformFailedStat = "transformFailed"
+
+// unencodableStat stat counting the documents skipped for failing to be encoded
+const unencodableStat = "unencodable"
+
+// sampledOutStat stat counting the documents dropped by sampling
+const sampledOutStat = "sampledout"
+
+// queuedStat stat counting the documents queued for asynchronous indexing
+const queuedStat = "queued"
+
+// oversizedStat stat counting the documents skipped for exceeding the maximum document size
+const oversizedStat = "oversized"
+
+// parallelEncodingThreshold number of documents from which they're encoded by a pool of workers
+const parallelEncodingThreshold = 1000
+
+// jsonMarshalerType and textMarshalerType types of the values encoding themselves, which aren't flattened
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// hashDocument returns the SHA-256 hash of the canonical form of the given encoded document, streamed to the hash
+func hashDocument(j []byte) string {
+	hasher := sha256.New()
+	writeCanonicalJSON(hasher, j)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// writeCanonicalJSON writes the canonical form of the given encoded document to w, with sorted object keys, no
+// insignificant whitespace and normalized numbers, so that documents with the same content have the same hash
+// whatever their Go representation. Documents that can't be decoded are written as is
+func writeCanonicalJSON(w io.Writer, j []byte) {
+	if value, err := canonicalValue(j); err == nil {
+		// json.Encoder writes nothing when the encoding fails
+		if err := json.NewEncoder(trimNewlineWriter{w}).Encode(value); err == nil {
+			return
+		}
+	}
+	_, _ = w.Write(j)
+}
+
+// canonicalValue decodes the given encoded document with its numbers normalized, its maps being encoded
+// with sorted keys
+func canonicalValue(j []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(value), nil
+}
+
+// normalizeNumbers replaces the numbers of the given decoded value with their normalized form
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		return normalizeNumber(v)
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = normalizeNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = normalizeNumbers(child)
+		}
+	}
+	return value
+}
+
+// normalizeNumber returns the normalized form of the given number: integral values are written as integers,
+// i.e. 1.0 and 1e2 as 1 and 100, and other values as the shortest representation of their float64 value
+func normalizeNumber(n json.Number) json.Number {
+	if i, err := n.Int64(); err == nil {
+		return json.Number(strconv.FormatInt(i, 10))
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return n
+	}
+	// Integral values are exactly represented up to 2^53
+	if f == math.Trunc(f) && math.Abs(f) <= 1<<53 {
+		return json.Number(strconv.FormatInt(int64(f), 10))
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// encodeDocument encodes the document shaped by shapeDocument and returns it along with its content hash
+func encodeDocument(document interface{}, opts IndexingOpts) ([]byte, string, error) {
+	if opts.StreamingHash {
+		return streamDocument(shapeDocument(document, opts), !opts.DisableHTMLEscape)
+	}
+	j, err := marshalDocument(shapeDocument(document, opts), !opts.DisableHTMLEscape)
+	if err != nil {
+		return nil, "", err
+	}
+	return j, hashDocument(j), nil
+}
+
+// streamDocument encodes the document to both the body buffer and the hasher in a single pass, hashing the
+// document as encoded rather than its canonical form
+func streamDocument(document interface{}, escapeHTML bool) ([]byte, string, error) {
+	var buf bytes.Buffer
+	hasher := sha256.New()
+	encoder := json.NewEncoder(trimNewlineWriter{io.MultiWriter(&buf, hasher)})
+	encoder.SetEscapeHTML(escapeHTML)
+	if err := encoder.Encode(document); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// shapeDocument returns the document projected down to IncludeFields and flattened when Flatten is set
+func shapeDocument(document interface{}, opts IndexingOpts) interface{} {
+	document = projectDocument(document, opts.IncludeFields)
+	if opts.Flatten {
+		document = flattenDocument(document)
+	}
+	return document
+}
+
+// flattenDocument returns the given map document with its nested maps and arrays flattened into dotted keys, the
+// array elements being suffixed with their index. Empty maps and arrays, as well as the values encoding themselves,
+// are kept as is. Other documents are returned as is
+func flattenDocument(document interface{}) interface{} {
+	fields, ok := document.(map[string]interface{})
+	if !ok {
+		return document
+	}
+	flattened := make(map[string]interface{}, len(fields))
+	// Sorted so the colliding keys are resolved the same way every time
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		flattenValue(flattened, key, reflect.ValueOf(fields[key]))
+	}
+	return flattened
+}
+
+// flattenValue sets the given value in flattened at key, its nested maps and arrays being flattened into dotted keys
+func flattenValue(flattened map[string]interface{}, key string, value reflect.Value) {
+	if !value.IsValid() {
+		flattened[key] = nil
+		return
+	}
+	if value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+	}
+	nested := false
+	switch value.Kind() {
+	case reflect.Map:
+		nested = value.Type().Key().Kind() == reflect.String && value.Len() > 0
+	case reflect.Slice, reflect.Array:
+		// Byte slices are encoded as base64 strings
+		nested = value.Type().Elem().Kind() != reflect.Uint8 && value.Len() > 0
+	}
+	if !nested || value.Type().Implements(jsonMarshalerType) || value.Type().Implements(textMarshalerType) {
+		flattened[key] = value.Interface()
+		return
+	}
+	if value.Kind() != reflect.Map {
+		for i := 0; i < value.Len(); i++ {
+			flattenValue(flattened, key+"."+strconv.Itoa(i), value.Index(i))
+		}
+		return
+	}
+	keys := make([]string, 0, value.Len())
+	for _, mapKey := range value.MapKeys() {
+		keys = append(keys, mapKey.String())
+	}
+	sort.Strings(keys)
+	for _, mapKey := range keys {
+		flattenValue(flattened, key+"."+mapKey, value.MapIndex(reflect.ValueOf(mapKey).Convert(value.Type().Key())))
+	}
+}
+
+// projectDocument returns the given map document projected down to the given fields, other documents being returned
+// as is, as well as every document when no fields are given
+func projectDocument(document interface{}, fields []string) interface{} {
+	fieldsMap, ok := document.(map[string]interface{})
+	if !ok || len(fields) == 0 {
+		return document
+	}
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, exists := fieldsMap[field]; exists {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+// marshalDocument encodes the document with a json.Encoder, escaping the HTML characters when escapeHTML is set
+func marshalDocument(document interface{}, escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(trimNewlineWriter{&buf})
+	encoder.SetEscapeHTML(escapeHTML)
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// remarshalDocument encodes the document built from the given encoded one, keeping its HTML escaping: JSON
+// documents can only hold raw HTML characters in their strings when encoded without escaping them
+func remarshalDocument(document interface{}, j []byte) ([]byte, error) {
+	return marshalDocument(document, !bytes.ContainsAny(j, "<>&"))
+}
+
+// trimNewlineWriter drops the newline terminating the documents written by json.Encoder, which writes every
+// document with a single call
+type trimNewlineWriter struct {
+	w io.Writer
+}
+
+// Write writes p without its trailing newline
+func (t trimNewlineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	if _, err := t.w.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// documentID returns the ID of the given encoded document, taken from idField when
+// present in the document, or from its already computed content hash otherwise
+func documentID(j []byte, idField, hash string) string {
+	if id, exists := documentField(j, idField); exists {
+		return id
+	}
+	return hash
+}
+
+// reportDocumentID passes the document along with its ID to the OnDocumentID callback, if any
+func reportDocumentID(opts IndexingOpts, document interface{}, id string) {
+	if opts.OnDocumentID != nil {
+		opts.OnDocumentID(document, id)
+	}
+}
+
+// documentField returns the value of the given top-level field of the encoded document, if present
+func documentField(j []byte, field string) (string, bool) {
+	if field == "" {
+		return "", false
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return "", false
+	}
+	if value, exists := fields[field]; exists && value != nil {
+		return fmt.Sprint(value), true
+	}
+	return "", false
+}
+
+// timeBasedIndex returns the index name suffixed with the given time formatted with layout
+func timeBasedIndex(index, layout string, t time.Time) string {
+	return fmt.Sprintf("%s-%s", index, t.UTC().Format(layout))
+}
+
+// documentIndex returns the target index held in the IndexField of the given encoded document, normalized as the
+// default index and suffixed according to TimeBasedSuffix, reporting false when the document doesn't hold it
+func documentIndex(j []byte, opts IndexingOpts, sanitize bool, t time.Time) (string, bool, error) {
+	index, exists := documentField(j, opts.IndexField)
+	if !exists {
+		return "", false, nil
+	}
+	index = strings.ToLower(index)
+	if sanitize {
+		index = sanitizeIndexName(index)
+	}
+	if err := validateIndexName(index); err != nil {
+		return "", false, err
+	}
+	if opts.TimeBasedSuffix != "" {
+		index = timeBasedIndex(index, opts.TimeBasedSuffix, t)
+	}
+	return index, true, nil
+}
+
+// withTimestamp returns the given encoded document with field set to t, unless the document already has it
+func withTimestamp(j []byte, field string, t time.Time) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(j, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("document %s is not a JSON object, cannot set %s", j, field)
+	}
+	if _, exists := fields[field]; exists {
+		return j, nil
+	}
+	fields[field], _ = json.Marshal(t.UTC().Format(time.RFC3339Nano))
+	return remarshalDocument(fields, j)
+}
+
+// dataStreamTemplate returns the index template backing the given data stream, mappings holds the optional
+// mappings and settings of the backing indices
+func dataStreamTemplate(name string, mappings json.RawMessage) ([]byte, error) {
+	template := map[string]interface{}{
+		"index_patterns": []string{name + "*"},
+		"data_stream":    map[string]interface{}{},
+		// Takes precedence over the built-in templates, which use priority 100
+		"priority": 200,
+	}
+	if len(mappings) > 0 {
+		template["template"] = mappings
+	}
+	return json.Marshal(template)
+}
+
+// withLifecyclePolicy returns the given index mappings and settings with the setting attaching the given lifecycle
+// policy to the index, the mappings being returned as is when no policy is given
+func withLifecyclePolicy(mappings json.RawMessage, setting, policy string) (json.RawMessage, error) {
+	if policy == "" {
+		return mappings, nil
+	}
+	body := make(map[string]json.RawMessage)
+	if len(mappings) > 0 {
+		if err := json.Unmarshal(mappings, &body); err != nil {
+			return nil, fmt.Errorf("invalid index mappings: %s", err)
+		}
+	}
+	settings := make(map[string]interface{})
+	if len(body["settings"]) > 0 {
+		if err := json.Unmarshal(body["settings"], &settings); err != nil {
+			return nil, fmt.Errorf("invalid index settings: %s", err)
+		}
+	}
+	settings[setting] = policy
+	body["settings"], _ = json.Marshal(settings)
+	return json.Marshal(body)
+}
+
+// aliasActions returns the alias actions pointing the given alias at index, removing it from the indices of the
+// given get alias response, so the alias is swapped atomically. aliases is empty when the alias doesn't exist
+func aliasActions(alias, index string, aliases io.Reader) ([]byte, error) {
+	current := make(map[string]json.RawMessage)
+	if aliases != nil {
+		if err := json.NewDecoder(aliases).Decode(&current); err != nil {
+			return nil, fmt.Errorf("invalid alias %s: %s", alias, err)
+		}
+	}
+	var actions []map[string]map[string]string
+	for aliasedIndex := range current {
+		if aliasedIndex != index {
+			actions = append(actions, map[string]map[string]string{"remove": {"index": aliasedIndex, "alias": alias}})
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i]["remove"]["index"] < actions[j]["remove"]["index"] })
+	actions = append(actions, map[string]map[string]string{"add": {"index": index, "alias": alias}})
+	return json.Marshal(map[string]interface{}{"actions": actions})
+}
+
+// decodeServerVersion decodes the version of the given cluster info response, the distribution defaulting
+// to elasticsearch as Elasticsearch doesn't report it
+func decodeServerVersion(body io.Reader) (ServerVersion, error) {
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(body).Decode(&info); err != nil {
+		return ServerVersion{}, fmt.Errorf("cannot decode the cluster info: %s", err)
+	}
+	version := ServerVersion{Distribution: info.Version.Distribution, Number: info.Version.Number}
+	if version.Distribution == "" {
+		version.Distribution = elasticsearchDistribution
+	}
+	parts := strings.SplitN(version.Number, ".", 3)
+	var err error
+	if version.Major, err = strconv.Atoi(parts[0]); err != nil || len(parts) < 2 {
+		return ServerVersion{}, fmt.Errorf("invalid version number %q", version.Number)
+	}
+	if version.Minor, err = strconv.Atoi(parts[1]); err != nil {
+		return ServerVersion{}, fmt.Errorf("invalid version number %q", version.Number)
+	}
+	return version, nil
+}
+
+// documentFields returns the fields to set on every document according to the indexing options
+func documentFields(opts IndexingOpts, t time.Time) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if opts.MetricName != "" {
+		fields["metricName"] = opts.MetricName
+	}
+	if opts.JobName != "" {
+		fields["jobName"] = opts.JobName
+	}
+	if opts.AddTimestamp {
+		field := opts.TimestampField
+		if field == "" {
+			field = defaultTimestampField
+		}
+		fields[field] = t.UTC().Format(time.RFC3339Nano)
+	}
+	return fields
+}
+
+// decorateDocument returns the given encoded document with the fields it lacks set, wrapped in an envelope when it
+// isn't a JSON object
+func decorateDocument(j []byte, fields map[string]interface{}) ([]byte, error) {
+	if len(fields) == 0 {
+		return j, nil
+	}
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("Cannot decode document %s: %s", j, err)
+	}
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{documentEnvelopeField: document}
+	}
+	for field, value := range fields {
+		setField(object, strings.Split(field, "."), value)
+	}
+	return remarshalDocument(object, j)
+}
+
+// objectDocument returns the given encoded document, wrapped in the document envelope when it isn't a JSON object
+func objectDocument(j []byte) []byte {
+	if bytes.HasPrefix(bytes.TrimSpace(j), []byte("{")) {
+		return j
+	}
+	return []byte(fmt.Sprintf(`{%q:%s}`, documentEnvelopeField, j))
+}
+
+// setField sets value at the given path of the object, creating the missing intermediate objects
+func setField(object map[string]interface{}, path []string, value interface{}) {
+	current, exists := object[path[0]]
+	if len(path) == 1 {
+		if !exists {
+			object[path[0]] = value
+		}
+		return
+	}
+	if !exists {
+		current = make(map[string]interface{})
+		object[path[0]] = current
+	}
+	if child, ok := current.(map[string]interface{}); ok {
+		setField(child, path[1:], value)
+	}
+}
+
+// documentIterator returns the next document to index, ok is false once there are no more documents
+type documentIterator func() (document interface{}, ok bool, err error)
+
+// sliceIterator returns an iterator over the given documents, until the context is done
+func sliceIterator(ctx context.Context, documents []interface{}) documentIterator {
+	return func() (interface{}, bool, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		if len(documents) == 0 {
+			return nil, false, nil
+		}
+		document := documents[0]
+		documents = documents[1:]
+		return document, true, nil
+	}
+}
+
+// channelIterator returns an iterator over the documents received from the given channel, until it's closed
+// or the context is done
+func channelIterator(ctx context.Context, documents <-chan interface{}) documentIterator {
+	return func() (interface{}, bool, error) {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case document, ok := <-documents:
+			return document, ok, nil
+		}
+	}
+}
+
+// transformingIterator returns an iterator over the documents returned by next transformed by transform,
+// skipping the documents it fails on and counting them in failed
+func transformingIterator(next documentIterator, transform func(interface{}) (interface{}, error), failed *int) documentIterator {
+	if transform == nil {
+		return next
+	}
+	return func() (interface{}, bool, error) {
+		for {
+			document, ok, err := next()
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			if document, err = transform(document); err == nil {
+				return document, true, nil
+			}
+			*failed++
+		}
+	}
+}
+
+// filteringIterator returns an iterator over the documents returned by next kept by filter
+func filteringIterator(next documentIterator, filter *documentFilter) documentIterator {
+	if !filter.active() {
+		return next
+	}
+	return func() (interface{}, bool, error) {
+		for {
+			document, ok, err := next()
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			if filter.keep(document) {
+				return document, true, nil
+			}
+		}
+	}
+}
+
+// transformDocuments returns the documents transformed by transform along with the number of documents it failed on,
+// which are dropped
+func transformDocuments(documents []interface{}, transform func(interface{}) (interface{}, error)) ([]interface{}, int) {
+	if transform == nil {
+		return documents, 0
+	}
+	transformed := make([]interface{}, 0, len(documents))
+	failed := 0
+	for _, document := range documents {
+		document, err := transform(document)
+		if err != nil {
+			failed++
+			continue
+		}
+		transformed = append(transformed, document)
+	}
+	return transformed, failed
+}
+
+// filtersDocuments reports whether the indexing options transform or filter the documents, which is done by
+// indexTransformed before indexing them
+func filtersDocuments(opts IndexingOpts) bool {
+	return opts.Transform != nil || opts.SkipUnencodable || opts.SampleRate != 0
+}
+
+// indexTransformed indexes with index the documents transformed by the Transform of the indexing options,
+// reporting the documents it failed on in the transformFailed stat. The documents are then filtered, reporting the
+// ones dropped in the unencodable and sampledout stats
+func indexTransformed(ctx context.Context, documents []interface{}, opts IndexingOpts, index func(context.Context, []interface{}, IndexingOpts) (IndexingResult, error)) (IndexingResult, error) {
+	if err := validateSampleRate(opts.SampleRate); err != nil {
+		return IndexingResult{}, err
+	}
+	documents, failed := transformDocuments(documents, opts.Transform)
+	filter := &documentFilter{opts: opts}
+	documents = filter.documents(documents)
+	opts.Transform, opts.SkipUnencodable, opts.SampleRate = nil, false, 0
+	if len(documents) == 0 {
+		result := IndexingResult{Stats: make(map[string]int)}
+		addTransformFailed(&result, failed)
+		filter.addStats(&result)
+		return result, nil
+	}
+	result, err := index(ctx, documents, opts)
+	if err != nil {
+		return result, err
+	}
+	addTransformFailed(&result, failed)
+	filter.addStats(&result)
+	return result, nil
+}
+
+// documentFilter drops the documents failing to be encoded when SkipUnencodable is set and the documents sampled
+// out when SampleRate is set, counting them
+type documentFilter struct {
+	opts        IndexingOpts
+	unencodable int
+	sampledOut  int
+}
+
+// active reports whether the filter may drop any document
+func (f *documentFilter) active() bool {
+	return f.opts.SkipUnencodable || f.sampling()
+}
+
+// sampling reports whether the documents are sampled
+func (f *documentFilter) sampling() bool {
+	return f.opts.SampleRate > 0 && f.opts.SampleRate < 1
+}
+
+// keep reports whether the document is kept, counting it when dropped
+func (f *documentFilter) keep(document interface{}) bool {
+	if !f.sampling() {
+		if !f.opts.SkipUnencodable {
+			return true
+		}
+		if _, err := marshalDocument(shapeDocument(document, f.opts), !f.opts.DisableHTMLEscape); err != nil {
+			f.unencodable++
+			return false
+		}
+		return true
+	}
+	_, hash, err := encodeDocument(document, f.opts)
+	if err != nil {
+		if f.opts.SkipUnencodable {
+			f.unencodable++
+			return false
+		}
+		// Kept for the indexer to report the encoding error
+		return true
+	}
+	if !sampledIn(hash, f.opts.SampleRate) {
+		f.sampledOut++
+		return false
+	}
+	return true
+}
+
+// documents returns the documents kept
+func (f *documentFilter) documents(documents []interface{}) []interface{} {
+	if !f.active() {
+		return documents
+	}
+	kept := make([]interface{}, 0, len(documents))
+	for _, document := range documents {
+		if f.keep(document) {
+			kept = append(kept, document)
+		}
+	}
+	return kept
+}
+
+// addStats adds the number of documents dropped to the result stats
+func (f *documentFilter) addStats(result *IndexingResult) {
+	addUnencodable(result, f.unencodable)
+	if f.sampledOut == 0 {
+		return
+	}
+	if result.Stats == nil {
+		result.Stats = make(map[string]int)
+	}
+	result.Stats[sampledOutStat] += f.sampledOut
+}
+
+// validateSampleRate checks the sample rate is a fraction of the documents, 0 disabling sampling
+func validateSampleRate(rate float64) error {
+	if rate < 0 || rate > 1 || math.IsNaN(rate) {
+		return fmt.Errorf("invalid sample rate: %v", rate)
+	}
+	return nil
+}
+
+// sampledIn reports whether the document of the given content hash is kept when sampling at rate. The hashes being
+// uniformly distributed, rate of the documents are kept, the same ones every time
+func sampledIn(hash string, rate float64) bool {
+	if len(hash) < 16 {
+		return true
+	}
+	value, err := strconv.ParseUint(hash[:16], 16, 64)
+	if err != nil {
+		return true
+	}
+	return float64(value) < rate*math.MaxUint64
+}
+
+// addUnencodable adds the number of documents skipped for failing to be encoded to the result stats
+func addUnencodable(result *IndexingResult, unencodable int) {
+	if unencodable == 0 {
+		return
+	}
+	if result.Stats == nil {
+		result.Stats = make(map[string]int)
+	}
+	result.Stats[unencodableStat] += unencodable
+}
+
+// addTransformFailed adds the number of documents the transform failed on to the result stats
+func addTransformFailed(result *IndexingResult, failed int) {
+	if failed == 0 {
+		return
+	}
+	if result.Stats == nil {
+		result.Stats = make(map[string]int)
+	}
+	result.Stats[transformFailedStat] += failed
+}
+
+// resultMessage returns the message reporting the indexing result, the result of the documents indexed before an
+// error being reported along with it
+func resultMessage(result IndexingResult, err error) (string, error) {
+	if err != nil && len(result.Stats) == 0 {
+		return "", err
+	}
+	return result.String(), err
+}
+
+// copyStats returns a copy of the given stats
+func copyStats(stats map[string]int) map[string]int {
+	copied := make(map[string]int, len(stats))
+	for stat, val := range stats {
+		copied[stat] = val
+	}
+	return copied
+}
+
+// addOversized adds the number of documents skipped for exceeding the maximum document size to the result stats
+func addOversized(result *IndexingResult, oversized int) {
+	if oversized == 0 {
+		return
+	}
+	result.Stats[oversizedStat] += oversized
+}
+
+// failedDocumentsError returns ErrDocumentsFailed along with the number of failed documents of the result, nil when
+// none failed
+func failedDocumentsError(result IndexingResult) error {
+	if result.Failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d of %d documents failed", ErrDocumentsFailed, result.Failed, result.Created+result.Updated+result.Failed)
+}
+
+// encodedDocument JSON encoding of a document along with its content hash
+type encodedDocument struct {
+	document interface{}
+	j        []byte
+	hash     string
+}
+
+// encodedIterator returns the next encoded document, ok is false once there are no more documents
+type encodedIterator func() (encoded encodedDocument, ok bool, err error)
+
+// newEncodedDocument encodes and hashes the given document
+func newEncodedDocument(document interface{}, opts IndexingOpts) (encodedDocument, error) {
+	j, hash, err := encodeDocument(document, opts)
+	if err != nil {
+		return encodedDocument{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+	}
+	return encodedDocument{document: document, j: j, hash: hash}, nil
+}
+
+// ndjsonIterator returns an iterator over the JSON lines read from r, used as is and hashed from their raw bytes.
+// Blank lines are skipped
+func ndjsonIterator(ctx context.Context, r io.Reader) encodedIterator {
+	reader := bufio.NewReader(r)
+	line := 0
+	return func() (encodedDocument, bool, error) {
+		for {
+			if err := ctx.Err(); err != nil {
+				return encodedDocument{}, false, err
+			}
+			j, err := reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return encodedDocument{}, false, fmt.Errorf("error reading NDJSON: %s", err)
+			}
+			if len(j) == 0 && err == io.EOF {
+				return encodedDocument{}, false, nil
+			}
+			line++
+			if j = bytes.TrimSpace(j); len(j) == 0 {
+				continue
+			}
+			if !json.Valid(j) {
+				return encodedDocument{}, false, fmt.Errorf("invalid JSON document on line %d", line)
+			}
+			return encodedDocument{document: json.RawMessage(j), j: j, hash: hashDocument(j)}, true, nil
+		}
+	}
+}
+
+// encodingIterator returns an iterator encoding the documents returned by next one at a time
+func encodingIterator(next documentIterator, opts IndexingOpts) encodedIterator {
+	return func() (encodedDocument, bool, error) {
+		document, ok, err := next()
+		if err != nil || !ok {
+			return encodedDocument{}, false, err
+		}
+		encoded, err := newEncodedDocument(document, opts)
+		if err != nil {
+			return encodedDocument{}, false, err
+		}
+		return encoded, true, nil
+	}
+}
+
+// encodeDocuments returns an iterator over the given documents, encoded by a worker per CPU
+func encodeDocuments(ctx context.Context, documents []interface{}, opts IndexingOpts) encodedIterator {
+	return encodeDocumentsWithWorkers(ctx, documents, opts, runtime.NumCPU())
+}
+
+// encodeDocumentsWithWorkers encodes and hashes the given documents with a pool of workers. The iterator returns
+// them in their original order, so deduplication keeps the same documents regardless of the number of workers
+func encodeDocumentsWithWorkers(ctx context.Context, documents []interface{}, opts IndexingOpts, workers int) encodedIterator {
+	if workers <= 1 || len(documents) < parallelEncodingThreshold {
+		return encodingIterator(sliceIterator(ctx, documents), opts)
+	}
+	encoded := make([]encodedDocument, len(documents))
+	errs := make([]error, len(documents))
+	chunkSize := (len(documents) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for first := 0; first < len(documents); first += chunkSize {
+		last := first + chunkSize
+		if last > len(documents) {
+			last = len(documents)
+		}
+		wg.Add(1)
+		go func(first, last int) {
+			defer wg.Done()
+			for i := first; i < last && ctx.Err() == nil; i++ {
+				encoded[i], errs[i] = newEncodedDocument(documents[i], opts)
+			}
+		}(first, last)
+	}
+	wg.Wait()
+	next := 0
+	return func() (encodedDocument, bool, error) {
+		if err := ctx.Err(); err != nil {
+			return encodedDocument{}, false, err
+		}
+		if next == len(encoded) {
+			return encodedDocument{}, false, nil
+		}
+		i := next
+		next++
+		if errs[i] != nil {
+			return encodedDocument{}, false, errs[i]
+		}
+		return encoded[i], true, nil
+	}
+}
+
+// dryRun deduplicates and decorates the encoded documents like the indexers do, without sending them
+func dryRun(next encodedIterator, opts IndexingOpts) (IndexingResult, error) {
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	validated := 0
+	for {
+		encoded, ok, err := next()
+		if err != nil {
+			return IndexingResult{}, err
+		}
+		if !ok {
+			break
+		}
+		j, docHashKey := encoded.j, encoded.hash
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if _, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+		validated++
+	}
+	return newIndexingResult(map[string]int{"validated": validated}, redundantSkipped, time.Since(start)), nil
+}
+
+// encodeBatch encodes, deduplicates and decorates the given documents like the indexers do, returning them along
+// with the number of redundant documents skipped
+func encodeBatch(ctx context.Context, documents []interface{}, opts IndexingOpts) ([][]byte, int, error) {
+	fields := documentFields(opts, time.Now().UTC())
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	var batch [][]byte
+	next := encodeDocuments(ctx, documents, opts)
+	for {
+		encoded, ok, err := next()
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			break
+		}
+		if _, exists := docHash[encoded.hash]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		j, err := decorateDocument(encoded.j, fields)
+		if err != nil {
+			return nil, 0, err
+		}
+		batch = append(batch, j)
+		if !opts.SkipDedup {
+			docHash[encoded.hash] = true
+		}
+	}
+	return batch, redundantSkipped, nil
+}
+
+// validateRefresh checks the refresh parameter of the bulk requests is supported
+func validateRefresh(refresh string) error {
+	switch refresh {
+	case "", "true", "false", "wait_for":
+		return nil
+	}
+	return fmt.Errorf("invalid refresh value: %s", refresh)
+}
+
+// bulkAction returns the bulk action of the indexed documents, defaulting to index, or to create for data streams
+// as it's the only action they support
+func bulkAction(action string, dataStream bool) (string, error) {
+	switch action {
+	case "":
+		if dataStream {
+			return "create", nil
+		}
+		return "index", nil
+	case "index", "update":
+		if dataStream {
+			return "", fmt.Errorf("bulk action %s not supported by data streams", action)
+		}
+		return action, nil
+	case "create":
+		return action, nil
+	}
+	return "", fmt.Errorf("invalid bulk action: %s", action)
+}
+
+// versionType returns the version type of the bulk items, defaulting to external when a version field is set
+func versionType(opts IndexingOpts) (string, error) {
+	if opts.VersionField == "" {
+		if opts.VersionType != "" {
+			return "", fmt.Errorf("version type %s set without a version field", opts.VersionType)
+		}
+		return "", nil
+	}
+	if opts.Action == "update" {
+		return "", fmt.Errorf("versioning isn't supported by the update action")
+	}
+	switch opts.VersionType {
+	case "":
+		return "external", nil
+	case "external", "external_gte":
+		return opts.VersionType, nil
+	}
+	return "", fmt.Errorf("invalid version type: %s", opts.VersionType)
+}
+
+// documentVersion returns the version held by the given field of the encoded document, nil when it's not set
+func documentVersion(j []byte, field string) (*int64, error) {
+	value, exists := documentField(j, field)
+	if !exists {
+		return nil, nil
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 0 {
+		return nil, fmt.Errorf("invalid version %s", value)
+	}
+	return &version, nil
+}
+
+// flushSemaphore limits the number of bulk requests in flight, a nil semaphore not limiting them
+type flushSemaphore chan struct{}
+
+// flushSemaphoreKey context key marking the flushes holding a slot of the semaphore
+type flushSemaphoreKey struct{}
+
+// newFlushSemaphore returns the semaphore allowing max bulk requests in flight, nil when max is 0
+func newFlushSemaphore(max int) flushSemaphore {
+	if max == 0 {
+		return nil
+	}
+	return make(flushSemaphore, max)
+}
+
+// acquire blocks until a slot is available, returning the flush context marked as holding it. It keeps waiting
+// when ctx is done, as the flush is sent anyway and the slots are released by the flushes in flight once done
+func (s flushSemaphore) acquire(ctx context.Context) context.Context {
+	if s == nil {
+		return ctx
+	}
+	s <- struct{}{}
+	return context.WithValue(ctx, flushSemaphoreKey{}, true)
+}
+
+// release releases the slot held by the given flush context
+func (s flushSemaphore) release(ctx context.Context) {
+	if held, _ := ctx.Value(flushSemaphoreKey{}).(bool); held {
+		<-s
+	}
+}
+
+// flushLatencies records the duration of the bulk indexer flushes
+type flushLatencies struct {
+	sync.Mutex
+	durations []time.Duration
+}
+
+// flushStartKey context key holding the start time of a flush
+type flushStartKey struct{}
+
+// hooks returns flush hooks calling the given ones and recording the flush durations, not counting the time
+// spent waiting for a slot of the flush semaphore
+func (l *flushLatencies) hooks(onStart func(context.Context) context.Context, onEnd func(context.Context)) (func(context.Context) context.Context, func(context.Context)) {
+	start := func(ctx context.Context) context.Context {
+		return context.WithValue(onStart(ctx), flushStartKey{}, time.Now())
+	}
+	end := func(ctx context.Context) {
+		if t, ok := ctx.Value(flushStartKey{}).(time.Time); ok {
+			l.Lock()
+			l.durations = append(l.durations, time.Since(t))
+			l.Unlock()
+		}
+		onEnd(ctx)
+	}
+	return start, end
+}
+
+// percentiles returns the percentiles of the recorded flush durations and resets them
+func (l *flushLatencies) percentiles() FlushLatency {
+	l.Lock()
+	defer l.Unlock()
+	durations := l.durations
+	l.durations = nil
+	if len(durations) == 0 {
+		return FlushLatency{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	// Nearest-rank percentile
+	percentile := func(p float64) time.Duration {
+		return durations[int(math.Ceil(p/100*float64(len(durations))))-1]
+	}
+	return FlushLatency{
+		Flushes: len(durations),
+		P50:     percentile(50),
+		P95:     percentile(95),
+		P99:     percentile(99),
+	}
+}
+
+// contentType returns the Content-Type header of the requests sent by the JSON based HTTP indexers
+func contentType(indexerConfig IndexerConfig) string {
+	if indexerConfig.ContentType == "" {
+		return defaultContentType
+	}
+	return indexerConfig.ContentType
+}
+
+// updateBody returns the partial document body of the bulk update action for the given encoded document
+func updateBody(j []byte) []byte {
+	return []byte(fmt.Sprintf(`{"doc":%s}`, objectDocument(j)))
+}
+
+// validateIndexName checks the given lowercase index name follows the index naming rules
+func validateIndexName(index string) error {
+	if index == "" {
+		return fmt.Errorf("invalid index name %q: must not be empty", index)
+	}
+	if index == "." || index == ".." {
+		return fmt.Errorf("invalid index name %q: must not be . or ..", index)
+	}
+	if len(index) > maxIndexNameBytes {
+		return fmt.Errorf("invalid index name %q: must not be longer than %d bytes", index, maxIndexNameBytes)
+	}
+	if i := strings.IndexAny(index, indexNameInvalidChars); i >= 0 {
+		return fmt.Errorf("invalid index name %q: must not contain %q", index, index[i])
+	}
+	if strings.IndexAny(index, "-_+") == 0 {
+		return fmt.Errorf("invalid index name %q: must not start with %q", index, index[0])
+	}
+	return nil
+}
+
+// sanitizeIndexName returns the given lowercase index name with the invalid characters replaced with underscores,
+// without the invalid leading characters and truncated to the maximum length
+func sanitizeIndexName(index string) string {
+	index = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(indexNameInvalidChars, r) {
+			return '_'
+		}
+		return r
+	}, index)
+	index = strings.TrimLeft(index, "-_+")
+	if len(index) > maxIndexNameBytes {
+		index = strings.ToValidUTF8(index[:maxIndexNameBytes], "")
+	}
+	return index
+}
+
+// pingFailed returns the error of a failed ping of the given backend, wrapping the given Ping error
+func pingFailed(backend string, pingErr error, cause interface{}) error {
+	return fmt.Errorf("%s %w: %v", backend, pingErr, cause)
+}
+
+// pingStatus returns the error of the given health check response status code of the backend, nil when healthy,
+// i.e. 2xx or one of the allowed status codes
+func pingStatus(backend string, statusCode int, allowed ...int) error {
+	if statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	for _, code := range allowed {
+		if statusCode == code {
+			return nil
+		}
+	}
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return pingFailed(backend, ErrUnauthorized, fmt.Sprintf("status code %d", statusCode))
+	}
+	return pingFailed(backend, ErrUnhealthy, fmt.Sprintf("status code %d", statusCode))
+}
+
+// pingHTTP sends a GET request to the health endpoint of the backend, authorize setting the request credentials.
+// The response status codes outside 2xx fail the ping, except the allowed ones
+func pingHTTP(ctx context.Context, backend string, client *http.Client, url string, authorize func(*http.Request), allowed ...int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return pingFailed(backend, ErrUnreachable, err)
+	}
+	if authorize != nil {
+		authorize(req)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return pingFailed(backend, ErrUnreachable, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return pingStatus(backend, resp.StatusCode, allowed...)
+}
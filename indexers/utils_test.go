@@ -0,0 +1,631 @@
+package indexers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tests for utils.go", func() {
+	Context("Tests for documentID()", func() {
+		It("Returns the content hash when no ID field is given", func() {
+			Expect(documentID([]byte(`{"uuid":"a"}`), "", "hash")).To(Equal("hash"))
+		})
+
+		It("Returns the value of the ID field", func() {
+			Expect(documentID([]byte(`{"uuid":"a"}`), "uuid", "hash")).To(Equal("a"))
+			Expect(documentID([]byte(`{"uuid":12345678901}`), "uuid", "hash")).To(Equal("12345678901"))
+		})
+
+		It("Falls back to the content hash when the ID field is missing", func() {
+			Expect(documentID([]byte(`{"name":"a"}`), "uuid", "hash")).To(Equal("hash"))
+			Expect(documentID([]byte(`42`), "uuid", "hash")).To(Equal("hash"))
+		})
+	})
+
+	Context("Tests for encodeDocument()", func() {
+		It("Projects the map documents down to the included fields", func() {
+			opts := IndexingOpts{IncludeFields: []string{"uuid", "value", "missing"}}
+			j, hash, err := encodeDocument(map[string]interface{}{"uuid": "abc", "value": 1, "labels": map[string]string{"node": "worker"}}, opts)
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"uuid":"abc","value":1}`))
+			Expect(hash).To(Equal(hashDocument(j)))
+			j, _, err = encodeDocument([]int{1, 2}, opts)
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`[1,2]`))
+		})
+
+		It("Flattens the nested map documents into dotted keys", func() {
+			document := map[string]interface{}{
+				"uuid":   "abc",
+				"labels": map[string]string{"node": "worker-0"},
+				"pods":   []interface{}{map[string]interface{}{"name": "nginx", "ready": true}, "pending"},
+				"quantiles": map[string]interface{}{
+					"P99": map[string]int{"value": 42},
+					"raw": json.RawMessage(`{"value":1}`),
+				},
+				"empty": map[string]interface{}{},
+				"none":  nil,
+			}
+			j, _, err := encodeDocument(document, IndexingOpts{Flatten: true})
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{
+				"uuid": "abc",
+				"labels.node": "worker-0",
+				"pods.0.name": "nginx",
+				"pods.0.ready": true,
+				"pods.1": "pending",
+				"quantiles.P99.value": 42,
+				"quantiles.raw": {"value": 1},
+				"empty": {},
+				"none": null
+			}`))
+			j, _, err = encodeDocument([]interface{}{map[string]int{"value": 1}}, IndexingOpts{Flatten: true})
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`[{"value":1}]`))
+		})
+
+		It("Returns the document along with its hash", func() {
+			documents := []interface{}{
+				"example document",
+				42,
+				3.14,
+				map[string]interface{}{"key1": "<value1>", "key2": 123, "key3": []int{1, 2}},
+			}
+			for _, document := range documents {
+				j, hash, err := encodeDocument(document, IndexingOpts{})
+				Expect(err).To(BeNil())
+				expected, _ := json.Marshal(document)
+				Expect(j).To(Equal(expected))
+				Expect(hash).To(Equal(hashDocument(j)))
+			}
+		})
+
+		It("Returns the same document and hash when streaming the canonical documents", func() {
+			documents := []interface{}{
+				"example document",
+				42,
+				3.14,
+				map[string]interface{}{"key1": "<value1>", "key2": 123, "key3": []int{1, 2}},
+			}
+			for _, document := range documents {
+				j, hash, err := encodeDocument(document, IndexingOpts{})
+				Expect(err).To(BeNil())
+				streamed, streamedHash, err := encodeDocument(document, IndexingOpts{StreamingHash: true})
+				Expect(err).To(BeNil())
+				Expect(streamed).To(Equal(j))
+				Expect(streamedHash).To(Equal(hash))
+			}
+		})
+
+		It("Hashes the documents as encoded when streaming", func() {
+			document := map[string]interface{}{"url": "<a>", "value": 1.5}
+			j, hash, err := encodeDocument(document, IndexingOpts{StreamingHash: true, DisableHTMLEscape: true})
+			Expect(err).To(BeNil())
+			Expect(string(j)).To(Equal(`{"url":"<a>","value":1.5}`))
+			sum := sha256.Sum256(j)
+			Expect(hash).To(Equal(hex.EncodeToString(sum[:])))
+			_, _, err = encodeDocument(make(chan string), IndexingOpts{StreamingHash: true})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Returns the same hash for documents with the same content", func() {
+			type sample struct {
+				Value      float64 `json:"value"`
+				MetricName string  `json:"metricName"`
+			}
+			documents := []interface{}{
+				map[string]interface{}{"metricName": "podLatency", "value": 1},
+				map[string]interface{}{"value": 1.0, "metricName": "podLatency"},
+				map[string]interface{}{"value": json.Number("1e0"), "metricName": "podLatency"},
+				sample{Value: 1, MetricName: "podLatency"},
+				json.RawMessage(`{ "value": 1.00, "metricName": "podLatency" }`),
+			}
+			_, expected, err := encodeDocument(documents[0], IndexingOpts{})
+			Expect(err).To(BeNil())
+			for _, document := range documents[1:] {
+				_, hash, err := encodeDocument(document, IndexingOpts{})
+				Expect(err).To(BeNil())
+				Expect(hash).To(Equal(expected), "document %v", document)
+			}
+		})
+
+		It("Returns different hashes for documents with different content", func() {
+			_, hash, _ := encodeDocument(map[string]interface{}{"value": 1}, IndexingOpts{})
+			_, otherHash, _ := encodeDocument(map[string]interface{}{"value": 1.5}, IndexingOpts{})
+			Expect(hash).ToNot(Equal(otherHash))
+		})
+
+		It("Escapes HTML characters unless disabled, returning the same hash", func() {
+			document := map[string]interface{}{"url": "http://x?a=1&b=<c>"}
+			j, hash, err := encodeDocument(document, IndexingOpts{})
+			Expect(err).To(BeNil())
+			Expect(string(j)).To(Equal(`{"url":"http://x?a=1\u0026b=\u003cc\u003e"}`))
+			raw, rawHash, err := encodeDocument(document, IndexingOpts{DisableHTMLEscape: true})
+			Expect(err).To(BeNil())
+			Expect(string(raw)).To(Equal(`{"url":"http://x?a=1&b=<c>"}`))
+			Expect(rawHash).To(Equal(hash))
+		})
+
+		It("Returns err for documents that can't be encoded", func() {
+			_, _, err := encodeDocument(make(chan string), IndexingOpts{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Tests for writeCanonicalJSON()", func() {
+		It("Sorts the keys and normalizes the numbers", func() {
+			var buf bytes.Buffer
+			writeCanonicalJSON(&buf, []byte(`{"b": [1.0, 2.5e0, -0], "a": {"d": 1e2, "c": 12345678901234567890}}`))
+			Expect(buf.String()).To(Equal(`{"a":{"c":1.2345678901234567e+19,"d":100},"b":[1,2.5,0]}`))
+		})
+
+		It("Writes invalid documents as is", func() {
+			var buf bytes.Buffer
+			writeCanonicalJSON(&buf, []byte(`{"a":`))
+			Expect(buf.String()).To(Equal(`{"a":`))
+		})
+	})
+
+	Context("Tests for documentField()", func() {
+		It("Returns the value of the field", func() {
+			value, exists := documentField([]byte(`{"shard":"a","replica":1}`), "replica")
+			Expect(exists).To(BeTrue())
+			Expect(value).To(Equal("1"))
+		})
+
+		It("Returns false when the field is missing or the document isn't an object", func() {
+			_, exists := documentField([]byte(`{"shard":"a"}`), "replica")
+			Expect(exists).To(BeFalse())
+			_, exists = documentField([]byte(`42`), "replica")
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("Tests for timeBasedIndex()", func() {
+		It("Appends the formatted time to the index name", func() {
+			t := time.Date(2024, time.January, 15, 23, 30, 0, 0, time.UTC)
+			Expect(timeBasedIndex("metrics", "2006.01.02", t)).To(Equal("metrics-2024.01.15"))
+			Expect(timeBasedIndex("metrics", "2006.01", t)).To(Equal("metrics-2024.01"))
+		})
+
+		It("Uses the UTC date", func() {
+			t := time.Date(2024, time.January, 15, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600))
+			Expect(timeBasedIndex("metrics", "2006.01.02", t)).To(Equal("metrics-2024.01.16"))
+		})
+	})
+
+	Context("Tests for documentIterator", func() {
+		It("Iterates over the documents of a slice", func() {
+			next := sliceIterator(context.Background(), []interface{}{1, 2})
+			for _, expected := range []interface{}{1, 2} {
+				document, ok, err := next()
+				Expect(err).To(BeNil())
+				Expect(ok).To(BeTrue())
+				Expect(document).To(Equal(expected))
+			}
+			_, ok, err := next()
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("Iterates over the documents of a channel until it's closed", func() {
+			documents := make(chan interface{}, 1)
+			next := channelIterator(context.Background(), documents)
+			documents <- 1
+			close(documents)
+			document, ok, err := next()
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(document).To(Equal(1))
+			_, ok, err = next()
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("Returns the context error when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, _, err := sliceIterator(ctx, []interface{}{1})()
+			Expect(err).To(Equal(context.Canceled))
+			_, _, err = channelIterator(ctx, make(chan interface{}))()
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+
+	Context("Tests for encodedIterator", func() {
+		// documents returns n documents where every other document is a duplicate of the previous one
+		documents := func(n int) []interface{} {
+			var documents []interface{}
+			for i := 0; i < n; i++ {
+				documents = append(documents, map[string]interface{}{"value": i / 2})
+			}
+			return documents
+		}
+
+		It("Returns the encoded documents in their original order regardless of the number of workers", func() {
+			docs := documents(2 * parallelEncodingThreshold)
+			var expected []encodedDocument
+			next := encodeDocumentsWithWorkers(context.Background(), docs, IndexingOpts{}, 1)
+			for {
+				encoded, ok, err := next()
+				Expect(err).To(BeNil())
+				if !ok {
+					break
+				}
+				expected = append(expected, encoded)
+			}
+			Expect(expected).To(HaveLen(len(docs)))
+			for _, workers := range []int{2, 3, 8} {
+				next := encodeDocumentsWithWorkers(context.Background(), docs, IndexingOpts{}, workers)
+				for i := range expected {
+					encoded, ok, err := next()
+					Expect(err).To(BeNil())
+					Expect(ok).To(BeTrue())
+					Expect(encoded).To(Equal(expected[i]))
+				}
+				_, ok, err := next()
+				Expect(err).To(BeNil())
+				Expect(ok).To(BeFalse())
+			}
+		})
+
+		It("Skips the same documents regardless of the number of workers", func() {
+			docs := documents(2 * parallelEncodingThreshold)
+			for _, workers := range []int{1, 2, 8} {
+				result, err := dryRun(encodeDocumentsWithWorkers(context.Background(), docs, IndexingOpts{}, workers), IndexingOpts{})
+				Expect(err).To(BeNil())
+				Expect(result.Stats).To(HaveKeyWithValue("validated", parallelEncodingThreshold))
+				Expect(result.Skipped).To(Equal(parallelEncodingThreshold))
+			}
+		})
+
+		It("Returns the error of the first document that can't be encoded", func() {
+			docs := documents(2 * parallelEncodingThreshold)
+			docs[10] = make(chan string)
+			docs[1500] = func() {}
+			next := encodeDocumentsWithWorkers(context.Background(), docs, IndexingOpts{}, 4)
+			var err error
+			encodedDocs := 0
+			for {
+				var ok bool
+				if _, ok, err = next(); !ok {
+					break
+				}
+				encodedDocs++
+			}
+			Expect(encodedDocs).To(Equal(10))
+			Expect(err.Error()).To(HavePrefix("Cannot encode document"))
+			Expect(err.Error()).To(ContainSubstring("chan string"))
+		})
+
+		It("Returns the context error when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, _, err := encodeDocumentsWithWorkers(ctx, documents(2*parallelEncodingThreshold), IndexingOpts{}, 4)()
+			Expect(err).To(Equal(context.Canceled))
+			_, _, err = encodingIterator(sliceIterator(ctx, documents(1)), IndexingOpts{})()
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+
+	Context("Tests for documentFilter", func() {
+		It("Keeps roughly the sample rate of the documents, the same ones every time", func() {
+			documents := make([]interface{}, 10000)
+			for i := range documents {
+				documents[i] = map[string]interface{}{"metricName": "podLatency", "value": i}
+			}
+			filter := &documentFilter{opts: IndexingOpts{SampleRate: 0.25}}
+			kept := filter.documents(documents)
+			Expect(len(kept)).To(BeNumerically("~", 2500, 200))
+			Expect(filter.sampledOut).To(Equal(len(documents) - len(kept)))
+			Expect((&documentFilter{opts: IndexingOpts{SampleRate: 0.25}}).documents(documents)).To(Equal(kept))
+			Expect(len((&documentFilter{opts: IndexingOpts{SampleRate: 0.5}}).documents(documents))).To(BeNumerically(">", len(kept)))
+		})
+
+		It("Keeps every document when not sampling", func() {
+			documents := []interface{}{1, 2, 3}
+			for _, rate := range []float64{0, 1} {
+				filter := &documentFilter{opts: IndexingOpts{SampleRate: rate}}
+				Expect(filter.documents(documents)).To(Equal(documents))
+				Expect(filter.sampledOut).To(BeZero())
+			}
+		})
+
+		It("Keeps the unencodable documents unless skipping them", func() {
+			documents := []interface{}{make(chan string), 1}
+			filter := &documentFilter{opts: IndexingOpts{SampleRate: 0.999999}}
+			Expect(filter.documents(documents)).To(HaveLen(2))
+			filter = &documentFilter{opts: IndexingOpts{SampleRate: 0.999999, SkipUnencodable: true}}
+			Expect(filter.documents(documents)).To(Equal([]interface{}{1}))
+			Expect(filter.unencodable).To(Equal(1))
+		})
+
+		It("Returns err invalid sample rate", func() {
+			Expect(validateSampleRate(1.5)).To(MatchError("invalid sample rate: 1.5"))
+			Expect(validateSampleRate(-0.1)).To(MatchError("invalid sample rate: -0.1"))
+			Expect(validateSampleRate(0.5)).To(Succeed())
+		})
+	})
+
+	Context("Tests for documentIndex()", func() {
+		It("Normalizes and suffixes the target index", func() {
+			t := time.Date(2024, time.January, 15, 23, 30, 0, 0, time.UTC)
+			opts := IndexingOpts{IndexField: "tenant", TimeBasedSuffix: "2006.01.02"}
+			index, exists, err := documentIndex([]byte(`{"tenant":"Tenant A"}`), opts, true, t)
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeTrue())
+			Expect(index).To(Equal("tenant_a-2024.01.15"))
+		})
+
+		It("Reports documents without index field", func() {
+			_, exists, err := documentIndex([]byte(`{"value":1}`), IndexingOpts{IndexField: "tenant"}, false, time.Now())
+			Expect(err).To(BeNil())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("Tests for decodeServerVersion()", func() {
+		It("Defaults the distribution to elasticsearch", func() {
+			version, err := decodeServerVersion(strings.NewReader(`{"version":{"number":"8.11.1","build_flavor":"default"}}`))
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal(ServerVersion{Distribution: "elasticsearch", Number: "8.11.1", Major: 8, Minor: 11}))
+			Expect(version.atLeast(7, 9)).To(BeTrue())
+			Expect(version.atLeast(8, 12)).To(BeFalse())
+		})
+
+		It("Returns err invalid version number", func() {
+			_, err := decodeServerVersion(strings.NewReader(`{"version":{"number":"8"}}`))
+			Expect(err).To(MatchError(`invalid version number "8"`))
+		})
+	})
+
+	Context("Tests for withTimestamp()", func() {
+		t := time.Date(2024, time.January, 15, 23, 30, 0, 0, time.UTC)
+
+		It("Sets the timestamp when missing", func() {
+			j, err := withTimestamp([]byte(`{"value":1}`), "@timestamp", t)
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"value":1,"@timestamp":"2024-01-15T23:30:00Z"}`))
+		})
+
+		It("Keeps the document timestamp", func() {
+			j, err := withTimestamp([]byte(`{"@timestamp":"2023-01-01T00:00:00Z"}`), "@timestamp", t)
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"@timestamp":"2023-01-01T00:00:00Z"}`))
+		})
+
+		It("Returns err for documents that aren't objects", func() {
+			_, err := withTimestamp([]byte(`"example document"`), "@timestamp", t)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Tests for decorateDocument()", func() {
+		It("Sets the fields on object documents", func() {
+			j, err := decorateDocument([]byte(`{"value":1,"metadata":{"uuid":"1234"}}`), map[string]interface{}{"metadata.timestamp": "now", "job": "node-density"})
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"value":1,"metadata":{"uuid":"1234","timestamp":"now"},"job":"node-density"}`))
+		})
+
+		It("Keeps the fields the document already holds", func() {
+			fields := documentFields(IndexingOpts{MetricName: "podLatency", JobName: "node-density", AddTimestamp: true}, time.Now())
+			j, err := decorateDocument([]byte(`{"metricName":"nodeStatus","jobName":"cluster-density","metadata":{"timestamp":"now"}}`), fields)
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"metricName":"nodeStatus","jobName":"cluster-density","metadata":{"timestamp":"now"}}`))
+			j, err = decorateDocument([]byte(`{"jobName":"cluster-density","metadata":"none"}`), fields)
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"metricName":"podLatency","jobName":"cluster-density","metadata":"none"}`))
+		})
+
+		It("Wraps the documents that aren't objects in an envelope", func() {
+			j, err := decorateDocument([]byte(`3.14`), map[string]interface{}{"metadata.timestamp": "now"})
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"document":3.14,"metadata":{"timestamp":"now"}}`))
+		})
+
+		It("Keeps the HTML characters of unescaped documents", func() {
+			j, err := decorateDocument([]byte(`{"url":"a&b"}`), map[string]interface{}{"job": "<node-density>"})
+			Expect(err).To(BeNil())
+			Expect(string(j)).To(ContainSubstring(`"url":"a&b"`))
+			Expect(string(j)).To(ContainSubstring(`"job":"<node-density>"`))
+		})
+
+		It("Returns the document unchanged without fields", func() {
+			j, err := decorateDocument([]byte(`42`), documentFields(IndexingOpts{}, time.Now()))
+			Expect(err).To(BeNil())
+			Expect(j).To(Equal([]byte(`42`)))
+		})
+	})
+
+	Context("Tests for validateIndexName()", func() {
+		It("Accepts valid index names", func() {
+			for _, index := range []string{"go-commons", "perf.results-2023", ".hidden", "a+b_c"} {
+				Expect(validateIndexName(index)).To(Succeed())
+			}
+		})
+
+		It("Rejects the invalid index names", func() {
+			Expect(validateIndexName("")).To(MatchError(`invalid index name "": must not be empty`))
+			Expect(validateIndexName("..")).To(MatchError(`invalid index name "..": must not be . or ..`))
+			Expect(validateIndexName("go,commons")).To(MatchError(`invalid index name "go,commons": must not contain ','`))
+			Expect(validateIndexName("go*")).To(MatchError(`invalid index name "go*": must not contain '*'`))
+			Expect(validateIndexName("-go")).To(MatchError(`invalid index name "-go": must not start with '-'`))
+			Expect(validateIndexName("+go")).To(MatchError(`invalid index name "+go": must not start with '+'`))
+			Expect(validateIndexName(strings.Repeat("a", 256))).To(MatchError(ContainSubstring("must not be longer than 255 bytes")))
+		})
+	})
+
+	Context("Tests for sanitizeIndexName()", func() {
+		It("Replaces the invalid characters", func() {
+			Expect(sanitizeIndexName(`go commons/results?#1`)).To(Equal("go_commons_results__1"))
+		})
+
+		It("Removes the invalid leading characters", func() {
+			Expect(sanitizeIndexName("_-+go")).To(Equal("go"))
+			Expect(sanitizeIndexName(" go")).To(Equal("go"))
+		})
+
+		It("Truncates long names", func() {
+			Expect(sanitizeIndexName(strings.Repeat("a", 300))).To(HaveLen(255))
+			Expect(sanitizeIndexName(strings.Repeat("é", 200))).To(Equal(strings.Repeat("é", 127)))
+		})
+
+		It("Produces valid names", func() {
+			for _, index := range []string{"go commons", "_go", "a|b<c>d", strings.Repeat("x", 256)} {
+				Expect(validateIndexName(sanitizeIndexName(index))).To(Succeed())
+			}
+		})
+	})
+
+	Context("Tests for documentFields()", func() {
+		It("Defaults the timestamp field", func() {
+			t := time.Date(2024, time.January, 15, 23, 30, 0, 0, time.UTC)
+			Expect(documentFields(IndexingOpts{AddTimestamp: true}, t)).To(Equal(map[string]interface{}{"metadata.timestamp": "2024-01-15T23:30:00Z"}))
+		})
+	})
+
+	Context("Tests for withLifecyclePolicy()", func() {
+		It("Returns the mappings as is without policy", func() {
+			mappings := []byte(`{"mappings":{}}`)
+			Expect(withLifecyclePolicy(mappings, "index.lifecycle.name", "")).To(Equal(json.RawMessage(mappings)))
+		})
+
+		It("Adds the policy to the existing settings", func() {
+			mappings := []byte(`{"settings":{"number_of_shards":1},"mappings":{"properties":{"value":{"type":"double"}}}}`)
+			j, err := withLifecyclePolicy(mappings, "index.lifecycle.name", "rollover")
+			Expect(err).To(BeNil())
+			Expect(j).To(MatchJSON(`{"settings":{"number_of_shards":1,"index.lifecycle.name":"rollover"},"mappings":{"properties":{"value":{"type":"double"}}}}`))
+		})
+
+		It("Returns err invalid mappings", func() {
+			_, err := withLifecyclePolicy([]byte(`[]`), "index.lifecycle.name", "rollover")
+			Expect(err.Error()).To(HavePrefix("invalid index mappings"))
+		})
+	})
+
+	Context("Tests for pingStatus()", func() {
+		It("Accepts the 2xx and allowed status codes", func() {
+			Expect(pingStatus("webhook", http.StatusOK)).To(Succeed())
+			Expect(pingStatus("webhook", http.StatusNoContent)).To(Succeed())
+			Expect(pingStatus("webhook", http.StatusMethodNotAllowed, http.StatusMethodNotAllowed)).To(Succeed())
+		})
+
+		It("Returns ErrUnauthorized when the credentials are rejected", func() {
+			Expect(pingStatus("webhook", http.StatusUnauthorized)).To(MatchError(ErrUnauthorized))
+			Expect(pingStatus("webhook", http.StatusForbidden)).To(MatchError(ErrUnauthorized))
+		})
+
+		It("Returns ErrUnhealthy for the other status codes", func() {
+			Expect(pingStatus("webhook", http.StatusBadRequest)).To(MatchError(ErrUnhealthy))
+			Expect(pingStatus("webhook", http.StatusNotFound)).To(MatchError(ErrUnhealthy))
+			Expect(pingStatus("webhook", http.StatusNotFound, http.StatusMethodNotAllowed)).To(MatchError(ErrUnhealthy))
+			Expect(pingStatus("webhook", http.StatusFound)).To(MatchError(ErrUnhealthy))
+			Expect(pingStatus("webhook", http.StatusServiceUnavailable)).To(MatchError("webhook backend unhealthy: status code 503"))
+		})
+	})
+
+	Context("Tests for flushSemaphore", func() {
+		It("Keeps the flushes of done contexts waiting for a slot", func() {
+			flushes := newFlushSemaphore(1)
+			held := flushes.acquire(context.Background())
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			acquired := make(chan context.Context)
+			go func() { acquired <- flushes.acquire(ctx) }()
+			Consistently(acquired, 50*time.Millisecond).ShouldNot(Receive())
+			Expect(flushes).To(HaveLen(1))
+			flushes.release(held)
+			var next context.Context
+			Eventually(acquired).Should(Receive(&next))
+			Expect(flushes).To(HaveLen(1))
+			flushes.release(next)
+			Expect(flushes).To(BeEmpty())
+		})
+
+		It("Doesn't limit the flushes without maximum", func() {
+			flushes := newFlushSemaphore(0)
+			ctx := flushes.acquire(context.Background())
+			flushes.release(ctx)
+			Expect(flushes).To(BeNil())
+		})
+	})
+
+	Context("Tests for flushLatencies", func() {
+		It("Returns the nearest-rank percentiles of the flush durations", func() {
+			latencies := flushLatencies{}
+			for i := 100; i > 0; i-- {
+				latencies.durations = append(latencies.durations, time.Duration(i)*time.Millisecond)
+			}
+			Expect(latencies.percentiles()).To(Equal(FlushLatency{Flushes: 100, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond}))
+			Expect(latencies.percentiles()).To(Equal(FlushLatency{}))
+		})
+
+		It("Records the flush durations around the given hooks", func() {
+			latencies := flushLatencies{}
+			ended := false
+			start, end := latencies.hooks(func(ctx context.Context) context.Context { return ctx }, func(ctx context.Context) { ended = true })
+			ctx := start(context.Background())
+			time.Sleep(10 * time.Millisecond)
+			end(ctx)
+			Expect(ended).To(BeTrue())
+			result := latencies.percentiles()
+			Expect(result.Flushes).To(Equal(1))
+			Expect(result.P50).To(BeNumerically(">=", 10*time.Millisecond))
+			Expect(result.P99).To(Equal(result.P50))
+		})
+	})
+})
+
+// BenchmarkEncodeDocument compares hashing the canonical form of a large document with hashing it while encoding it
+func BenchmarkEncodeDocument(b *testing.B) {
+	document := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		document[fmt.Sprintf("field%d", i)] = strings.Repeat("x", 100)
+	}
+	for _, streaming := range []bool{false, true} {
+		b.Run(fmt.Sprintf("streaming=%t", streaming), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := encodeDocument(document, IndexingOpts{StreamingHash: streaming}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncodeDocuments compares encoding and hashing a large batch of small documents serially and with a pool of workers
+func BenchmarkEncodeDocuments(b *testing.B) {
+	documents := make([]interface{}, 100000)
+	for i := range documents {
+		documents[i] = map[string]interface{}{"metricName": "podLatency", "value": i}
+	}
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				next := encodeDocumentsWithWorkers(context.Background(), documents, IndexingOpts{}, workers)
+				for {
+					_, ok, err := next()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if !ok {
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2023 The go-commons Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const webSocketIndexer = "websocket"
+
+// WebSocket streaming sink instance
+type WebSocket struct {
+	sync.Mutex
+	config     *websocket.Config
+	conn       *websocket.Conn
+	maxRetries int
+	backoff    func(int) time.Duration
+	logger     Logger
+}
+
+// Init function
+func init() {
+	Register(webSocketIndexer, func() Indexer { return &WebSocket{} })
+}
+
+// Returns new indexer for WebSocket endpoints, Servers[0] being the ws:// or wss:// URL of the endpoint. The
+// connection is established, checking the endpoint accepts it, unless the health check is skipped
+func (w *WebSocket) New(indexerConfig IndexerConfig) error {
+	if len(indexerConfig.Servers) == 0 {
+		return fmt.Errorf("servers not specified")
+	}
+	location, err := url.Parse(indexerConfig.Servers[0])
+	if err != nil {
+		return fmt.Errorf("invalid WebSocket URL: %s", err)
+	}
+	origin := &url.URL{Scheme: "http", Host: location.Host}
+	switch location.Scheme {
+	case "ws":
+	case "wss":
+		origin.Scheme = "https"
+	default:
+		return fmt.Errorf("invalid WebSocket URL scheme: %s", location.Scheme)
+	}
+	tlsClientConfig, err := tlsConfig(indexerConfig)
+	if err != nil {
+		return err
+	}
+	timeout := indexerConfig.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	w.config = &websocket.Config{
+		Location:  location,
+		Origin:    origin,
+		Version:   websocket.ProtocolVersionHybi13,
+		TlsConfig: tlsClientConfig,
+		Header:    http.Header{},
+		Dialer:    &net.Dialer{Timeout: timeout},
+	}
+	if indexerConfig.Username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(indexerConfig.Username + ":" + indexerConfig.Password))
+		w.config.Header.Set("Authorization", "Basic "+credentials)
+	}
+	w.maxRetries, w.backoff = retryPolicy(indexerConfig)
+	w.logger = loggerOrNop(indexerConfig.Logger)
+	w.conn = nil
+	if indexerConfig.SkipHealthCheck {
+		w.logger.Debugf("WebSocket health check skipped")
+		return nil
+	}
+	if err := w.connect(); err != nil {
+		return fmt.Errorf("WebSocket health check failed: %s", err)
+	}
+	return nil
+}
+
+// connect opens the connection to the endpoint
+func (w *WebSocket) connect() error {
+	conn, err := websocket.DialConfig(w.config)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	w.logger.Debugf("WebSocket connection to %s established", w.config.Location)
+	return nil
+}
+
+// Index streams the documents to the WebSocket endpoint
+func (w *WebSocket) Index(ctx context.Context, documents []interface{}, opts IndexingOpts) (string, error) {
+	if len(documents) <= 0 {
+		return fmt.Sprintf("Indexing skipped due to %v docs", len(documents)), ErrNoDocuments
+	}
+	result, err := w.IndexWithResult(ctx, documents, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// IndexWithResult streams every document as a JSON text frame to the WebSocket endpoint and returns the indexing
+// result. The connection is reestablished with backoff when sending a frame fails
+func (w *WebSocket) IndexWithResult(ctx context.Context, documents []interface{}, opts IndexingOpts) (IndexingResult, error) {
+	indexerStats := make(map[string]int)
+	if len(documents) <= 0 {
+		return IndexingResult{Stats: indexerStats}, ErrNoDocuments
+	}
+	if filtersDocuments(opts) {
+		return indexTransformed(ctx, documents, opts, w.IndexWithResult)
+	}
+	if opts.DryRun {
+		return dryRun(encodeDocuments(ctx, documents, opts), opts)
+	}
+	w.Lock()
+	defer w.Unlock()
+	start := time.Now().UTC()
+	fields := documentFields(opts, start)
+	docHash := make(map[string]bool)
+	redundantSkipped := 0
+	for _, document := range documents {
+		j, docHashKey, err := encodeDocument(document, opts)
+		if err != nil {
+			return IndexingResult{}, fmt.Errorf("Cannot encode document %s: %s", document, err)
+		}
+		if _, exists := docHash[docHashKey]; exists && !opts.SkipDedup {
+			redundantSkipped += 1
+			continue
+		}
+		if j, err = decorateDocument(j, fields); err != nil {
+			return IndexingResult{}, err
+		}
+		if err := w.send(ctx, j); err != nil {
+			return IndexingResult{}, err
+		}
+		indexerStats["created"]++
+		if !opts.SkipDedup {
+			docHash[docHashKey] = true
+		}
+	}
+	return newIndexingResult(indexerStats, redundantSkipped, time.Since(start)), nil
+}
+
+// send sends the frame, reconnecting with backoff up to maxRetries times when the connection fails
+func (w *WebSocket) send(ctx context.Context, frame []byte) error {
+	for attempt := 0; ; attempt++ {
+		err := ctx.Err()
+		if err == nil && w.conn == nil {
+			err = w.connect()
+		}
+		if err == nil {
+			if err = websocket.Message.Send(w.conn, string(frame)); err == nil {
+				return nil
+			}
+			w.conn.Close()
+			w.conn = nil
+		}
+		if attempt == w.maxRetries || ctx.Err() != nil {
+			return fmt.Errorf("Unexpected WebSocket error: %s", err)
+		}
+		w.logger.Debugf("WebSocket send failed, reconnecting: %s", err)
+		select {
+		case <-time.After(w.backoff(attempt + 1)):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// Ping checks the WebSocket endpoint is reachable, establishing the connection when it isn't
+func (w *WebSocket) Ping(ctx context.Context) error {
+	w.Lock()
+	defer w.Unlock()
+	if w.conn != nil {
+		return nil
+	}
+	if err := w.connect(); err != nil {
+		return pingFailed("WebSocket", ErrUnreachable, err)
+	}
+	return nil
+}
+
+// Close closes the WebSocket connection
+func (w *WebSocket) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
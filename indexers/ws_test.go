@@ -0,0 +1,190 @@
+package indexers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/websocket"
+)
+
+// mockWebSocketServer records the frames received by a WebSocket endpoint
+type mockWebSocketServer struct {
+	*httptest.Server
+	lock          sync.Mutex
+	frames        []string
+	connections   int
+	authorization string
+}
+
+func newMockWebSocketServer() *mockWebSocketServer {
+	m := &mockWebSocketServer{}
+	m.Server = httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		defer conn.Close()
+		m.lock.Lock()
+		m.connections++
+		m.authorization = conn.Request().Header.Get("Authorization")
+		m.lock.Unlock()
+		for {
+			var frame string
+			if err := websocket.Message.Receive(conn, &frame); err != nil {
+				return
+			}
+			m.lock.Lock()
+			m.frames = append(m.frames, frame)
+			m.lock.Unlock()
+		}
+	}))
+	return m
+}
+
+func (m *mockWebSocketServer) receivedFrames() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]string(nil), m.frames...)
+}
+
+func (m *mockWebSocketServer) connectionCount() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.connections
+}
+
+func (m *mockWebSocketServer) lastAuthorization() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.authorization
+}
+
+func (m *mockWebSocketServer) url() string {
+	return "ws" + strings.TrimPrefix(m.URL, "http")
+}
+
+var _ = Describe("Tests for ws.go", func() {
+	var server *mockWebSocketServer
+	BeforeEach(func() {
+		server = newMockWebSocketServer()
+		DeferCleanup(server.Close)
+	})
+
+	Context("Tests for New()", func() {
+		var indexer WebSocket
+
+		It("Returns nil as error", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{server.url()}, Username: "user", Password: "secret"})
+			Expect(err).To(BeNil())
+			Expect(indexer.Close()).To(BeNil())
+			Eventually(server.lastAuthorization).Should(Equal("Basic dXNlcjpzZWNyZXQ="))
+		})
+
+		It("Returns err no servers", func() {
+			err := indexer.New(IndexerConfig{})
+			Expect(err).To(BeEquivalentTo(errors.New("servers not specified")))
+		})
+
+		It("Returns err invalid scheme", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{server.URL}})
+			Expect(err).To(BeEquivalentTo(errors.New("invalid WebSocket URL scheme: http")))
+		})
+
+		It("Returns err when the endpoint rejects the connection", func() {
+			rejecting := httptest.NewServer(http.NotFoundHandler())
+			defer rejecting.Close()
+			err := indexer.New(IndexerConfig{Servers: []string{"ws" + strings.TrimPrefix(rejecting.URL, "http")}})
+			Expect(err.Error()).To(HavePrefix("WebSocket health check failed"))
+		})
+
+		It("Doesn't connect when the health check is skipped", func() {
+			err := indexer.New(IndexerConfig{Servers: []string{"ws://localhost:1"}, SkipHealthCheck: true})
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("Tests for Index()", func() {
+		var testcase indexMethodTestcase
+		var indexer WebSocket
+		BeforeEach(func() {
+			err := indexer.New(IndexerConfig{Servers: []string{server.url()}, RetryBackoff: time.Millisecond})
+			Expect(err).To(BeNil())
+			DeferCleanup(indexer.Close)
+			testcase = indexMethodTestcase{
+				documents: []interface{}{
+					"example document",
+					42,
+					map[string]interface{}{
+						"key1": "value1",
+						"key2": 123,
+					}},
+				opts: IndexingOpts{
+					MetricName: "placeholder",
+				},
+			}
+		})
+
+		It("Streams every document as a JSON frame", func() {
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Eventually(server.receivedFrames).Should(HaveLen(3))
+			frames := server.receivedFrames()
+			Expect(frames[0]).To(MatchJSON(`{"document":"example document","metricName":"placeholder"}`))
+			Expect(frames[2]).To(MatchJSON(`{"key1":"value1","key2":123,"metricName":"placeholder"}`))
+		})
+
+		It("Reconnects when sending a frame fails", func() {
+			// The broken connection is kept, so the next frame fails to be sent
+			Expect(indexer.conn.Close()).To(BeNil())
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Created).To(Equal(3))
+			Eventually(server.receivedFrames).Should(HaveLen(3))
+			Expect(server.connectionCount()).To(Equal(2))
+		})
+
+		It("Returns err when the endpoint is gone", func() {
+			server.Close()
+			Expect(indexer.Close()).To(BeNil())
+			_, err := indexer.Index(context.Background(), testcase.documents, testcase.opts)
+			Expect(err.Error()).To(HavePrefix("Unexpected WebSocket error"))
+		})
+
+		It("Skips redundant documents", func() {
+			testcase.documents = append(testcase.documents, testcase.documents[0])
+			result, err := indexer.IndexWithResult(context.Background(), testcase.documents, testcase.opts)
+			Expect(err).To(BeNil())
+			Expect(result.Skipped).To(Equal(1))
+			Expect(result.Created).To(Equal(3))
+		})
+
+		It("Test empty list of docs", func() {
+			msg, err := indexer.Index(context.Background(), []interface{}{}, testcase.opts)
+			Expect(err).To(MatchError(ErrNoDocuments))
+			Expect(msg).To(Equal("Indexing skipped due to 0 docs"))
+		})
+	})
+
+	Context("Tests for Ping()", func() {
+		var indexer WebSocket
+
+		It("Reconnects to the endpoint", func() {
+			Expect(indexer.New(IndexerConfig{Servers: []string{server.url()}})).To(BeNil())
+			Expect(indexer.Close()).To(BeNil())
+			Expect(indexer.Ping(context.Background())).To(Succeed())
+			Eventually(server.connectionCount).Should(Equal(2))
+			Expect(indexer.Close()).To(BeNil())
+		})
+
+		It("Returns ErrUnreachable when the endpoint is down", func() {
+			Expect(indexer.New(IndexerConfig{Servers: []string{server.url()}})).To(BeNil())
+			Expect(indexer.Close()).To(BeNil())
+			server.Close()
+			Expect(indexer.Ping(context.Background())).To(MatchError(ErrUnreachable))
+		})
+	})
+})